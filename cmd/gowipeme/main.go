@@ -4,10 +4,22 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/mat/gowipeme/internal/cli"
 	"github.com/mat/gowipeme/internal/tui"
 )
 
 func main() {
+	// Subcommands run headless for scripts, cron and provisioning
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	// The TUI needs a terminal; print usage instead when piped
+	if !cli.IsInteractive() {
+		fmt.Fprint(os.Stderr, cli.Usage())
+		os.Exit(cli.ExitUsage)
+	}
+
 	if err := tui.Run(); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
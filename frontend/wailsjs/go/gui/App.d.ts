@@ -3,6 +3,8 @@
 import {gui} from '../models';
 import {context} from '../models';
 
+export function CancelOperation():Promise<void>;
+
 export function CreateBackup():Promise<gui.BackupInfo>;
 
 export function DeleteBackup(arg1:string):Promise<void>;
@@ -11,6 +13,8 @@ export function GetBackupPreview():Promise<gui.BackupPreview>;
 
 export function GetCleanerStatus():Promise<Array<gui.CleanerInfo>>;
 
+export function GetCleanerWarnings():Promise<Array<string>>;
+
 export function GetContext():Promise<context.Context>;
 
 export function GetWiperStatus():Promise<gui.WiperInfo>;
@@ -23,4 +27,8 @@ export function RestoreBackup(arg1:string):Promise<void>;
 
 export function RunCleaner():Promise<void>;
 
+export function RunCleanerSelected(arg1:Array<string>):Promise<void>;
+
 export function RunWiper(arg1:number):Promise<void>;
+
+export function WaitForRunning(arg1:Array<string>):Promise<void>;
@@ -2,6 +2,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CancelOperation() {
+  return window['go']['gui']['App']['CancelOperation']();
+}
+
 export function CreateBackup() {
   return window['go']['gui']['App']['CreateBackup']();
 }
@@ -18,6 +22,10 @@ export function GetCleanerStatus() {
   return window['go']['gui']['App']['GetCleanerStatus']();
 }
 
+export function GetCleanerWarnings() {
+  return window['go']['gui']['App']['GetCleanerWarnings']();
+}
+
 export function GetContext() {
   return window['go']['gui']['App']['GetContext']();
 }
@@ -42,6 +50,14 @@ export function RunCleaner() {
   return window['go']['gui']['App']['RunCleaner']();
 }
 
+export function RunCleanerSelected(arg1) {
+  return window['go']['gui']['App']['RunCleanerSelected'](arg1);
+}
+
 export function RunWiper(arg1) {
   return window['go']['gui']['App']['RunWiper'](arg1);
 }
+
+export function WaitForRunning(arg1) {
+  return window['go']['gui']['App']['WaitForRunning'](arg1);
+}
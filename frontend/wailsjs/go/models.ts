@@ -1,3 +1,35 @@
+export namespace cleaner {
+	
+	export class Item {
+	    id: string;
+	    cleaner: string;
+	    category: string;
+	    label: string;
+	    path?: string;
+	    size: number;
+	    // Go type: time
+	    modTime: any;
+	    risk: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Item(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.cleaner = source["cleaner"];
+	        this.category = source["category"];
+	        this.label = source["label"];
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.modTime = source["modTime"];
+	        this.risk = source["risk"];
+	    }
+	}
+
+}
+
 export namespace gui {
 	
 	export class BackupInfo {
@@ -16,6 +48,7 @@ export namespace gui {
 	        this.timestamp = source["timestamp"];
 	        this.items = source["items"];
 	        this.size = source["size"];
+	        this.running = source["running"];
 	    }
 	}
 	export class BackupPreview {
@@ -32,8 +65,12 @@ export namespace gui {
 	}
 	export class CleanerInfo {
 	    name: string;
-	    items: string[];
+	    items: cleaner.Item[];
 	    count: number;
+	    size: number;
+	    running: string[];
+	    parents: string[];
+	    parentAdvice?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new CleanerInfo(source);
@@ -42,9 +79,31 @@ export namespace gui {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.name = source["name"];
-	        this.items = source["items"];
+	        this.items = this.convertValues(source["items"], cleaner.Item);
 	        this.count = source["count"];
+	        this.size = source["size"];
+	        this.running = source["running"];
+	        this.parents = source["parents"];
+	        this.parentAdvice = source["parentAdvice"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class WipeMethodInfo {
 	    id: number;
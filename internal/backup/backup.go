@@ -9,6 +9,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/mat/gowipeme/internal/browser"
 	"github.com/mat/gowipeme/internal/platform"
 )
 
@@ -51,36 +52,43 @@ func (bm *BackupManager) getBackupItems() []backupItem {
 	var items []backupItem
 
 	// Browser histories
-	browsers := []struct {
-		name   string
-		getter func() (string, error)
-	}{
-		{"Safari History", platform.GetSafariHistoryPath},
-		{"Chrome History", platform.GetChromeHistoryPath},
-		{"Edge History", platform.GetEdgeHistoryPath},
-		{"Brave History", platform.GetBraveHistoryPath},
-		{"Arc History", platform.GetArcHistoryPath},
+	if path, err := platform.GetSafariHistoryPath(); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			items = append(items, backupItem{Name: "Safari History", SourcePath: path})
+		}
 	}
 
-	for _, browser := range browsers {
-		if path, err := browser.getter(); err == nil {
-			if _, err := os.Stat(path); err == nil {
-				items = append(items, backupItem{Name: browser.name, SourcePath: path})
+	// Chromium-family browsers, one item per profile
+	for _, cb := range browser.ChromiumBrowsers {
+		userData, err := cb.UserDataPath()
+		if err != nil {
+			continue
+		}
+		profiles, err := browser.ChromiumProfiles(cb.Name, userData)
+		if err != nil {
+			continue
+		}
+		for _, profile := range profiles {
+			historyPath := profile.File("History")
+			if _, err := os.Stat(historyPath); err == nil {
+				items = append(items, backupItem{
+					Name:       fmt.Sprintf("%s History (%s)", cb.Name, profile.Name),
+					SourcePath: historyPath,
+				})
 			}
 		}
 	}
 
-	// Firefox uses profiles, find first profile with places.sqlite
-	if profilesPath, err := platform.GetFirefoxProfilesPath(); err == nil {
-		entries, err := os.ReadDir(profilesPath)
-		if err == nil {
-			for _, entry := range entries {
-				if entry.IsDir() {
-					placesPath := filepath.Join(profilesPath, entry.Name(), "places.sqlite")
-					if _, err := os.Stat(placesPath); err == nil {
-						items = append(items, backupItem{Name: "Firefox History", SourcePath: placesPath})
-						break
-					}
+	// Firefox uses profiles, back up places.sqlite of every profile
+	if root, err := platform.GetFirefoxDataPath(); err == nil {
+		if profiles, err := browser.FirefoxProfiles(root); err == nil {
+			for _, profile := range profiles {
+				placesPath := profile.File("places.sqlite")
+				if _, err := os.Stat(placesPath); err == nil {
+					items = append(items, backupItem{
+						Name:       fmt.Sprintf("Firefox History (%s)", profile.Name),
+						SourcePath: placesPath,
+					})
 				}
 			}
 		}
@@ -94,6 +102,11 @@ func (bm *BackupManager) getBackupItems() []backupItem {
 		{"Bash History", platform.GetBashHistoryPath},
 		{"Zsh History", platform.GetZshHistoryPath},
 		{"Fish History", platform.GetFishHistoryPath},
+		{"PowerShell History", platform.GetPowerShellHistoryPath},
+		{"Ksh History", platform.GetKshHistoryPath},
+		{"Tcsh History", platform.GetTcshHistoryPath},
+		{"Nushell History", func() (string, error) { return nushellFile("history.txt") }},
+		{"Nushell SQLite History", func() (string, error) { return nushellFile("history.sqlite3") }},
 	}
 
 	for _, shell := range shells {
@@ -107,6 +120,15 @@ func (bm *BackupManager) getBackupItems() []backupItem {
 	return items
 }
 
+// nushellFile returns a file in the nushell config directory
+func nushellFile(name string) (string, error) {
+	dir, err := platform.GetNushellConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
 // CreateBackup creates a new backup of all cleanable items
 func (bm *BackupManager) CreateBackup() (*BackupInfo, error) {
 	timestamp := time.Now()
@@ -125,8 +147,11 @@ func (bm *BackupManager) CreateBackup() (*BackupInfo, error) {
 	manifest := make(map[string]string)
 
 	for _, item := range items {
-		// Create a safe filename from the item name
+		// Create a safe filename from the item name; profiles may share a name
 		safeFilename := sanitizeFilename(item.Name)
+		for i := 2; manifest[safeFilename] != ""; i++ {
+			safeFilename = fmt.Sprintf("%s_%d", sanitizeFilename(item.Name), i)
+		}
 		destPath := filepath.Join(backupPath, safeFilename)
 
 		size, err := copyFile(item.SourcePath, destPath)
@@ -0,0 +1,46 @@
+// Package browser discovers browser profiles and the data files inside them.
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Profile is a single browser profile directory
+type Profile struct {
+	// Browser is the browser name, e.g. "Firefox"
+	Browser string
+	// Name is the display name of the profile, e.g. "work"
+	Name string
+	// Dir is the profile directory name, used as a stable identifier
+	Dir string
+	// Path is the absolute path of the profile directory
+	Path string
+	// Default marks the profile the browser opens by default
+	Default bool
+}
+
+// File returns the path of a file inside the profile
+func (p Profile) File(name string) string {
+	return filepath.Join(p.Path, name)
+}
+
+// sortProfiles orders profiles with the default first, then by name
+func sortProfiles(profiles []Profile) {
+	sort.SliceStable(profiles, func(i, j int) bool {
+		if profiles[i].Default != profiles[j].Default {
+			return profiles[i].Default
+		}
+		if profiles[i].Name != profiles[j].Name {
+			return profiles[i].Name < profiles[j].Name
+		}
+		return profiles[i].Dir < profiles[j].Dir
+	})
+}
+
+// isDir reports whether path exists and is a directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
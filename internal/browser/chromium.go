@@ -0,0 +1,102 @@
+package browser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/platform"
+)
+
+// ChromiumBrowser is a Chromium-family browser and its user data directory getter
+type ChromiumBrowser struct {
+	Name         string
+	UserDataPath func() (string, error)
+	// Processes are the executable names of the running browser
+	Processes []string
+}
+
+// ChromiumBrowsers lists the supported Chromium-family browsers
+var ChromiumBrowsers = []ChromiumBrowser{
+	{"Chrome", platform.GetChromeUserDataPath, []string{"chrome", "google-chrome", "Google Chrome"}},
+	{"Chromium", platform.GetChromiumUserDataPath, []string{"chromium", "chromium-browser", "Chromium"}},
+	{"Edge", platform.GetEdgeUserDataPath, []string{"msedge", "microsoft-edge", "Microsoft Edge"}},
+	{"Brave", platform.GetBraveUserDataPath, []string{"brave", "brave-browser", "Brave Browser"}},
+	{"Arc", platform.GetArcUserDataPath, []string{"Arc"}},
+}
+
+// localState is the subset of the "Local State" file we need
+type localState struct {
+	Profile struct {
+		InfoCache map[string]struct {
+			Name string `json:"name"`
+		} `json:"info_cache"`
+		LastUsed string `json:"last_used"`
+	} `json:"profile"`
+}
+
+// ChromiumProfiles returns every profile of a Chromium-family browser whose
+// user data directory is userDataDir.
+//
+// Profiles and their display names are read from the "Local State" file.
+// "Default", "Profile N" and "Guest Profile" directories that are missing
+// from it are added too, named after their directory.
+func ChromiumProfiles(browserName, userDataDir string) ([]Profile, error) {
+	profiles := make([]Profile, 0)
+	seen := make(map[string]bool)
+
+	var state localState
+	data, err := os.ReadFile(filepath.Join(userDataDir, "Local State"))
+	if err == nil {
+		// A corrupt Local State only loses display names; keep discovering
+		_ = json.Unmarshal(data, &state)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	lastUsed := state.Profile.LastUsed
+	if lastUsed == "" {
+		lastUsed = "Default"
+	}
+
+	add := func(dir, name string) {
+		path := filepath.Join(userDataDir, dir)
+		if seen[dir] || !isDir(path) {
+			return
+		}
+		seen[dir] = true
+		if name == "" {
+			name = dir
+		}
+		profiles = append(profiles, Profile{
+			Browser: browserName,
+			Name:    name,
+			Dir:     dir,
+			Path:    path,
+			Default: dir == lastUsed,
+		})
+	}
+
+	for dir, info := range state.Profile.InfoCache {
+		add(dir, info.Name)
+	}
+
+	entries, err := os.ReadDir(userDataDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case name == "Default", strings.HasPrefix(name, "Profile "):
+			add(name, "")
+		case name == "Guest Profile":
+			add(name, "Guest")
+		}
+	}
+
+	sortProfiles(profiles)
+
+	return profiles, nil
+}
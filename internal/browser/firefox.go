@@ -0,0 +1,110 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FirefoxProcesses are the executable names of a running Firefox
+var FirefoxProcesses = []string{"firefox", "firefox-bin", "firefox-esr"}
+
+// FirefoxProfiles returns every Firefox profile under root, the directory
+// that holds profiles.ini and installs.ini.
+//
+// Profiles are read from profiles.ini, with defaults taken from both
+// profiles.ini and installs.ini. Profile directories that contain a
+// places.sqlite but are not listed (e.g. after a manual copy) are added as
+// well, so nothing is missed when the INI files are stale.
+func FirefoxProfiles(root string) ([]Profile, error) {
+	profiles := make([]Profile, 0)
+	seen := make(map[string]int) // cleaned absolute path -> index
+
+	add := func(p Profile) {
+		key := filepath.Clean(p.Path)
+		if i, ok := seen[key]; ok {
+			profiles[i].Default = profiles[i].Default || p.Default
+			return
+		}
+		if !isDir(p.Path) {
+			return
+		}
+		seen[key] = len(profiles)
+		profiles = append(profiles, p)
+	}
+
+	// Defaults recorded per installation (installs.ini and [Install...] sections)
+	installDefaults := make(map[string]bool)
+	collectDefaults := func(sections []iniSection, all bool) {
+		for _, section := range sections {
+			if !all && !strings.HasPrefix(section.Name, "Install") {
+				continue
+			}
+			if def := section.Values["Default"]; def != "" {
+				installDefaults[filepath.Clean(resolveFirefoxPath(root, def, "1"))] = true
+			}
+		}
+	}
+
+	if sections, err := readINI(filepath.Join(root, "installs.ini")); err == nil {
+		collectDefaults(sections, true)
+	}
+
+	sections, err := readINI(filepath.Join(root, "profiles.ini"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	collectDefaults(sections, false)
+
+	for _, section := range sections {
+		if !strings.HasPrefix(section.Name, "Profile") {
+			continue
+		}
+		relPath := section.Values["Path"]
+		if relPath == "" {
+			continue
+		}
+
+		path := resolveFirefoxPath(root, relPath, section.Values["IsRelative"])
+		name := section.Values["Name"]
+		if name == "" {
+			name = filepath.Base(path)
+		}
+
+		add(Profile{
+			Browser: "Firefox",
+			Name:    name,
+			Dir:     filepath.Base(path),
+			Path:    path,
+			Default: section.Values["Default"] == "1" || installDefaults[filepath.Clean(path)],
+		})
+	}
+
+	// Fall back to profile directories that are not listed in profiles.ini
+	for _, pattern := range []string{"*/places.sqlite", "Profiles/*/places.sqlite"} {
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, match := range matches {
+			path := filepath.Dir(match)
+			add(Profile{
+				Browser: "Firefox",
+				Name:    filepath.Base(path),
+				Dir:     filepath.Base(path),
+				Path:    path,
+				Default: installDefaults[filepath.Clean(path)],
+			})
+		}
+	}
+
+	sortProfiles(profiles)
+
+	return profiles, nil
+}
+
+// resolveFirefoxPath resolves a profile path from profiles.ini. Relative
+// paths always use forward slashes, even on Windows.
+func resolveFirefoxPath(root, path, isRelative string) string {
+	if isRelative == "0" {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(root, filepath.FromSlash(path))
+}
@@ -0,0 +1,58 @@
+package browser
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// iniSection is a named section of an INI file
+type iniSection struct {
+	Name   string
+	Values map[string]string
+}
+
+// parseINI reads a simple INI file as used by Firefox (profiles.ini,
+// installs.ini). Sections are returned in file order; keys are case-sensitive.
+func parseINI(r io.Reader) ([]iniSection, error) {
+	var sections []iniSection
+	var current *iniSection
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sections = append(sections, iniSection{
+				Name:   strings.TrimSpace(line[1 : len(line)-1]),
+				Values: make(map[string]string),
+			})
+			current = &sections[len(sections)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			current.Values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return sections, scanner.Err()
+}
+
+// readINI parses the INI file at path
+func readINI(path string) ([]iniSection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseINI(f)
+}
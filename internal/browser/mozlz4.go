@@ -0,0 +1,167 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// mozLz4Magic starts every Firefox .jsonlz4/.baklz4 file
+var mozLz4Magic = []byte("mozLz40\x00")
+
+// ErrNotMozLz4 is returned for data without the mozLz4 header
+var ErrNotMozLz4 = errors.New("not a mozLz4 file")
+
+// DecodeMozLz4 decompresses Firefox's mozLz4 format: an 8-byte magic, the
+// decompressed size as a little-endian uint32 and a single LZ4 block.
+func DecodeMozLz4(data []byte) ([]byte, error) {
+	if len(data) < len(mozLz4Magic)+4 || !bytes.Equal(data[:len(mozLz4Magic)], mozLz4Magic) {
+		return nil, ErrNotMozLz4
+	}
+
+	size := binary.LittleEndian.Uint32(data[len(mozLz4Magic):])
+	out, err := decodeLZ4Block(data[len(mozLz4Magic)+4:], int(size))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != int(size) {
+		return nil, fmt.Errorf("mozLz4: decoded %d bytes, header says %d", len(out), size)
+	}
+
+	return out, nil
+}
+
+// ReadMozLz4 reads and decompresses a mozLz4 file
+func ReadMozLz4(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMozLz4(data)
+}
+
+// decodeLZ4Block decodes a raw LZ4 block (no frame header). Each sequence
+// is a token, literals, then a back-reference, except the last which only
+// has literals.
+func decodeLZ4Block(src []byte, sizeHint int) ([]byte, error) {
+	dst := make([]byte, 0, sizeHint)
+	errCorrupt := errors.New("lz4: corrupt block")
+
+	// readLength extends a 4-bit length with 255-valued continuation bytes
+	readLength := func(i, n int) (int, int, error) {
+		if n != 15 {
+			return i, n, nil
+		}
+		for {
+			if i >= len(src) {
+				return i, 0, errCorrupt
+			}
+			b := src[i]
+			i++
+			n += int(b)
+			if b != 255 {
+				return i, n, nil
+			}
+		}
+	}
+
+	i := 0
+	for i < len(src) {
+		token := src[i]
+		i++
+
+		var litLen int
+		var err error
+		if i, litLen, err = readLength(i, int(token>>4)); err != nil {
+			return nil, err
+		}
+		if litLen > len(src)-i {
+			return nil, errCorrupt
+		}
+		dst = append(dst, src[i:i+litLen]...)
+		i += litLen
+
+		// The last sequence ends after its literals
+		if i >= len(src) {
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, errCorrupt
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errCorrupt
+		}
+
+		var matchLen int
+		if i, matchLen, err = readLength(i, int(token&15)); err != nil {
+			return nil, err
+		}
+		matchLen += 4
+
+		// Copy byte by byte: the match may overlap the bytes it produces
+		start := len(dst) - offset
+		for k := 0; k < matchLen; k++ {
+			dst = append(dst, dst[start+k])
+		}
+	}
+
+	return dst, nil
+}
+
+// SessionStore summarises a Firefox session store file
+type SessionStore struct {
+	Windows       int
+	Tabs          int
+	ClosedWindows int
+	ClosedTabs    int
+}
+
+// String returns a summary such as "2 windows, 14 tabs, 3 closed tabs"
+func (s SessionStore) String() string {
+	summary := fmt.Sprintf("%d windows, %d tabs", s.Windows, s.Tabs)
+	if s.ClosedWindows > 0 {
+		summary += fmt.Sprintf(", %d closed windows", s.ClosedWindows)
+	}
+	if s.ClosedTabs > 0 {
+		summary += fmt.Sprintf(", %d closed tabs", s.ClosedTabs)
+	}
+	return summary
+}
+
+// ReadSessionStore decodes a sessionstore.jsonlz4 (or a backup such as
+// recovery.jsonlz4) and counts its windows and tabs
+func ReadSessionStore(path string) (SessionStore, error) {
+	var store SessionStore
+
+	data, err := ReadMozLz4(path)
+	if err != nil {
+		return store, err
+	}
+
+	type window struct {
+		Tabs       []json.RawMessage `json:"tabs"`
+		ClosedTabs []json.RawMessage `json:"_closedTabs"`
+	}
+	var session struct {
+		Windows       []window          `json:"windows"`
+		ClosedWindows []json.RawMessage `json:"_closedWindows"`
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return store, fmt.Errorf("%s: %w", path, err)
+	}
+
+	store.Windows = len(session.Windows)
+	store.ClosedWindows = len(session.ClosedWindows)
+	for _, w := range session.Windows {
+		store.Tabs += len(w.Tabs)
+		store.ClosedTabs += len(w.ClosedTabs)
+	}
+
+	return store, nil
+}
@@ -4,21 +4,84 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 
+	"github.com/mat/gowipeme/internal/browser"
 	"github.com/mat/gowipeme/internal/platform"
 )
 
+// browserHistory is a history database of one browser profile
+type browserHistory struct {
+	Browser    string
+	Profile    string // profile display name, empty for single-profile browsers
+	ProfileDir string // profile directory name, used in item IDs
+	Path       string
+}
+
+// isChromium reports whether the history is a Chromium History database
+func (h browserHistory) isChromium() bool {
+	for _, cb := range browser.ChromiumBrowsers {
+		if cb.Name == h.Browser {
+			return true
+		}
+	}
+	return false
+}
+
+// itemID returns the ID of the history's item, e.g. "browser:chrome:default"
+func (h browserHistory) itemID() string {
+	if h.ProfileDir == "" {
+		return itemID("browser", h.Browser)
+	}
+	return itemID("browser", h.Browser, h.ProfileDir)
+}
+
+// label returns the display name, e.g. "Firefox (work)"
+func (h browserHistory) label() string {
+	if h.Profile == "" {
+		return h.Browser
+	}
+	return fmt.Sprintf("%s (%s)", h.Browser, h.Profile)
+}
+
+// BrowserOptions configures how browser history is cleaned
+type BrowserOptions struct {
+	// InPlace deletes history rows from Chromium databases instead of
+	// removing the History file, so the browser keeps a valid database.
+	// Histories that cannot be pruned in place are skipped.
+	InPlace bool
+	// Range limits in-place cleaning to history recorded in the range.
+	// Setting it implies InPlace. The zero value covers all history.
+	Range TimeRange
+	// Domains limits in-place cleaning to matching sites, covering history,
+	// downloads, cookies and favicons. Setting it implies InPlace.
+	Domains DomainRules
+	// ProfileData offers each Chromium profile's cookies, autofill, sessions
+	// and other data besides history as separate items. Only history is
+	// listed otherwise, so a default clean keeps the user's logins.
+	ProfileData bool
+	// Passwords offers each Chromium profile's saved passwords (Login Data)
+	// as an item. They are never listed otherwise.
+	Passwords bool
+}
+
 // BrowserCleaner handles cleaning browser history
 type BrowserCleaner struct {
-	browsers map[string]string // browser name -> path
+	histories []browserHistory
+	opts      BrowserOptions
 }
 
-// NewBrowserCleaner creates a new browser cleaner
+// NewBrowserCleaner creates a new browser cleaner that deletes history files
 func NewBrowserCleaner() *BrowserCleaner {
-	bc := &BrowserCleaner{
-		browsers: make(map[string]string),
+	return NewBrowserCleanerWithOptions(BrowserOptions{})
+}
+
+// NewBrowserCleanerWithOptions creates a new browser cleaner with the given options
+func NewBrowserCleanerWithOptions(opts BrowserOptions) *BrowserCleaner {
+	if !opts.Range.IsZero() || !opts.Domains.IsZero() {
+		opts.InPlace = true
 	}
+	bc := &BrowserCleaner{opts: opts}
 
 	// Discover installed browsers
 	bc.discoverBrowsers()
@@ -26,85 +89,230 @@ func NewBrowserCleaner() *BrowserCleaner {
 	return bc
 }
 
+// addHistory records a history file if it exists
+func (bc *BrowserCleaner) addHistory(h browserHistory) {
+	if _, err := os.Stat(h.Path); err == nil {
+		bc.histories = append(bc.histories, h)
+	}
+}
+
 // discoverBrowsers finds installed browsers and their history paths
 func (bc *BrowserCleaner) discoverBrowsers() {
 	// Safari
 	if path, err := platform.GetSafariHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			bc.browsers["Safari"] = path
-		}
+		bc.addHistory(browserHistory{Browser: "Safari", Path: path})
 	}
 
-	// Chrome
-	if path, err := platform.GetChromeHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			bc.browsers["Chrome"] = path
+	// Chromium-family browsers (every profile listed in Local State)
+	for _, cb := range browser.ChromiumBrowsers {
+		userData, err := cb.UserDataPath()
+		if err != nil {
+			continue
+		}
+		profiles, err := browser.ChromiumProfiles(cb.Name, userData)
+		if err != nil {
+			continue
+		}
+		for _, profile := range profiles {
+			bc.addHistory(browserHistory{
+				Browser:    cb.Name,
+				Profile:    profile.Name,
+				ProfileDir: profile.Dir,
+				Path:       profile.File("History"),
+			})
 		}
 	}
 
-	// Chromium (Linux)
-	if runtime.GOOS == "linux" {
-		if path, err := platform.ExpandPath("~/.config/chromium/Default/History"); err == nil {
-			if _, err := os.Stat(path); err == nil {
-				bc.browsers["Chromium"] = path
+	// Firefox keeps bookmarks in its history database and is handled by
+	// FirefoxCleaner
+}
+
+// prunesInPlace reports whether the history is pruned row by row
+func (bc *BrowserCleaner) prunesInPlace(h browserHistory) bool {
+	return bc.opts.InPlace && h.isChromium()
+}
+
+// Processes returns the executable names of the discovered browsers
+func (bc *BrowserCleaner) Processes() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, h := range bc.histories {
+		if seen[h.Browser] {
+			continue
+		}
+		seen[h.Browser] = true
+
+		if h.Browser == "Safari" {
+			names = append(names, "Safari")
+		}
+		for _, cb := range browser.ChromiumBrowsers {
+			if cb.Name == h.Browser {
+				names = append(names, cb.Processes...)
 			}
 		}
 	}
+	return names
+}
+
+// Name returns the name of this cleaner
+func (bc *BrowserCleaner) Name() string {
+	return "Browser History"
+}
+
+// Locks returns the profile directories of the discovered histories
+func (bc *BrowserCleaner) Locks() []string {
+	locks := make([]string, 0, len(bc.histories))
+	for _, h := range bc.histories {
+		locks = append(locks, filepath.Dir(h.Path))
+	}
+	return locks
+}
+
+// DryRun returns the browser history databases that will be cleaned
+func (bc *BrowserCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0, len(bc.histories))
 
-	// Firefox (check for profiles)
-	if profilesPath, err := platform.GetFirefoxProfilesPath(); err == nil {
-		profiles, err := filepath.Glob(filepath.Join(profilesPath, "*/places.sqlite"))
-		if err == nil {
-			for _, profile := range profiles {
-				bc.browsers["Firefox"] = profile
-				break // Use first profile for now
+	for _, h := range bc.histories {
+		id := h.itemID()
+
+		// Only Chromium histories can be pruned; never fall back to deleting
+		// a whole database when the user asked for in-place cleaning
+		if bc.opts.InPlace && !h.isChromium() {
+			continue
+		}
+
+		if !bc.opts.Domains.IsZero() {
+			// One item per rule, so sites can be selected individually
+			for _, rule := range bc.opts.Domains.Rules() {
+				stats, err := countChromiumSites(h.Path, bc.opts.Range, bc.opts.Domains.Matcher(rule))
+				if err != nil || stats.total() == 0 {
+					continue
+				}
+				items = append(items, siteItem(itemID(id, "site", rule), bc.Name(), h.label(), rule, stats, bc.opts.Range, h.Path))
 			}
+			continue
 		}
-	}
 
-	// Edge
-	if path, err := platform.GetEdgeHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			bc.browsers["Edge"] = path
+		if bc.prunesInPlace(h) {
+			stats, err := countChromiumHistory(h.Path, bc.opts.Range, nil)
+			if err != nil || stats.total() == 0 {
+				continue
+			}
+			item := fileItem(id, bc.Name(), "History",
+				fmt.Sprintf("%s - %s (%s)", h.label(), stats, bc.opts.Range), h.Path)
+			// Pruning frees rows rather than the file, so the size is not reclaimed
+			item.Size = 0
+			items = append(items, item)
+			continue
 		}
-	}
 
-	// Brave
-	if path, err := platform.GetBraveHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			bc.browsers["Brave"] = path
+		label := h.label()
+		if h.isChromium() {
+			label += " - history"
 		}
-	}
+		item := fileItem(id, bc.Name(), "History", label, h.Path)
+		for _, suffix := range []string{"-wal", "-shm"} {
+			if info, err := os.Stat(h.Path + suffix); err == nil {
+				item.Size += info.Size()
+			}
+		}
+
+		items = append(items, item)
 
-	// Arc
-	if path, err := platform.GetArcHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			bc.browsers["Arc"] = path
+		// Other Chromium profile data requested by the options, each
+		// selectable on its own
+		if h.isChromium() {
+			profileDir := filepath.Dir(h.Path)
+			for _, category := range bc.categories() {
+				if path := category.path(profileDir); path != "" {
+					items = append(items, category.item(itemID(id, category.ID), bc.Name(), h.label(), path))
+				}
+			}
 		}
 	}
-}
 
-// Name returns the name of this cleaner
-func (bc *BrowserCleaner) Name() string {
-	return "Browser History"
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return items, nil
 }
 
-// DryRun returns a list of browsers that will be cleaned
-func (bc *BrowserCleaner) DryRun() ([]string, error) {
-	items := make([]string, 0, len(bc.browsers))
+// categories returns the Chromium data categories offered besides history
+func (bc *BrowserCleaner) categories() []profileCategory {
+	categories := make([]profileCategory, 0, len(chromiumCategories)+1)
+	if bc.opts.ProfileData {
+		categories = append(categories, chromiumCategories...)
+	}
+	if bc.opts.Passwords {
+		categories = append(categories, chromiumPasswords)
+	}
+	return categories
+}
 
-	for browser, path := range bc.browsers {
-		items = append(items, fmt.Sprintf("%s (%s)", browser, path))
+// category returns the Chromium data category of an item ID
+func (bc *BrowserCleaner) category(id string) (profileCategory, bool) {
+	for _, h := range bc.histories {
+		for _, category := range bc.categories() {
+			if id == itemID(h.itemID(), category.ID) {
+				return category, true
+			}
+		}
 	}
+	return profileCategory{}, false
+}
 
-	return items, nil
+// history returns the discovered history with the given path
+func (bc *BrowserCleaner) history(path string) (browserHistory, bool) {
+	for _, h := range bc.histories {
+		if h.Path == path {
+			return h, true
+		}
+	}
+	return browserHistory{}, false
 }
 
-// Clean removes browser history files
+// Clean removes all browser history files
 func (bc *BrowserCleaner) Clean() error {
+	items, err := bc.DryRun()
+	if err != nil {
+		return err
+	}
+	return bc.CleanItems(items)
+}
+
+// CleanItems removes the history files of the given browsers, or prunes
+// their rows in place when configured to
+func (bc *BrowserCleaner) CleanItems(items []Item) error {
 	errors := make([]error, 0)
 
-	for browser, path := range bc.browsers {
+	for _, item := range items {
+		path := item.Path
+
+		if category, ok := bc.category(item.ID); ok {
+			if err := category.clean(path); err != nil {
+				errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
+			}
+			continue
+		}
+
+		h, ok := bc.history(path)
+		if ok && bc.prunesInPlace(h) && !bc.opts.Domains.IsZero() {
+			for _, rule := range bc.opts.Domains.Rules() {
+				if item.ID != itemID(h.itemID(), "site", rule) {
+					continue
+				}
+				if err := pruneChromiumSites(path, bc.opts.Range, bc.opts.Domains.Matcher(rule)); err != nil {
+					errors = append(errors, fmt.Errorf("%s (%s): %w", h.label(), rule, err))
+				}
+			}
+			continue
+		}
+		if ok && bc.prunesInPlace(h) {
+			if err := pruneChromiumHistory(path, bc.opts.Range, nil); err != nil {
+				errors = append(errors, fmt.Errorf("%s: %w", h.label(), err))
+			}
+			continue
+		}
+
 		// Check if file still exists
 		if _, err := os.Stat(path); os.IsNotExist(err) {
 			continue
@@ -115,7 +323,7 @@ func (bc *BrowserCleaner) Clean() error {
 		// For now, we'll delete the file
 		err := os.Remove(path)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("%s: %w", browser, err))
+			errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
 			continue
 		}
 
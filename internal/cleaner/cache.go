@@ -1,24 +1,142 @@
 package cleaner
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/mat/gowipeme/internal/config"
+	"github.com/mat/gowipeme/internal/event"
 	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/process"
 )
 
+// cacheKeep lists, per OS, globs of cache entries kept by default: system
+// caches that are slow to rebuild or held by the desktop session, and
+// caches handled by other cleaners
+var cacheKeep = map[string][]string{
+	"darwin": {
+		"com.apple.bird", // iCloud
+		"com.apple.notificationcenter",
+		"com.apple.LaunchServices",
+		"com.apple.iconservices",
+		"com.apple.nsurlsessiond",
+	},
+	"linux": {
+		"fontconfig",
+		"mesa_shader_cache", "mesa_shader_cache_db", "nvidia", "radv_builtin_shaders*",
+		"dconf", "ibus", "gstreamer-1.0",
+		"ksycoca*", "icon-cache.kcache", "plasma_theme_*.kcache",
+		"thumbnails",          // ThumbnailCleaner
+		"tracker", "tracker3", // RecentFilesCleaner
+	},
+}
+
+// cacheProcesses maps cache entries to the programs using them when the
+// names differ. Entries named like a running program are kept as well.
+var cacheProcesses = map[string][]string{
+	"mozilla":        {"firefox"},
+	"google-chrome":  {"chrome"},
+	"chromium":       {"chromium", "chromium-browser"},
+	"BraveSoftware":  {"brave"},
+	"microsoft-edge": {"msedge"},
+	"vivaldi":        {"vivaldi-bin"},
+	"thunderbird":    {"thunderbird"},
+	"JetBrains":      editorProcesses["JetBrains"],
+}
+
+// CacheOptions selects the cache entries to clean
+type CacheOptions struct {
+	// Include lists globs of entries cleaned even if kept by default
+	Include []string
+	// Exclude lists globs of entries that are never cleaned
+	Exclude []string
+	// OlderThan only cleans entries with nothing modified for this many days
+	OlderThan int
+	// MinSize only cleans entries of at least this many bytes
+	MinSize int64
+}
+
+// CacheOptionsFromConfig converts the user configuration into cache options
+func CacheOptionsFromConfig(cfg config.CacheConfig) (CacheOptions, error) {
+	opts := CacheOptions{Include: cfg.Include, Exclude: cfg.Exclude, OlderThan: cfg.OlderThanDays}
+	if cfg.OlderThanDays < 0 {
+		return opts, fmt.Errorf("cache: olderThanDays must not be negative")
+	}
+	for _, pattern := range append(append([]string{}, cfg.Include...), cfg.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return opts, fmt.Errorf("cache: invalid glob %q", pattern)
+		}
+	}
+	if cfg.MinSize != "" {
+		size, err := parseSize(cfg.MinSize)
+		if err != nil {
+			return opts, fmt.Errorf("cache: invalid minSize: %w", err)
+		}
+		opts.MinSize = size
+	}
+	return opts, nil
+}
+
+// parseSize parses a size such as "512", "200K", "1.5 GB" or "10MiB";
+// units are powers of 1024 as in formatSize
+func parseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGT", s[n-1]); i >= 0 {
+			multiplier = int64(1) << (10 * (i + 1))
+			s = s[:n-1]
+		}
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("%q is not a size", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// loadCacheOptions returns the cache options of the user configuration, or
+// none if the configuration is invalid
+func loadCacheOptions() CacheOptions {
+	cfg, err := config.Load()
+	if err != nil {
+		return CacheOptions{}
+	}
+	opts, err := CacheOptionsFromConfig(cfg.Cache)
+	if err != nil {
+		return CacheOptions{}
+	}
+	return opts
+}
+
 // CacheCleaner handles cleaning application caches
 type CacheCleaner struct {
 	cachePath string
-	// Whitelist of cache directories to preserve (system-critical)
-	whitelist map[string]bool
+	// Globs of cache entries to preserve (system-critical)
+	keep []string
+	opts CacheOptions
 }
 
-// NewCacheCleaner creates a new cache cleaner
+// NewCacheCleaner creates a cache cleaner configured by the user
+// configuration
 func NewCacheCleaner() *CacheCleaner {
+	return NewCacheCleanerWithOptions(loadCacheOptions())
+}
+
+// NewCacheCleanerWithOptions creates a cache cleaner with the given rules
+func NewCacheCleanerWithOptions(opts CacheOptions) *CacheCleaner {
 	cc := &CacheCleaner{
-		whitelist: make(map[string]bool),
+		keep: cacheKeep[runtime.GOOS],
+		opts: opts,
 	}
 
 	// Get cache path
@@ -27,13 +145,6 @@ func NewCacheCleaner() *CacheCleaner {
 		cc.cachePath = path
 	}
 
-	// Whitelist system-critical caches
-	cc.whitelist["com.apple.bird"] = true           // iCloud
-	cc.whitelist["com.apple.notificationcenter"] = true
-	cc.whitelist["com.apple.LaunchServices"] = true
-	cc.whitelist["com.apple.iconservices"] = true
-	cc.whitelist["com.apple.nsurlsessiond"] = true
-
 	return cc
 }
 
@@ -42,78 +153,140 @@ func (cc *CacheCleaner) Name() string {
 	return "Application Caches"
 }
 
-// DryRun returns a list of cache directories that will be cleaned
-func (cc *CacheCleaner) DryRun() ([]string, error) {
-	items := make([]string, 0)
+// Locks returns the cache directory, which holds other cleaners' caches
+func (cc *CacheCleaner) Locks() []string {
+	if cc.cachePath == "" {
+		return nil
+	}
+	return []string{cc.cachePath}
+}
+
+// selects reports whether a cache entry is cleaned by name: exclude globs
+// win over include globs, which win over the keep-list and running programs
+func (cc *CacheCleaner) selects(name string, procs []process.Process) bool {
+	if matchAny(cc.opts.Exclude, name) {
+		return false
+	}
+	if matchAny(cc.opts.Include, name) {
+		return true
+	}
+	if matchAny(cc.keep, name) {
+		return false
+	}
+	names := append([]string{name}, cacheProcesses[name]...)
+	for _, p := range procs {
+		if p.Matches(names) {
+			return false
+		}
+	}
+	return true
+}
+
+// DryRun returns the cache entries that will be cleaned
+func (cc *CacheCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0)
 
+	err := cc.DryRunStream(context.Background(), func(item Item) {
+		items = append(items, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries are found in completion order
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+
+	return items, nil
+}
+
+// DryRunStream sizes the cache entries concurrently and reports each
+// selected entry as soon as its size is known
+func (cc *CacheCleaner) DryRunStream(ctx context.Context, found func(Item)) error {
 	if cc.cachePath == "" {
-		return items, nil
+		return nil
 	}
 
 	// Check if cache directory exists
 	if _, err := os.Stat(cc.cachePath); os.IsNotExist(err) {
-		return items, nil
+		return nil
 	}
 
 	// Read cache directory
 	entries, err := os.ReadDir(cc.cachePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+		return fmt.Errorf("failed to read cache directory: %w", err)
 	}
 
-	// Filter out whitelisted caches
+	// Caches of running programs are in use; without a procfs none are known
+	procs, _ := process.NewScanner().List()
+	cutoff := time.Now().AddDate(0, 0, -cc.opts.OlderThan)
+
+	paths := make([]string, 0, len(entries))
 	for _, entry := range entries {
-		if entry.IsDir() {
-			// Skip whitelisted directories
-			if cc.whitelist[entry.Name()] {
-				continue
-			}
-
-			// Get size estimate
-			cachePath := filepath.Join(cc.cachePath, entry.Name())
-			size := getDirSize(cachePath)
-			sizeStr := formatSize(size)
-
-			items = append(items, fmt.Sprintf("%s (%s)", entry.Name(), sizeStr))
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			continue
+		}
+		if cc.selects(entry.Name(), procs) {
+			paths = append(paths, filepath.Join(cc.cachePath, entry.Name()))
 		}
 	}
 
-	return items, nil
+	scanner := newSizeScanner(ctx)
+	scanner.fresh = cc.opts.OlderThan > 0
+	return scanner.scanAll(paths, func(path string, stats dirStats) {
+		if cc.opts.OlderThan > 0 && !stats.modTime.Before(cutoff) {
+			return
+		}
+		if stats.size < cc.opts.MinSize {
+			return
+		}
+		name := filepath.Base(path)
+		found(Item{
+			ID:       itemID("cache", name),
+			Cleaner:  cc.Name(),
+			Category: "Cache",
+			Label:    name,
+			Path:     path,
+			Size:     stats.size,
+			ModTime:  stats.modTime,
+		})
+	})
 }
 
-// Clean removes application cache directories
+// Clean removes all selected application cache entries
 func (cc *CacheCleaner) Clean() error {
 	if cc.cachePath == "" {
 		return fmt.Errorf("cache path not found")
 	}
 
-	// Check if cache directory exists
-	if _, err := os.Stat(cc.cachePath); os.IsNotExist(err) {
-		return nil // Nothing to clean
-	}
-
-	// Read cache directory
-	entries, err := os.ReadDir(cc.cachePath)
+	items, err := cc.DryRun()
 	if err != nil {
-		return fmt.Errorf("failed to read cache directory: %w", err)
+		return err
 	}
 
+	return cc.CleanItems(items)
+}
+
+// CleanItems removes the given cache entries
+func (cc *CacheCleaner) CleanItems(items []Item) error {
+	return cc.CleanItemsContext(context.Background(), items, nil)
+}
+
+// CleanItemsContext removes the given cache entries until the context is
+// cancelled
+func (cc *CacheCleaner) CleanItemsContext(ctx context.Context, items []Item, report event.Func) error {
 	errors := make([]error, 0)
 
-	// Remove each cache directory (except whitelisted)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			// Skip whitelisted directories
-			if cc.whitelist[entry.Name()] {
-				continue
-			}
-
-			cachePath := filepath.Join(cc.cachePath, entry.Name())
-			err := os.RemoveAll(cachePath)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("%s: %w", entry.Name(), err))
-			}
+	for i, item := range items {
+		err := removeAllContext(ctx, item.Path)
+		forgetStats(item.Path)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
 		}
+		report.Send(event.Event{Kind: event.Progress, Step: item.Label, Done: int64(i + 1)})
 	}
 
 	if len(errors) > 0 {
@@ -125,23 +298,7 @@ func (cc *CacheCleaner) Clean() error {
 
 // getDirSize calculates the total size of a directory
 func getDirSize(path string) int64 {
-	var size int64
-
-	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		if !info.IsDir() {
-			size += info.Size()
-		}
-		return nil
-	})
-
-	if err != nil {
-		return 0
-	}
-
-	return size
+	return newSizeScanner(context.Background()).stats(path).size
 }
 
 // formatSize formats bytes into human-readable format
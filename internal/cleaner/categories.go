@@ -0,0 +1,128 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// profileCategory is a kind of data kept in a browser profile besides history,
+// offered as its own item
+type profileCategory struct {
+	ID       string   // item ID suffix, e.g. "cookies"
+	Category string   // item category
+	Label    string   // e.g. "cookies"
+	Files    []string // candidate paths relative to the profile; the first that exists is used
+	Count    string   // optional query counting the entries
+	Unit     string   // what Count counts, e.g. "cookies"
+	Risk     RiskLevel
+	// Describe, when set, summarises the contents for the label instead of Count
+	Describe func(path string) string
+	// Rows, when set, lists tables to clear instead of deleting the database,
+	// for files that also hold data the user wants to keep
+	Rows []string
+}
+
+// sqliteSidecars are the files SQLite keeps next to a database
+var sqliteSidecars = []string{"-journal", "-wal", "-shm"}
+
+// path returns the category's file or directory in a profile, or "" if absent
+func (c profileCategory) path(profileDir string) string {
+	for _, file := range c.Files {
+		path := filepath.Join(profileDir, filepath.FromSlash(file))
+		if fileExists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// item builds the dry-run item for the category, counting its entries
+func (c profileCategory) item(id, cleanerName, profile, path string) Item {
+	item := fileItem(id, cleanerName, c.Category, fmt.Sprintf("%s - %s", profile, c.Label), path)
+
+	info, err := os.Stat(path)
+	isDir := err == nil && info.IsDir()
+	if !isDir {
+		for _, suffix := range sqliteSidecars {
+			if info, err := os.Stat(path + suffix); err == nil {
+				item.Size += info.Size()
+			}
+		}
+	}
+
+	switch {
+	case c.Describe != nil:
+		if summary := c.Describe(path); summary != "" {
+			item.Label += fmt.Sprintf(" (%s)", summary)
+		}
+	case isDir:
+		if entries, err := os.ReadDir(path); err == nil {
+			item.Label += fmt.Sprintf(" (%d files)", len(entries))
+		}
+	case c.Count != "":
+		if db, err := openSQLite(path); err == nil {
+			item.Label += fmt.Sprintf(" (%d %s)", countRows(db, c.Count), c.Unit)
+			db.Close()
+		}
+	}
+
+	if len(c.Rows) > 0 {
+		// Clearing rows keeps the file, so its size is not reclaimed
+		item.Size = 0
+	}
+
+	item.Risk = c.Risk
+	return item
+}
+
+// clean removes the category's data at path
+func (c profileCategory) clean(path string) error {
+	if len(c.Rows) > 0 {
+		db, err := openSQLite(path)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		for _, table := range c.Rows {
+			if !tableExists(db, table) {
+				continue
+			}
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				return fmt.Errorf("failed to clear %s: %w", table, err)
+			}
+		}
+		return secureVacuum(db)
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		// Keep the directory itself so the browser can write new sessions
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	for _, suffix := range sqliteSidecars {
+		_ = os.Remove(path + suffix)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// chromiumEpochOffset is the number of microseconds between 1601-01-01,
+// the epoch Chromium uses for its timestamps, and the Unix epoch.
+const chromiumEpochOffset = 11644473600 * 1000000
+
+// chromiumTime converts t into Chromium's microseconds since 1601-01-01
+func chromiumTime(t time.Time) int64 {
+	return t.UnixMicro() + chromiumEpochOffset
+}
+
+// Chromium cookie and favicon tables selected by site
+var (
+	chromiumCookies = hostTable{
+		Table:      "cookies",
+		Column:     "host_key",
+		TimeColumn: "creation_utc",
+		ToDB:       chromiumTime,
+	}
+	chromiumFavicons = hostTable{
+		Table:  "icon_mapping",
+		Column: "page_url",
+		IsURL:  true,
+		Cleanup: []string{
+			"DELETE FROM favicons WHERE id NOT IN (SELECT icon_id FROM icon_mapping)",
+			"DELETE FROM favicon_bitmaps WHERE icon_id NOT IN (SELECT id FROM favicons)",
+		},
+	}
+)
+
+// chromiumCookiesPath returns the cookie database of a profile; newer
+// versions keep it in the Network subdirectory
+func chromiumCookiesPath(profileDir string) string {
+	path := filepath.Join(profileDir, "Network", "Cookies")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return filepath.Join(profileDir, "Cookies")
+}
+
+// chromiumScope holds the conditions limiting a history prune to matching
+// URLs and downloads. They are empty when every site is selected.
+type chromiumScope struct {
+	visits    string
+	urls      string
+	downloads string
+}
+
+// newChromiumScope selects the URLs and downloads of matching hosts into
+// temporary tables. A nil match selects everything.
+func newChromiumScope(db sqlConn, match hostMatcher) (chromiumScope, error) {
+	var scope chromiumScope
+	if match == nil {
+		return scope, nil
+	}
+
+	byURL := func(v string) bool { return match(urlHost(v)) }
+	if err := fillScope(db, "scope_urls", "SELECT id, url FROM urls", byURL); err != nil {
+		return scope, err
+	}
+	scope.visits = " AND url IN (SELECT id FROM temp.scope_urls)"
+	scope.urls = " AND id IN (SELECT id FROM temp.scope_urls)"
+
+	// A download matches if any URL in its redirect chain does
+	if tableExists(db, "downloads_url_chains") {
+		if err := fillScope(db, "scope_downloads", "SELECT id, url FROM downloads_url_chains", byURL); err != nil {
+			return scope, err
+		}
+		scope.downloads = " AND id IN (SELECT id FROM temp.scope_downloads)"
+	}
+
+	return scope, nil
+}
+
+// countChromiumHistory returns how many rows pruneChromiumHistory would remove
+func countChromiumHistory(path string, r TimeRange, match hostMatcher) (historyStats, error) {
+	var stats historyStats
+
+	db, err := openSQLite(path)
+	if err != nil {
+		return stats, err
+	}
+	defer db.Close()
+
+	scope, err := newChromiumScope(db, match)
+	if err != nil {
+		return stats, err
+	}
+
+	visitWhere, visitArgs := r.where("visit_time", chromiumTime)
+	stats.Visits = countRows(db, "SELECT COUNT(*) FROM visits WHERE "+visitWhere+scope.visits, visitArgs...)
+
+	urlWhere, urlArgs := r.where("last_visit_time", chromiumTime)
+	stats.Pages = countRows(db, "SELECT COUNT(*) FROM urls WHERE "+urlWhere+scope.urls, urlArgs...)
+
+	if tableExists(db, "downloads") {
+		downloadWhere, downloadArgs := r.where("start_time", chromiumTime)
+		stats.Downloads = countRows(db, "SELECT COUNT(*) FROM downloads WHERE "+downloadWhere+scope.downloads, downloadArgs...)
+	}
+
+	return stats, nil
+}
+
+// pruneChromiumHistory deletes the visits, URLs, search terms and downloads
+// recorded in the time range from a Chromium History database, limited to
+// matching hosts when match is non-nil. It then vacuums the database so the
+// removed rows cannot be recovered from free pages.
+func pruneChromiumHistory(path string, r TimeRange, match hostMatcher) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scope, err := newChromiumScope(tx, match)
+	if err != nil {
+		return err
+	}
+
+	visitWhere, visitArgs := r.where("visit_time", chromiumTime)
+	if _, err := tx.Exec("DELETE FROM visits WHERE "+visitWhere+scope.visits, visitArgs...); err != nil {
+		return fmt.Errorf("failed to delete visits: %w", err)
+	}
+
+	// URLs are removed once none of their visits remain, as long as they were
+	// last visited in the range. Survivors get their counters recomputed.
+	urlWhere, urlArgs := r.where("last_visit_time", chromiumTime)
+	if _, err := tx.Exec("DELETE FROM urls WHERE id NOT IN (SELECT url FROM visits) AND "+urlWhere+scope.urls, urlArgs...); err != nil {
+		return fmt.Errorf("failed to delete urls: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE urls SET
+		visit_count = (SELECT COUNT(*) FROM visits WHERE visits.url = urls.id),
+		last_visit_time = COALESCE((SELECT MAX(visit_time) FROM visits WHERE visits.url = urls.id), last_visit_time)`); err != nil {
+		return fmt.Errorf("failed to update urls: %w", err)
+	}
+
+	// Tables that reference visits or urls and may not exist in every version
+	orphans := []struct{ table, stmt string }{
+		{"keyword_search_terms", "DELETE FROM keyword_search_terms WHERE url_id NOT IN (SELECT id FROM urls)"},
+		{"visit_source", "DELETE FROM visit_source WHERE id NOT IN (SELECT id FROM visits)"},
+		{"content_annotations", "DELETE FROM content_annotations WHERE visit_id NOT IN (SELECT id FROM visits)"},
+		{"context_annotations", "DELETE FROM context_annotations WHERE visit_id NOT IN (SELECT id FROM visits)"},
+		{"segments", "DELETE FROM segments WHERE url_id NOT IN (SELECT id FROM urls)"},
+		{"segment_usage", "DELETE FROM segment_usage WHERE segment_id NOT IN (SELECT id FROM segments)"},
+	}
+	for _, orphan := range orphans {
+		if !tableExists(tx, orphan.table) {
+			continue
+		}
+		if _, err := tx.Exec(orphan.stmt); err != nil {
+			return fmt.Errorf("failed to clean %s: %w", orphan.table, err)
+		}
+	}
+
+	if tableExists(tx, "downloads") {
+		downloadWhere, downloadArgs := r.where("start_time", chromiumTime)
+		if _, err := tx.Exec("DELETE FROM downloads WHERE "+downloadWhere+scope.downloads, downloadArgs...); err != nil {
+			return fmt.Errorf("failed to delete downloads: %w", err)
+		}
+		for _, table := range []string{"downloads_url_chains", "downloads_slices"} {
+			if !tableExists(tx, table) {
+				continue
+			}
+			if _, err := tx.Exec("DELETE FROM " + table + " WHERE id NOT IN (SELECT id FROM downloads)"); err != nil {
+				return fmt.Errorf("failed to clean %s: %w", table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return secureVacuum(db)
+}
+
+// countChromiumSites returns the history, cookies and favicons of matching
+// hosts in a Chromium profile
+func countChromiumSites(historyPath string, r TimeRange, match hostMatcher) (historyStats, error) {
+	stats, err := countChromiumHistory(historyPath, r, match)
+	if err != nil {
+		return stats, err
+	}
+
+	profileDir := filepath.Dir(historyPath)
+	if path := chromiumCookiesPath(profileDir); fileExists(path) {
+		stats.Cookies, _ = chromiumCookies.count(path, r, match)
+	}
+	if path := filepath.Join(profileDir, "Favicons"); fileExists(path) {
+		stats.Favicons, _ = chromiumFavicons.count(path, r, match)
+	}
+
+	return stats, nil
+}
+
+// pruneChromiumSites removes the history, cookies and favicons of matching
+// hosts from a Chromium profile
+func pruneChromiumSites(historyPath string, r TimeRange, match hostMatcher) error {
+	errors := make([]error, 0)
+
+	if err := pruneChromiumHistory(historyPath, r, match); err != nil {
+		errors = append(errors, fmt.Errorf("history: %w", err))
+	}
+
+	profileDir := filepath.Dir(historyPath)
+	if path := chromiumCookiesPath(profileDir); fileExists(path) {
+		if err := chromiumCookies.prune(path, r, match); err != nil {
+			errors = append(errors, fmt.Errorf("cookies: %w", err))
+		}
+	}
+	if path := filepath.Join(profileDir, "Favicons"); fileExists(path) {
+		if err := chromiumFavicons.prune(path, r, match); err != nil {
+			errors = append(errors, fmt.Errorf("favicons: %w", err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%v", errors)
+	}
+	return nil
+}
+
+// chromiumCategories are the per-profile data offered besides history with
+// BrowserOptions.ProfileData
+var chromiumCategories = []profileCategory{
+	{
+		ID: "cookies", Category: "Cookies", Label: "cookies",
+		Files: []string{"Network/Cookies", "Cookies"},
+		Count: "SELECT COUNT(*) FROM cookies", Unit: "cookies",
+		Risk: RiskMedium,
+	},
+	{
+		// Web Data also holds search engines and payment cards, so only the
+		// autofill tables are cleared
+		ID: "autofill", Category: "Autofill", Label: "autofill entries",
+		Files: []string{"Web Data"},
+		Count: "SELECT COUNT(*) FROM autofill", Unit: "entries",
+		Risk: RiskMedium,
+		Rows: []string{
+			"autofill", "autofill_profiles", "autofill_profile_names",
+			"autofill_profile_emails", "autofill_profile_phones", "autofill_profile_addresses",
+			"addresses", "address_type_tokens",
+		},
+	},
+	{
+		ID: "top-sites", Category: "Top Sites", Label: "top sites",
+		Files: []string{"Top Sites"},
+		Count: "SELECT COUNT(*) FROM top_sites", Unit: "sites",
+	},
+	{
+		ID: "visited-links", Category: "Visited Links", Label: "visited links",
+		Files: []string{"Visited Links"},
+	},
+	{
+		ID: "favicons", Category: "Favicons", Label: "favicons",
+		Files: []string{"Favicons"},
+		Count: "SELECT COUNT(*) FROM favicons", Unit: "icons",
+	},
+	{
+		ID: "shortcuts", Category: "Shortcuts", Label: "omnibox shortcuts",
+		Files: []string{"Shortcuts"},
+		Count: "SELECT COUNT(*) FROM omni_box_shortcuts", Unit: "shortcuts",
+	},
+	{
+		ID: "sessions", Category: "Sessions", Label: "saved sessions and tabs",
+		Files: []string{"Sessions"},
+		Risk:  RiskMedium,
+	},
+	{
+		ID: "network-action-predictor", Category: "Predictor", Label: "network action predictor",
+		Files: []string{"Network Action Predictor"},
+		Count: "SELECT COUNT(*) FROM network_action_predictor", Unit: "entries",
+	},
+}
+
+// chromiumPasswords holds saved passwords and is only offered on request
+var chromiumPasswords = profileCategory{
+	ID: "passwords", Category: "Passwords", Label: "saved passwords",
+	Files: []string{"Login Data"},
+	Count: "SELECT COUNT(*) FROM logins", Unit: "passwords",
+	Risk: RiskHigh,
+}
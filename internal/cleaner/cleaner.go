@@ -1,9 +1,16 @@
 package cleaner
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/mat/gowipeme/internal/event"
+	"github.com/mat/gowipeme/internal/process"
 )
 
 // Cleaner defines the interface for all cleaning operations
@@ -11,30 +18,204 @@ type Cleaner interface {
 	// Name returns the human-readable name of this cleaner
 	Name() string
 
-	// DryRun returns a list of items that would be deleted without actually deleting them
-	DryRun() ([]string, error)
+	// DryRun returns the items that would be deleted without actually deleting them
+	DryRun() ([]Item, error)
 
-	// Clean performs the actual cleaning operation
+	// Clean performs the actual cleaning operation on every item
 	Clean() error
+
+	// CleanItems cleans only the given items, which must come from DryRun
+	CleanItems(items []Item) error
+}
+
+// ProcessAware is implemented by cleaners whose files are held open or
+// rewritten by running programs, such as browsers and shells
+type ProcessAware interface {
+	// Processes returns the executable names of those programs
+	Processes() []string
+}
+
+// Streamer is implemented by cleaners with slow dry-runs that can report
+// items while they are still looking for more
+type Streamer interface {
+	// DryRunStream calls found, one item at a time, for the items DryRun
+	// would return; DryRun sorts them by label. It stops with the context's
+	// error when the context is cancelled.
+	DryRunStream(ctx context.Context, found func(Item)) error
+}
+
+// ContextCleaner is implemented by cleaners whose cleaning can take long
+// enough to be worth stopping, such as large cache or trash removals
+type ContextCleaner interface {
+	// CleanItemsContext is CleanItems stopping with the context's error
+	// when the context is cancelled. Items are removed in order, and a
+	// Progress event counting the items done so far follows each one.
+	CleanItemsContext(ctx context.Context, items []Item, report event.Func) error
+}
+
+// RiskLevel describes how disruptive removing an item is
+type RiskLevel int
+
+const (
+	// RiskLow items are traces the user is unlikely to miss
+	RiskLow RiskLevel = iota
+	// RiskMedium items hold state such as logins, sessions or settings
+	RiskMedium
+	// RiskHigh items hold data that cannot be recreated, such as bookmarks or passwords
+	RiskHigh
+)
+
+// String returns the name of the risk level
+func (r RiskLevel) String() string {
+	switch r {
+	case RiskLow:
+		return "low"
+	case RiskMedium:
+		return "medium"
+	case RiskHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the risk level by name
+func (r RiskLevel) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a risk level name
+func (r *RiskLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*r = RiskLow
+	case "medium":
+		*r = RiskMedium
+	case "high":
+		*r = RiskHigh
+	default:
+		return fmt.Errorf("unknown risk level %q", text)
+	}
+	return nil
+}
+
+// Item describes a single thing a cleaner would remove
+type Item struct {
+	// ID is stable across dry-runs and is used to select items for cleaning
+	ID string `json:"id"`
+	// Cleaner is the name of the cleaner that owns the item
+	Cleaner string `json:"cleaner"`
+	// Category groups related items, e.g. "History" or "Cache"
+	Category string `json:"category"`
+	// Label is the human-readable description of the item
+	Label   string    `json:"label"`
+	Path    string    `json:"path,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Risk    RiskLevel `json:"risk"`
+}
+
+// String returns a display string such as "Chrome (/path/to/History)"
+func (i Item) String() string {
+	switch {
+	case i.Path != "" && i.Size > 0:
+		return fmt.Sprintf("%s (%s, %s)", i.Label, i.Path, formatSize(i.Size))
+	case i.Path != "":
+		return fmt.Sprintf("%s (%s)", i.Label, i.Path)
+	case i.Size > 0:
+		return fmt.Sprintf("%s (%s)", i.Label, formatSize(i.Size))
+	default:
+		return i.Label
+	}
+}
+
+// itemID builds a stable item ID from a cleaner prefix and identifying parts
+func itemID(prefix string, parts ...string) string {
+	id := prefix
+	for _, part := range parts {
+		id += ":" + strings.ToLower(strings.ReplaceAll(part, " ", "-"))
+	}
+	return id
+}
+
+// fileItem builds an item for a file or directory, filling in size and
+// modification time from the filesystem when available.
+func fileItem(id, cleanerName, category, label, path string) Item {
+	item := Item{
+		ID:       id,
+		Cleaner:  cleanerName,
+		Category: category,
+		Label:    label,
+		Path:     path,
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		item.ModTime = info.ModTime()
+		if info.IsDir() {
+			item.Size = getDirSize(path)
+		} else {
+			item.Size = info.Size()
+		}
+	}
+
+	return item
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// removeAllContext removes a file or tree like os.RemoveAll, checking the
+// context between entries so that large trees can be left half removed.
+// Symbolic links are removed, never followed.
+func removeAllContext(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(path); err != nil || !info.IsDir() {
+		return os.RemoveAll(path)
+	}
+	if entries, err := os.ReadDir(path); err == nil {
+		for _, entry := range entries {
+			child := filepath.Join(path, entry.Name())
+			if entry.IsDir() {
+				if err := removeAllContext(ctx, child); err != nil && ctx.Err() != nil {
+					return err
+				}
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			os.Remove(child)
+		}
+	}
+	// Whatever could not be removed above, such as read-only directories,
+	// comes with RemoveAll's error
+	return os.RemoveAll(path)
 }
 
 // CleanResult holds the result of a cleaning operation
 type CleanResult struct {
-	CleanerName string
+	CleanerName  string
 	ItemsCleaned int
-	BytesFreed int64
-	Error error
+	BytesFreed   int64
+	Error        error
 }
 
 // CleanerManager manages multiple cleaners
 type CleanerManager struct {
-	cleaners []Cleaner
+	cleaners    []Cleaner
+	concurrency int
 }
 
 // NewCleanerManager creates a new cleaner manager
 func NewCleanerManager() *CleanerManager {
 	return &CleanerManager{
-		cleaners: make([]Cleaner, 0),
+		cleaners:    make([]Cleaner, 0),
+		concurrency: DefaultConcurrency,
 	}
 }
 
@@ -48,12 +229,109 @@ func (cm *CleanerManager) GetCleaners() []Cleaner {
 	return cm.cleaners
 }
 
-// DryRunAll runs dry-run on all cleaners and returns a summary
-func (cm *CleanerManager) DryRunAll() (map[string][]string, error) {
-	results := make(map[string][]string)
+// RunningPrograms returns, by cleaner name, the running programs that use the
+// cleaner's files. Cleaning while they run may fail or be undone when they
+// write their state back.
+func (cm *CleanerManager) RunningPrograms(scanner *process.Scanner) (map[string][]string, error) {
+	return cm.runningPrograms(scanner, func(process.Process) bool { return true })
+}
+
+// ParentPrograms returns, by cleaner name, the programs among
+// RunningPrograms that gowipeme itself runs under (see process.Scanner.Self),
+// typically the shell it was started from. They cannot be waited for, and
+// a shell writes its history back when it exits; see ParentAdvice.
+func (cm *CleanerManager) ParentPrograms(scanner *process.Scanner) (map[string][]string, error) {
+	return cm.runningPrograms(scanner, func(p process.Process) bool { return p.Ancestor })
+}
+
+// ParentAdvice tells the user how to keep a parent shell from undoing the
+// clean of its history when it exits
+const ParentAdvice = "run `unset HISTFILE` in it (or `history -c` in bash), or close it with `kill -9 $$`, so it does not write its history back on exit"
+
+// WaitForPrograms waits until no program using the cleaners' files runs,
+// checking every interval. Parent programs are not waited for, as they
+// cannot exit first. It stops with the context's error when the context
+// is cancelled.
+func (cm *CleanerManager) WaitForPrograms(ctx context.Context, scanner *process.Scanner, interval time.Duration) error {
+	for {
+		running, err := cm.runningPrograms(scanner, func(p process.Process) bool { return !p.Ancestor })
+		if err != nil {
+			return err
+		}
+		if len(running) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// runningPrograms returns, by cleaner name, the running programs that use
+// the cleaner's files and pass keep
+func (cm *CleanerManager) runningPrograms(scanner *process.Scanner, keep func(process.Process) bool) (map[string][]string, error) {
+	procs, err := scanner.List()
+	if err != nil {
+		return nil, err
+	}
+
+	running := make(map[string][]string)
+	for _, cleaner := range cm.cleaners {
+		aware, ok := cleaner.(ProcessAware)
+		if !ok {
+			continue
+		}
+		names := aware.Processes()
+		matched := make([]process.Process, 0)
+		for _, p := range procs {
+			if keep(p) && p.Matches(names) {
+				matched = append(matched, p)
+			}
+		}
+		if len(matched) > 0 {
+			running[cleaner.Name()] = process.Names(matched)
+		}
+	}
+
+	return running, nil
+}
+
+// DryRunAll runs dry-run on all cleaners and returns their items by cleaner name
+func (cm *CleanerManager) DryRunAll() (map[string][]Item, error) {
+	return cm.DryRunAllStream(context.Background(), nil)
+}
+
+// DryRunAllStream runs dry-run on all cleaners like DryRunAll and also
+// passes every item to found as soon as it is known: while Streamer cleaners
+// are still scanning, and after DryRun returns for the others. found may be
+// nil. It stops with the context's error when the context is cancelled.
+func (cm *CleanerManager) DryRunAllStream(ctx context.Context, found func(Item)) (map[string][]Item, error) {
+	results := make(map[string][]Item)
 
 	for _, cleaner := range cm.cleaners {
-		items, err := cleaner.DryRun()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var items []Item
+		var err error
+		if streamer, ok := cleaner.(Streamer); ok {
+			err = streamer.DryRunStream(ctx, func(item Item) {
+				items = append(items, item)
+				if found != nil {
+					found(item)
+				}
+			})
+			sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+		} else {
+			items, err = cleaner.DryRun()
+			if err == nil && found != nil {
+				for _, item := range items {
+					found(item)
+				}
+			}
+		}
 		if err != nil {
 			return nil, fmt.Errorf("%s dry-run failed: %w", cleaner.Name(), err)
 		}
@@ -67,45 +345,58 @@ func (cm *CleanerManager) DryRunAll() (map[string][]string, error) {
 
 // CleanAll runs all cleaners and returns results
 func (cm *CleanerManager) CleanAll() []CleanResult {
-	results := make([]CleanResult, 0, len(cm.cleaners))
+	return cm.CleanAllContext(context.Background(), nil)
+}
 
-	for _, cleaner := range cm.cleaners {
-		result := CleanResult{
-			CleanerName: cleaner.Name(),
-		}
+// CleanAllContext runs all cleaners like CleanAll, several at a time (see
+// SetConcurrency), reporting their progress. When the context is cancelled,
+// running cleaners stop if they are ContextCleaners and the remaining
+// cleaners are not run.
+func (cm *CleanerManager) CleanAllContext(ctx context.Context, report event.Func) []CleanResult {
+	return cm.clean(ctx, nil, report)
+}
 
-		// Get items before cleaning to count them
-		items, err := cleaner.DryRun()
-		if err != nil {
-			result.Error = err
-			results = append(results, result)
-			continue
-		}
+// CleanSelected cleans only the items with the given IDs. Cleaners without
+// any selected items are skipped and do not appear in the results.
+func (cm *CleanerManager) CleanSelected(ids []string) []CleanResult {
+	return cm.CleanSelectedContext(context.Background(), ids, nil)
+}
 
-		result.ItemsCleaned = len(items)
+// CleanSelectedContext cleans only the items with the given IDs like
+// CleanSelected, reporting progress and stopping like CleanAllContext
+func (cm *CleanerManager) CleanSelectedContext(ctx context.Context, ids []string, report event.Func) []CleanResult {
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+	return cm.clean(ctx, selected, report)
+}
 
-		// Perform actual cleaning
-		err = cleaner.Clean()
-		if err != nil {
-			result.Error = err
+// filterItems returns the items whose IDs are selected
+func filterItems(items []Item, selected map[string]bool) []Item {
+	filtered := make([]Item, 0, len(items))
+	for _, item := range items {
+		if selected[item.ID] {
+			filtered = append(filtered, item)
 		}
-
-		results = append(results, result)
 	}
-
-	return results
+	return filtered
 }
 
 // Summary returns a formatted summary of dry-run results
-func Summary(dryRunResults map[string][]string) string {
+func Summary(dryRunResults map[string][]Item) string {
 	var sb strings.Builder
 
 	totalItems := 0
+	var totalSize int64
 	for _, items := range dryRunResults {
 		totalItems += len(items)
+		for _, item := range items {
+			totalSize += item.Size
+		}
 	}
 
-	sb.WriteString(fmt.Sprintf("Total items to clean: %d\n\n", totalItems))
+	sb.WriteString(fmt.Sprintf("Total items to clean: %d (%s)\n\n", totalItems, formatSize(totalSize)))
 
 	names := make([]string, 0, len(dryRunResults))
 	for cleanerName := range dryRunResults {
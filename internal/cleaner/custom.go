@@ -0,0 +1,426 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/cleanerdef"
+	"github.com/mat/gowipeme/internal/event"
+	"github.com/mat/gowipeme/internal/shellhist"
+	"github.com/mat/gowipeme/internal/wiper"
+)
+
+// CustomCleaner runs a declarative cleaner definition from
+// ~/.gowipeme/cleaners.d/. Each rule that applies on this system becomes
+// one item covering all the files it matches.
+type CustomCleaner struct {
+	def   *cleanerdef.Definition
+	rules []cleanerdef.Rule
+}
+
+// NewCustomCleaner creates a cleaner from a validated definition
+func NewCustomCleaner(def *cleanerdef.Definition) *CustomCleaner {
+	return &CustomCleaner{def: def, rules: def.RulesFor(runtime.GOOS)}
+}
+
+// ErrDefinitionSkipped starts the errors of definitions AddCustomCleaners
+// leaves out
+var ErrDefinitionSkipped = errors.New("skipped cleaner definition")
+
+// LoadCustomCleaners creates cleaners from the definitions in
+// ~/.gowipeme/cleaners.d/. Definitions with errors are left out and their
+// errors returned.
+func LoadCustomCleaners() ([]*CustomCleaner, []error) {
+	dir, err := cleanerdef.Dir()
+	if err != nil {
+		return nil, []error{err}
+	}
+	defs, errs := cleanerdef.LoadDir(dir)
+	cleaners := make([]*CustomCleaner, 0, len(defs))
+	for _, def := range defs {
+		cleaners = append(cleaners, NewCustomCleaner(def))
+	}
+	return cleaners, errs
+}
+
+// AddCustomCleaners adds the cleaners defined in ~/.gowipeme/cleaners.d/
+// after the registered ones. Definitions that fail to load or reuse the
+// name of a registered cleaner are skipped and reported wrapping
+// ErrDefinitionSkipped. Parts of imported CleanerML files that were left
+// out are reported too.
+func (cm *CleanerManager) AddCustomCleaners() []error {
+	custom, loadErrs := LoadCustomCleaners()
+	errs := make([]error, 0, len(loadErrs))
+	for _, err := range loadErrs {
+		errs = append(errs, fmt.Errorf("%w %v", ErrDefinitionSkipped, err))
+	}
+
+	names := make(map[string]bool, len(cm.cleaners))
+	for _, c := range cm.cleaners {
+		names[strings.ToLower(c.Name())] = true
+	}
+	for _, c := range custom {
+		file := filepath.Base(c.def.File)
+		if names[strings.ToLower(c.Name())] {
+			errs = append(errs, fmt.Errorf("%w %s: name: %q is already used by a built-in cleaner", ErrDefinitionSkipped, file, c.Name()))
+			continue
+		}
+		for _, warning := range c.def.Warnings {
+			errs = append(errs, fmt.Errorf("cleaner definition %s: %s", file, warning))
+		}
+		cm.AddCleaner(c)
+	}
+
+	return errs
+}
+
+// Name returns the name of the definition
+func (cc *CustomCleaner) Name() string {
+	return cc.def.Name
+}
+
+// Definition returns the definition the cleaner runs
+func (cc *CustomCleaner) Definition() *cleanerdef.Definition {
+	return cc.def
+}
+
+// RuleCount returns how many rules apply on this system
+func (cc *CustomCleaner) RuleCount() int {
+	return len(cc.rules)
+}
+
+// Processes returns the definition's program names
+func (cc *CustomCleaner) Processes() []string {
+	return cc.def.Processes
+}
+
+// Locks returns the directories above the globs of every rule
+func (cc *CustomCleaner) Locks() []string {
+	locks := make([]string, 0)
+	for _, rule := range cc.rules {
+		for _, pattern := range rule.Paths {
+			if expanded, err := cleanerdef.Expand(pattern); err == nil {
+				locks = append(locks, cleanerdef.Base(expanded))
+			}
+		}
+	}
+	return locks
+}
+
+// matches returns the files a rule applies to, sorted. Patterns using
+// variables not set on this system match nothing; directories only match
+// rules that remove them, and recursive rules match the files below them.
+func (cc *CustomCleaner) matches(rule cleanerdef.Rule) []string {
+	seen := make(map[string]bool)
+	paths := make([]string, 0)
+	for _, pattern := range rule.Paths {
+		expanded, err := cleanerdef.Expand(pattern)
+		if err != nil || cleanerdef.TooBroad(cleanerdef.Base(expanded)) {
+			continue
+		}
+		found, err := filepath.Glob(expanded)
+		if err != nil {
+			continue
+		}
+		for _, path := range found {
+			if seen[path] || cleanerdef.TooBroad(path) {
+				continue
+			}
+			info, err := os.Lstat(path)
+			if err != nil {
+				continue
+			}
+			if rule.Recurse && info.IsDir() {
+				// WalkDir does not follow symbolic links
+				filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+					if err == nil && entry.Type().IsRegular() && !seen[file] {
+						seen[file] = true
+						paths = append(paths, file)
+					}
+					return nil
+				})
+				continue
+			}
+			if rule.Action != cleanerdef.Delete && rule.Action != cleanerdef.Shred && !info.Mode().IsRegular() {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ruleID returns the item ID of the rule at index i
+func (cc *CustomCleaner) ruleID(i int) string {
+	return itemID("custom", cc.def.Name, strconv.Itoa(i))
+}
+
+// DryRun returns an item for every rule with something to remove
+func (cc *CustomCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0, len(cc.rules))
+
+	for i, rule := range cc.rules {
+		paths := cc.matches(rule)
+		if len(paths) == 0 {
+			continue
+		}
+
+		item := Item{
+			ID:       cc.ruleID(i),
+			Cleaner:  cc.Name(),
+			Category: rule.Category,
+			Label:    rule.Description,
+		}
+		if item.Category == "" {
+			item.Category = "Custom"
+		}
+		if item.Label == "" {
+			item.Label = strings.Join(rule.Paths, ", ")
+		}
+		if rule.Risk != "" {
+			_ = item.Risk.UnmarshalText([]byte(rule.Risk))
+		}
+		if len(paths) == 1 {
+			item.Path = paths[0]
+		}
+
+		count, unit := 0, ""
+		switch rule.Action {
+		case cleanerdef.Delete, cleanerdef.Shred, cleanerdef.Truncate:
+			scanner := newSizeScanner(context.Background())
+			for _, path := range paths {
+				stats := scanner.stats(path)
+				item.Size += stats.size
+				if stats.modTime.After(item.ModTime) {
+					item.ModTime = stats.modTime
+				}
+			}
+			if rule.Action == cleanerdef.Truncate && item.Size == 0 {
+				continue
+			}
+			count, unit = len(paths), "files"
+		case cleanerdef.SQLiteVacuum:
+			scanner := newSizeScanner(context.Background())
+			for _, path := range paths {
+				item.Size += scanner.stats(path).size
+			}
+			count, unit = len(paths), "files"
+		case cleanerdef.SQLiteDeleteWhere:
+			for _, path := range paths {
+				count += int(countSQLiteWhere(path, rule))
+			}
+			unit = "rows"
+		case cleanerdef.JSONKeyDelete:
+			for _, path := range paths {
+				if data, err := os.ReadFile(path); err == nil {
+					if _, n, err := deleteJSONKeys(data, rule.Keys); err == nil {
+						count += n
+					}
+				}
+			}
+			unit = "keys"
+		}
+		if count == 0 {
+			continue
+		}
+		if count > 1 || unit != "files" {
+			item.Label = fmt.Sprintf("%s (%d %s)", item.Label, count, unit)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Clean applies every rule
+func (cc *CustomCleaner) Clean() error {
+	items, err := cc.DryRun()
+	if err != nil {
+		return err
+	}
+	return cc.CleanItems(items)
+}
+
+// CleanItems applies the rules of the given items
+func (cc *CustomCleaner) CleanItems(items []Item) error {
+	return cc.CleanItemsContext(context.Background(), items, nil)
+}
+
+// CleanItemsContext applies the rules of the given items until the context
+// is cancelled
+func (cc *CustomCleaner) CleanItemsContext(ctx context.Context, items []Item, report event.Func) error {
+	errors := make([]error, 0)
+
+	for i, item := range items {
+		for r, rule := range cc.rules {
+			if cc.ruleID(r) != item.ID {
+				continue
+			}
+			for _, path := range cc.matches(rule) {
+				err := applyRule(ctx, rule, path)
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if err != nil {
+					errors = append(errors, fmt.Errorf("%s: %w", path, err))
+				}
+			}
+		}
+		report.Send(event.Event{Kind: event.Progress, Step: item.Label, Done: int64(i + 1)})
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to clean some files: %v", errors)
+	}
+
+	return nil
+}
+
+// applyRule applies a rule's action to one matching file
+func applyRule(ctx context.Context, rule cleanerdef.Rule, path string) error {
+	switch rule.Action {
+	case cleanerdef.Delete:
+		err := removeAllContext(ctx, path)
+		forgetStats(path)
+		return err
+	case cleanerdef.Shred:
+		method := wiper.SinglePassZeros
+		if rule.Method != "" {
+			method, _ = wiper.ParseMethod(rule.Method)
+		}
+		err := wiper.ShredTreeContext(ctx, path, method)
+		forgetStats(path)
+		return err
+	case cleanerdef.Truncate:
+		return os.Truncate(path, 0)
+	case cleanerdef.SQLiteDeleteWhere:
+		return deleteSQLiteWhere(path, rule)
+	case cleanerdef.SQLiteVacuum:
+		db, err := openSQLite(path)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return secureVacuum(db)
+	case cleanerdef.JSONKeyDelete:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out, removed, err := deleteJSONKeys(data, rule.Keys)
+		if err != nil || removed == 0 {
+			return err
+		}
+		return shellhist.WriteFileAtomic(path, out)
+	default:
+		return fmt.Errorf("unknown action %q", rule.Action)
+	}
+}
+
+// countSQLiteWhere counts the rows a sqlite-delete-where rule would delete
+func countSQLiteWhere(path string, rule cleanerdef.Rule) int64 {
+	db, err := openSQLite(path)
+	if err != nil {
+		return 0
+	}
+	defer db.Close()
+	if !tableExists(db, rule.Table) {
+		return 0
+	}
+	return countRows(db, fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE %s`, rule.Table, rule.Where))
+}
+
+// deleteSQLiteWhere deletes the matching rows and vacuums the database so
+// they do not survive in free pages
+func deleteSQLiteWhere(path string, rule cleanerdef.Rule) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if !tableExists(db, rule.Table) {
+		return nil
+	}
+	if _, err := db.Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE %s`, rule.Table, rule.Where)); err != nil {
+		return err
+	}
+	return secureVacuum(db)
+}
+
+// deleteJSONKeys removes dot-separated keys from a JSON object, keeping the
+// file's indentation. It returns the new contents and how many keys existed.
+func deleteJSONKeys(data []byte, keys []string) ([]byte, int, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	removed := 0
+	for _, key := range keys {
+		if deleteJSONKey(doc, strings.Split(key, ".")) {
+			removed++
+		}
+	}
+	if removed == 0 {
+		return data, 0, nil
+	}
+
+	indent := jsonIndent(data)
+	if indent == "" {
+		out, err := json.Marshal(doc)
+		return out, removed, err
+	}
+	out, err := json.MarshalIndent(doc, "", indent)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append(out, '\n'), removed, nil
+}
+
+// deleteJSONKey removes a key path from an object, rewriting the nested
+// objects on the way, and reports whether it existed
+func deleteJSONKey(doc map[string]json.RawMessage, path []string) bool {
+	value, ok := doc[path[0]]
+	if !ok {
+		return false
+	}
+	if len(path) == 1 {
+		delete(doc, path[0])
+		return true
+	}
+
+	var child map[string]json.RawMessage
+	if json.Unmarshal(value, &child) != nil || !deleteJSONKey(child, path[1:]) {
+		return false
+	}
+	out, err := json.Marshal(child)
+	if err != nil {
+		return false
+	}
+	doc[path[0]] = out
+	return true
+}
+
+// jsonIndent returns the indentation of the first indented line, or "" for
+// JSON written on one line
+func jsonIndent(data []byte) string {
+	lines := strings.SplitN(string(data), "\n", 3)
+	if len(lines) < 2 {
+		return ""
+	}
+	line := lines[1]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
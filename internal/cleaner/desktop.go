@@ -0,0 +1,124 @@
+package cleaner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/xbel"
+)
+
+// desktopTrace is an activity trace kept by a Linux desktop environment
+type desktopTrace struct {
+	Desktop   string   // "KDE", "GNOME" or "GTK", shown in labels
+	Dir       string   // directory the category's files are relative to
+	Processes []string // daemons that keep the trace open
+	profileCategory
+}
+
+// desktopProcesses are the daemons of the desktop traces that were found
+func desktopProcesses(traces []desktopTrace) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, t := range traces {
+		for _, name := range t.Processes {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// countPlaces describes a KDE user-places.xbel
+func countPlaces(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	bookmarks, err := xbel.Parse(data)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d places", len(bookmarks))
+}
+
+// countLines describes a GTK bookmarks file
+func countLines(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	count := 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			count++
+		}
+	}
+	return fmt.Sprintf("%d bookmarks", count)
+}
+
+// desktopTraces returns the KDE, GNOME and GTK activity traces that exist.
+// KDE places and GTK bookmarks are made by hand, not recorded, so they are
+// only included with places.
+func desktopTraces(places bool) []desktopTrace {
+	traces := make([]desktopTrace, 0)
+	add := func(desktop string, full string, err error, processes []string, c profileCategory) {
+		if err != nil {
+			return
+		}
+		c.Files = []string{filepath.Base(full)}
+		t := desktopTrace{Desktop: desktop, Dir: filepath.Dir(full), Processes: processes, profileCategory: c}
+		if t.path(t.Dir) != "" {
+			traces = append(traces, t)
+		}
+	}
+
+	// KDE
+	path, err := platform.GetKDERecentDocumentsPath()
+	add("KDE", path, err, nil, profileCategory{ID: "kde:recent-documents", Category: "KDE Activity",
+		Label: "recent documents"})
+	path, err = platform.GetKActivitiesDatabasePath()
+	add("KDE", path, err, []string{"kactivitymanagerd"}, profileCategory{ID: "kde:activities", Category: "KDE Activity",
+		Label: "file and application usage (kactivitymanagerd)", Count: "SELECT COUNT(*) FROM ResourceEvent", Unit: "events",
+		// ResourceLink holds the favourites pinned in the launcher
+		Rows: []string{"ResourceEvent", "ResourceScoreCache", "ResourceInfo"}})
+	if places {
+		path, err = platform.GetKDEPlacesPath()
+		add("KDE", path, err, nil, profileCategory{ID: "kde:places", Category: "KDE Places",
+			Label: "file dialog places (user-places.xbel)", Describe: countPlaces, Risk: RiskHigh})
+	}
+
+	// GNOME
+	path, err = platform.GetZeitgeistPath()
+	if err == nil {
+		add("GNOME", filepath.Join(path, "activity.sqlite"), nil, []string{"zeitgeist-daemon"}, profileCategory{ID: "gnome:zeitgeist",
+			Category: "GNOME Activity", Label: "activity log (zeitgeist)", Count: "SELECT COUNT(*) FROM event", Unit: "events",
+			Rows: []string{"event", "uri", "text", "payload"}})
+		add("GNOME", filepath.Join(path, "fts.index"), nil, []string{"zeitgeist-fts"}, profileCategory{ID: "gnome:zeitgeist-index",
+			Category: "GNOME Activity", Label: "activity search index (zeitgeist)"})
+	}
+	if paths, err := platform.GetTrackerCachePaths(); err == nil {
+		for _, path := range paths {
+			// tracker3/files or tracker
+			name := filepath.Base(path)
+			if name == "files" {
+				name = filepath.Base(filepath.Dir(path))
+			}
+			add("GNOME", path, nil, []string{"tracker-miner-fs-3", "localsearch-3", "tracker-miner-fs"}, profileCategory{
+				ID: "gnome:" + name, Category: "GNOME Activity", Label: "file search index (" + name + ")"})
+		}
+	}
+
+	// GTK file choosers are used by GNOME, Xfce, Cinnamon and MATE alike
+	if places {
+		path, err = platform.GetGTKBookmarksPath()
+		add("GTK", path, err, nil, profileCategory{ID: "gtk:bookmarks", Category: "GTK File Chooser",
+			Label: "file chooser bookmarks", Describe: countLines, Risk: RiskHigh})
+	}
+
+	return traces
+}
@@ -0,0 +1,250 @@
+package cleaner
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// DomainRules selects browser data by site. A pattern such as "example.com"
+// matches that host and its subdomains; patterns containing * or ? are
+// matched as globs against the whole host, e.g. "*.corp.example".
+type DomainRules struct {
+	// Remove lists the sites to remove. When empty and Keep is set, every
+	// site not kept is removed.
+	Remove []string
+	// Keep lists sites that are never removed, even if a Remove rule matches
+	Keep []string
+}
+
+// IsZero reports whether no rules are set
+func (d DomainRules) IsZero() bool {
+	return len(d.Remove) == 0 && len(d.Keep) == 0
+}
+
+// Rules returns the remove rules, or "*" for a keep-list only configuration
+func (d DomainRules) Rules() []string {
+	if len(d.Remove) == 0 && len(d.Keep) > 0 {
+		return []string{"*"}
+	}
+	return d.Remove
+}
+
+// Matcher returns a function reporting whether a host is removed by rule
+func (d DomainRules) Matcher(rule string) hostMatcher {
+	return func(host string) bool {
+		if !matchDomain(rule, host) {
+			return false
+		}
+		for _, keep := range d.Keep {
+			if matchDomain(keep, host) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// hostMatcher reports whether data for a host is selected
+type hostMatcher func(host string) bool
+
+// matchDomain reports whether host matches a domain pattern
+func matchDomain(pattern, host string) bool {
+	pattern = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	host = strings.TrimPrefix(strings.ToLower(host), ".")
+
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, err := path.Match(pattern, host)
+		return err == nil && ok
+	}
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// urlHost returns the lowercase host of a URL, or "" if it has none
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// historyStats counts the browser entries matched by a cleaning operation
+type historyStats struct {
+	Pages     int64
+	Visits    int64
+	Downloads int64
+	Cookies   int64
+	Favicons  int64
+}
+
+// total returns the number of matched entries
+func (s historyStats) total() int64 {
+	return s.Pages + s.Visits + s.Downloads + s.Cookies + s.Favicons
+}
+
+// String returns a summary such as "12 pages, 40 visits, 3 cookies"
+func (s historyStats) String() string {
+	parts := make([]string, 0, 5)
+	for _, part := range []struct {
+		n    int64
+		name string
+	}{
+		{s.Pages, "pages"},
+		{s.Visits, "visits"},
+		{s.Downloads, "downloads"},
+		{s.Cookies, "cookies"},
+		{s.Favicons, "favicons"},
+	} {
+		if part.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", part.n, part.name))
+		}
+	}
+	if len(parts) == 0 {
+		return "no entries"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// siteItem builds the item for the data a domain rule matches in a profile
+func siteItem(id, cleanerName, profile, rule string, stats historyStats, r TimeRange, path string) Item {
+	label := fmt.Sprintf("%s - %s: %s", profile, rule, stats)
+	if rule == "*" {
+		label = fmt.Sprintf("%s - all sites not kept: %s", profile, stats)
+	}
+	if !r.IsZero() {
+		label += fmt.Sprintf(" (%s)", r)
+	}
+
+	item := fileItem(id, cleanerName, "Site Data", label, path)
+	// Pruning frees rows rather than the file, so the size is not reclaimed
+	item.Size = 0
+	return item
+}
+
+// fillScope creates the temporary table temp.<name> holding the ids selected
+// by query (which must return an id and a value) whose value matches.
+func fillScope(db sqlConn, name, query string, match func(value string) bool) error {
+	if err := execAll(db,
+		"CREATE TEMP TABLE IF NOT EXISTS "+name+" (id INTEGER PRIMARY KEY)",
+		"DELETE FROM temp."+name,
+	); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		var value sql.NullString
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		if match(value.String) {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := db.Exec("INSERT OR IGNORE INTO temp."+name+" (id) VALUES (?)", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostTable describes rows of a browser database that are selected by host,
+// such as cookies or favicon mappings.
+type hostTable struct {
+	Table      string
+	Column     string // column holding a URL, or a cookie host when IsURL is false
+	IsURL      bool
+	TimeColumn string // optional; limits rows to the time range
+	ToDB       func(time.Time) int64
+	// Cleanup statements remove rows orphaned by the delete
+	Cleanup []string
+}
+
+// scope selects the matching rows into temp.scope_rows and returns the
+// condition limiting the table to them
+func (t hostTable) scope(db sqlConn, r TimeRange, match hostMatcher) (string, []interface{}, error) {
+	value := func(v string) bool { return match(strings.TrimPrefix(v, ".")) }
+	if t.IsURL {
+		value = func(v string) bool { return match(urlHost(v)) }
+	}
+	if err := fillScope(db, "scope_rows", "SELECT rowid, "+t.Column+" FROM "+t.Table, value); err != nil {
+		return "", nil, err
+	}
+
+	where := "rowid IN (SELECT id FROM temp.scope_rows)"
+	if t.TimeColumn == "" {
+		return where, nil, nil
+	}
+	timeWhere, args := r.where(t.TimeColumn, t.ToDB)
+	return where + " AND " + timeWhere, args, nil
+}
+
+// count returns how many rows of the database at path match
+func (t hostTable) count(path string, r TimeRange, match hostMatcher) (int64, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if !tableExists(db, t.Table) {
+		return 0, nil
+	}
+	where, args, err := t.scope(db, r, match)
+	if err != nil {
+		return 0, err
+	}
+	return countRows(db, "SELECT COUNT(*) FROM "+t.Table+" WHERE "+where, args...), nil
+}
+
+// prune deletes the matching rows from the database at path and vacuums it
+func (t hostTable) prune(path string, r TimeRange, match hostMatcher) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if !tableExists(db, t.Table) {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args, err := t.scope(tx, r, match)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM "+t.Table+" WHERE "+where, args...); err != nil {
+		return fmt.Errorf("failed to delete from %s: %w", t.Table, err)
+	}
+	if err := execAll(tx, t.Cleanup...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return secureVacuum(db)
+}
@@ -0,0 +1,367 @@
+package cleaner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/editors"
+	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/process"
+	"github.com/mat/gowipeme/internal/shellhist"
+)
+
+// editorTrace is a file or directory in which an editor records history.
+// Exactly one way of cleaning applies: Rewrite for state files, Keys for
+// SQLite key-value stores, or removing the contents of a directory.
+type editorTrace struct {
+	ID     string
+	Editor string
+	Label  string // what is removed, e.g. "command and search history"
+	Path   string
+	Unit   string
+	// Rewrite returns the file contents without the history and the number
+	// of entries removed
+	Rewrite func(data []byte) ([]byte, int, error)
+	// Keys are deleted from the ItemTable of an SQLite state database
+	Keys []string
+	// Swap marks the swap files of open buffers, which are left alone
+	// while the editor runs
+	Swap bool
+}
+
+// editorProcesses maps editors to the executables that write their traces
+var editorProcesses = map[string][]string{
+	"Vim":       {"vim", "gvim", "vi"},
+	"Neovim":    {"nvim"},
+	"Emacs":     {"emacs"},
+	"VS Code":   {"code", "Code"},
+	"VSCodium":  {"codium", "VSCodium"},
+	"JetBrains": {"idea", "pycharm", "goland", "webstorm", "clion", "rider", "phpstorm", "rubymine", "datagrip"},
+}
+
+// vscodeVariants maps VS Code builds to their configuration directory names
+var vscodeVariants = []struct{ editor, dir string }{
+	{"VS Code", "Code"},
+	{"VS Code", "Code - Insiders"},
+	{"VS Code", "Code - OSS"},
+	{"VSCodium", "VSCodium"},
+}
+
+// viminfo removes command, search and mark history from a viminfo file
+func viminfo(data []byte) ([]byte, int, error) {
+	out, removed := editors.CleanViminfo(data)
+	return out, removed, nil
+}
+
+// emacsRecentf clears recentf-list in an emacs recentf file
+func emacsRecentf(data []byte) ([]byte, int, error) {
+	return editors.ClearLispVariables(data, func(name string) bool { return name == "recentf-list" })
+}
+
+// emacsSavehist clears the minibuffer, search and command history lists
+// saved by savehist-mode
+func emacsSavehist(data []byte) ([]byte, int, error) {
+	return editors.ClearLispVariables(data, func(name string) bool {
+		return strings.HasSuffix(name, "-history") || strings.HasSuffix(name, "-ring")
+	})
+}
+
+// EditorCleaner removes recent files, search and command history from
+// editor state without touching their settings
+type EditorCleaner struct {
+	traces []editorTrace
+}
+
+// NewEditorCleaner creates a new editor history cleaner
+func NewEditorCleaner() *EditorCleaner {
+	ec := &EditorCleaner{}
+	ec.discoverTraces()
+	return ec
+}
+
+// discoverTraces finds the editor state files and directories that exist
+func (ec *EditorCleaner) discoverTraces() {
+	add := func(t editorTrace) {
+		info, err := os.Stat(t.Path)
+		if err != nil {
+			return
+		}
+		isDir := t.Rewrite == nil && t.Keys == nil
+		if info.IsDir() == isDir {
+			ec.traces = append(ec.traces, t)
+		}
+	}
+
+	// Vim
+	if path, err := platform.GetViminfoPath(); err == nil {
+		add(editorTrace{ID: "viminfo", Editor: "Vim", Label: "history and marks (viminfo)", Path: path, Unit: "entries", Rewrite: viminfo})
+	}
+	if home, err := platform.GetHomeDir(); err == nil {
+		// Backups are copies of the user's files, not history
+		for _, dir := range []string{"swap", "undo", "undodir"} {
+			add(editorTrace{ID: dir, Editor: "Vim", Label: dir + " files", Path: filepath.Join(home, ".vim", dir), Unit: "files", Swap: dir == "swap"})
+		}
+	}
+
+	// Neovim moved swap, undo and shada from the data to the state directory
+	roots := make([]string, 0, 2)
+	if dir, err := platform.GetNeovimStatePath(); err == nil {
+		roots = append(roots, dir)
+	}
+	if dir, err := platform.GetNeovimDataPath(); err == nil && (len(roots) == 0 || dir != roots[0]) {
+		roots = append(roots, dir)
+	}
+	for i, root := range roots {
+		prefix := ""
+		if i > 0 {
+			prefix = "legacy-"
+		}
+		shadas, _ := filepath.Glob(filepath.Join(root, "shada", "*.shada"))
+		for _, path := range shadas {
+			add(editorTrace{ID: prefix + "shada:" + filepath.Base(path), Editor: "Neovim", Label: "history and marks (" + filepath.Base(path) + ")",
+				Path: path, Unit: "entries", Rewrite: editors.CleanShada})
+		}
+		for _, dir := range []string{"swap", "undo"} {
+			add(editorTrace{ID: prefix + dir, Editor: "Neovim", Label: dir + " files", Path: filepath.Join(root, dir), Unit: "files", Swap: dir == "swap"})
+		}
+	}
+
+	// Emacs reads ~/.emacs.d, or $XDG_CONFIG_HOME/emacs when that is missing
+	emacsDirs := make([]string, 0, 2)
+	if dir, err := platform.ExpandPath("~/.emacs.d"); err == nil {
+		emacsDirs = append(emacsDirs, dir)
+	}
+	if config, err := platform.GetConfigHomePath(); err == nil {
+		emacsDirs = append(emacsDirs, filepath.Join(config, "emacs"))
+	}
+	for _, dir := range emacsDirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		add(editorTrace{ID: "recentf", Editor: "Emacs", Label: "recent files (recentf)",
+			Path: filepath.Join(dir, "recentf"), Unit: "lists", Rewrite: emacsRecentf})
+		add(editorTrace{ID: "savehist", Editor: "Emacs", Label: "minibuffer and search history (savehist)",
+			Path: filepath.Join(dir, "history"), Unit: "lists", Rewrite: emacsSavehist})
+		break
+	}
+
+	appConfig, err := platform.GetAppConfigPath()
+	if err != nil {
+		return
+	}
+
+	// VS Code and its builds
+	for _, v := range vscodeVariants {
+		root := filepath.Join(appConfig, v.dir)
+		id := strings.ToLower(v.dir)
+		add(editorTrace{ID: id + ":storage", Editor: v.editor, Label: "recent workspaces (" + v.dir + " storage.json)",
+			Path: filepath.Join(root, "User", "globalStorage", "storage.json"), Unit: "entries", Rewrite: editors.CleanVSCodeStorage})
+		add(editorTrace{ID: id + ":legacy-storage", Editor: v.editor, Label: "recent workspaces (" + v.dir + " storage.json)",
+			Path: filepath.Join(root, "storage.json"), Unit: "entries", Rewrite: editors.CleanVSCodeStorage})
+		add(editorTrace{ID: id + ":state", Editor: v.editor, Label: "recent workspaces, command and search history (" + v.dir + " state.vscdb)",
+			Path: filepath.Join(root, "User", "globalStorage", "state.vscdb"), Unit: "keys", Keys: editors.VSCodeRecentKeys})
+	}
+
+	// JetBrains IDEs keep one configuration directory per product and version
+	for _, name := range []string{"recentProjects.xml", "recentSolutions.xml"} {
+		paths, _ := filepath.Glob(filepath.Join(appConfig, "JetBrains", "*", "options", name))
+		for _, path := range paths {
+			product := filepath.Base(filepath.Dir(filepath.Dir(path)))
+			add(editorTrace{ID: product + ":" + strings.TrimSuffix(name, ".xml"), Editor: "JetBrains",
+				Label: "recent projects (" + product + ")", Path: path, Unit: "projects", Rewrite: editors.CleanRecentProjects})
+		}
+	}
+}
+
+// Name returns the name of this cleaner
+func (ec *EditorCleaner) Name() string {
+	return "Editor History"
+}
+
+// Processes returns the editors whose traces were found
+func (ec *EditorCleaner) Processes() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, t := range ec.traces {
+		for _, name := range editorProcesses[t.Editor] {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// itemID returns the item ID of a trace
+func (t editorTrace) itemID() string {
+	return itemID("editor", t.Editor, t.ID)
+}
+
+// inUse reports whether the trace holds swap files of an editor in procs
+func (t editorTrace) inUse(procs []process.Process) bool {
+	if !t.Swap {
+		return false
+	}
+	for _, p := range procs {
+		if p.Matches(editorProcesses[t.Editor]) {
+			return true
+		}
+	}
+	return false
+}
+
+// DryRun returns the editor traces that contain history. Swap files are
+// left out while their editor runs, as they may hold unsaved changes.
+func (ec *EditorCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0, len(ec.traces))
+
+	// Without a procfs no editor is known to run
+	procs, _ := process.NewScanner().List()
+
+	for _, t := range ec.traces {
+		if t.inUse(procs) {
+			continue
+		}
+		count, err := t.clean(true)
+		if err != nil || count == 0 {
+			continue
+		}
+
+		item := fileItem(t.itemID(), ec.Name(), "Editor History",
+			fmt.Sprintf("%s - %s: %d %s", t.Editor, t.Label, count, t.Unit), t.Path)
+		if t.Rewrite != nil || t.Keys != nil {
+			item.Size = 0
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return items, nil
+}
+
+// clean removes the history from a trace, or only counts it when dryRun is
+// set. Directories count their files.
+func (t editorTrace) clean(dryRun bool) (int, error) {
+	switch {
+	case t.Rewrite != nil:
+		data, err := os.ReadFile(t.Path)
+		if err != nil {
+			return 0, err
+		}
+		out, removed, err := t.Rewrite(data)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(out, data) {
+			return 0, nil
+		}
+		if removed == 0 {
+			removed = 1
+		}
+		if dryRun {
+			return removed, nil
+		}
+		return removed, shellhist.WriteFileAtomic(t.Path, out)
+
+	case t.Keys != nil:
+		return cleanItemTable(t.Path, t.Keys, dryRun)
+
+	default:
+		entries, err := os.ReadDir(t.Path)
+		if err != nil || dryRun {
+			return len(entries), err
+		}
+		// The directory itself stays: vim refuses to write swap files into
+		// a missing 'directory'
+		errors := make([]error, 0)
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(t.Path, entry.Name())); err != nil {
+				errors = append(errors, err)
+			}
+		}
+		if len(errors) > 0 {
+			return len(entries), fmt.Errorf("%v", errors)
+		}
+		return len(entries), nil
+	}
+}
+
+// cleanItemTable deletes keys from the ItemTable of a VS Code style state
+// database, or counts them when dryRun is set
+func cleanItemTable(path string, keys []string, dryRun bool) (int, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if !tableExists(db, "ItemTable") {
+		return 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+
+	where := " FROM ItemTable WHERE key IN (" + placeholders + ")"
+	count := int(countRows(db, "SELECT COUNT(*)"+where, args...))
+	if dryRun || count == 0 {
+		return count, nil
+	}
+
+	if _, err := db.Exec("DELETE"+where, args...); err != nil {
+		return 0, fmt.Errorf("failed to delete from ItemTable: %w", err)
+	}
+	return count, secureVacuum(db)
+}
+
+// Clean removes the history from all discovered traces
+func (ec *EditorCleaner) Clean() error {
+	items, err := ec.DryRun()
+	if err != nil {
+		return err
+	}
+	return ec.CleanItems(items)
+}
+
+// CleanItems removes the history from the given traces
+func (ec *EditorCleaner) CleanItems(items []Item) error {
+	errors := make([]error, 0)
+
+	traces := make(map[string]editorTrace, len(ec.traces))
+	for _, t := range ec.traces {
+		traces[t.itemID()] = t
+	}
+
+	// The editor may have been started since the dry-run
+	procs, _ := process.NewScanner().List()
+
+	for _, item := range items {
+		t, ok := traces[item.ID]
+		if !ok {
+			continue
+		}
+		if t.inUse(procs) {
+			errors = append(errors, fmt.Errorf("%s %s: skipped, %s is running", t.Editor, t.Label, t.Editor))
+			continue
+		}
+		if _, err := t.clean(false); err != nil && !os.IsNotExist(err) {
+			errors = append(errors, fmt.Errorf("%s %s: %w", t.Editor, t.Label, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to clean some editor traces: %v", errors)
+	}
+
+	return nil
+}
@@ -0,0 +1,582 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mat/gowipeme/internal/browser"
+	"github.com/mat/gowipeme/internal/platform"
+)
+
+// FirefoxOptions configures how Firefox history is cleaned
+type FirefoxOptions struct {
+	// DeleteFile removes places.sqlite entirely, which also deletes every
+	// bookmark in the profile. By default only history rows are removed.
+	DeleteFile bool
+	// Range limits history cleaning to visits recorded in the range.
+	// The zero value covers all history. It is ignored with DeleteFile.
+	Range TimeRange
+	// Domains limits cleaning to matching sites, covering history,
+	// downloads, cookies and favicons. It is ignored with DeleteFile.
+	Domains DomainRules
+	// ProfileData offers each profile's form history, cookies, sessions
+	// and disk cache as separate items. Only history is listed otherwise,
+	// so a default clean keeps the user's logins and open tabs.
+	ProfileData bool
+}
+
+// FirefoxCleaner removes Firefox history while keeping bookmarks. Firefox
+// stores both in places.sqlite, so the database is edited in place.
+type FirefoxCleaner struct {
+	profiles  []browser.Profile
+	cacheDirs map[string]string // profile path -> disk cache directory
+	opts      FirefoxOptions
+}
+
+// NewFirefoxCleaner creates a new Firefox cleaner that preserves bookmarks
+func NewFirefoxCleaner() *FirefoxCleaner {
+	return NewFirefoxCleanerWithOptions(FirefoxOptions{})
+}
+
+// NewFirefoxCleanerWithOptions creates a new Firefox cleaner with the given options
+func NewFirefoxCleanerWithOptions(opts FirefoxOptions) *FirefoxCleaner {
+	fc := &FirefoxCleaner{opts: opts, cacheDirs: make(map[string]string)}
+
+	root, err := platform.GetFirefoxDataPath()
+	if err != nil {
+		return fc
+	}
+	cacheRoot, _ := platform.GetFirefoxCachePath()
+
+	// Every profile listed in profiles.ini that has a places database
+	if profiles, err := browser.FirefoxProfiles(root); err == nil {
+		for _, profile := range profiles {
+			if _, err := os.Stat(profile.File("places.sqlite")); err != nil {
+				continue
+			}
+			fc.profiles = append(fc.profiles, profile)
+
+			// The disk cache mirrors the profile's place under the data directory
+			if rel, err := filepath.Rel(root, profile.Path); err == nil && cacheRoot != "" && !strings.HasPrefix(rel, "..") {
+				fc.cacheDirs[profile.Path] = filepath.Join(cacheRoot, rel)
+			}
+		}
+	}
+
+	return fc
+}
+
+// firefoxCategories are the per-profile data offered besides history with
+// FirefoxOptions.ProfileData
+var firefoxCategories = []profileCategory{
+	{
+		ID: "form-history", Category: "Form History", Label: "form history",
+		Files: []string{"formhistory.sqlite"},
+		Count: "SELECT COUNT(*) FROM moz_formhistory", Unit: "entries",
+	},
+	{
+		ID: "cookies", Category: "Cookies", Label: "cookies",
+		Files: []string{"cookies.sqlite"},
+		Count: "SELECT COUNT(*) FROM moz_cookies", Unit: "cookies",
+		Risk: RiskMedium,
+	},
+	{
+		ID: "favicons", Category: "Favicons", Label: "favicons",
+		Files: []string{"favicons.sqlite"},
+		Count: "SELECT COUNT(*) FROM moz_icons", Unit: "icons",
+	},
+	{
+		ID: "session", Category: "Sessions", Label: "open windows and tabs",
+		Files:    []string{"sessionstore.jsonlz4"},
+		Describe: describeSessionStore,
+		Risk:     RiskMedium,
+	},
+	{
+		ID: "session-backups", Category: "Sessions", Label: "session backups",
+		Files:    []string{"sessionstore-backups"},
+		Describe: describeSessionBackups,
+		Risk:     RiskMedium,
+	},
+}
+
+// firefoxCache is the profile's disk cache, found under the cache directory
+var firefoxCache = profileCategory{
+	ID: "cache", Category: "Cache", Label: "disk cache",
+	Files:    []string{"cache2"},
+	Describe: describeFirefoxCache,
+}
+
+// describeSessionStore counts the windows and tabs a session store would lose
+func describeSessionStore(path string) string {
+	store, err := browser.ReadSessionStore(path)
+	if err != nil {
+		return ""
+	}
+	return store.String()
+}
+
+// describeSessionBackups counts the backup files and the tabs in the most
+// recent one
+func describeSessionBackups(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	summary := fmt.Sprintf("%d files", len(entries))
+	if store, err := browser.ReadSessionStore(filepath.Join(dir, "recovery.jsonlz4")); err == nil {
+		summary += fmt.Sprintf(", latest: %s", store)
+	}
+	return summary
+}
+
+// describeFirefoxCache counts the cache entries
+func describeFirefoxCache(dir string) string {
+	entries, err := os.ReadDir(filepath.Join(dir, "entries"))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d entries", len(entries))
+}
+
+// categoryItems returns the items for a profile's other data, if requested
+func (fc *FirefoxCleaner) categoryItems(profile browser.Profile, id, label string) []Item {
+	items := make([]Item, 0)
+	if !fc.opts.ProfileData {
+		return items
+	}
+	for _, category := range firefoxCategories {
+		if path := category.path(profile.Path); path != "" {
+			items = append(items, category.item(itemID(id, category.ID), fc.Name(), label, path))
+		}
+	}
+	if cacheDir, ok := fc.cacheDirs[profile.Path]; ok {
+		if path := firefoxCache.path(cacheDir); path != "" {
+			items = append(items, firefoxCache.item(itemID(id, firefoxCache.ID), fc.Name(), label, path))
+		}
+	}
+	return items
+}
+
+// category returns the data category of an item ID
+func (fc *FirefoxCleaner) category(id string) (profileCategory, bool) {
+	if !fc.opts.ProfileData {
+		return profileCategory{}, false
+	}
+	for _, profile := range fc.profiles {
+		base := itemID("firefox", profile.Dir)
+		for _, category := range append(firefoxCategories, firefoxCache) {
+			if id == itemID(base, category.ID) {
+				return category, true
+			}
+		}
+	}
+	return profileCategory{}, false
+}
+
+// Processes returns the executable names of a running Firefox
+func (fc *FirefoxCleaner) Processes() []string {
+	if len(fc.profiles) == 0 {
+		return nil
+	}
+	return browser.FirefoxProcesses
+}
+
+// Name returns the name of this cleaner
+func (fc *FirefoxCleaner) Name() string {
+	return "Firefox History"
+}
+
+// Locks returns the profile directories and their disk caches
+func (fc *FirefoxCleaner) Locks() []string {
+	locks := make([]string, 0, len(fc.profiles)+len(fc.cacheDirs))
+	for _, profile := range fc.profiles {
+		locks = append(locks, profile.Path)
+	}
+	for _, dir := range fc.cacheDirs {
+		locks = append(locks, dir)
+	}
+	return locks
+}
+
+// DryRun returns the Firefox profiles whose history will be cleaned
+func (fc *FirefoxCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0, len(fc.profiles))
+
+	for _, profile := range fc.profiles {
+		path := profile.File("places.sqlite")
+		id := fc.itemID(path)
+		label := fmt.Sprintf("Firefox (%s)", profile.Name)
+
+		if fc.opts.DeleteFile {
+			item := fileItem(id, fc.Name(), "History", label+" - history and bookmarks", path)
+			for _, suffix := range []string{"-wal", "-shm"} {
+				if info, err := os.Stat(path + suffix); err == nil {
+					item.Size += info.Size()
+				}
+			}
+			item.Risk = RiskHigh
+			items = append(items, item)
+			items = append(items, fc.categoryItems(profile, id, label)...)
+			continue
+		}
+
+		if !fc.opts.Domains.IsZero() {
+			// One item per rule, so sites can be selected individually
+			for _, rule := range fc.opts.Domains.Rules() {
+				stats, err := countFirefoxSites(path, fc.opts.Range, fc.opts.Domains.Matcher(rule))
+				if err != nil || stats.total() == 0 {
+					continue
+				}
+				items = append(items, siteItem(itemID(id, "site", rule), fc.Name(), label, rule, stats, fc.opts.Range, path))
+			}
+			continue
+		}
+
+		if stats, err := countFirefoxHistory(path, fc.opts.Range, nil); err == nil && stats.Visits > 0 {
+			historyLabel := label + fmt.Sprintf(" - %d visits, bookmarks kept", stats.Visits)
+			if !fc.opts.Range.IsZero() {
+				historyLabel = label + fmt.Sprintf(" - %d visits (%s), bookmarks kept", stats.Visits, fc.opts.Range)
+			}
+
+			item := fileItem(id, fc.Name(), "History", historyLabel, path)
+			// Pruning frees rows rather than the file, so the size is not reclaimed
+			item.Size = 0
+			items = append(items, item)
+		}
+
+		// Other profile data is removed whole, so it is only offered when
+		// all history is cleaned
+		if fc.opts.Range.IsZero() {
+			items = append(items, fc.categoryItems(profile, id, label)...)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return items, nil
+}
+
+// itemID returns the ID of the profile owning a places database, e.g.
+// "firefox:abc.default-release"
+func (fc *FirefoxCleaner) itemID(placesPath string) string {
+	for _, profile := range fc.profiles {
+		if profile.File("places.sqlite") == placesPath {
+			return itemID("firefox", profile.Dir)
+		}
+	}
+	return ""
+}
+
+// Clean removes history from every Firefox profile
+func (fc *FirefoxCleaner) Clean() error {
+	items, err := fc.DryRun()
+	if err != nil {
+		return err
+	}
+	return fc.CleanItems(items)
+}
+
+// CleanItems removes history from the given Firefox profiles
+func (fc *FirefoxCleaner) CleanItems(items []Item) error {
+	errors := make([]error, 0)
+
+	for _, item := range items {
+		if _, err := os.Stat(item.Path); os.IsNotExist(err) {
+			continue
+		}
+
+		if category, ok := fc.category(item.ID); ok {
+			if err := category.clean(item.Path); err != nil {
+				errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
+			}
+			continue
+		}
+
+		if fc.opts.DeleteFile {
+			if err := os.Remove(item.Path); err != nil {
+				errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
+				continue
+			}
+			_ = os.Remove(item.Path + "-wal")
+			_ = os.Remove(item.Path + "-shm")
+			continue
+		}
+
+		if !fc.opts.Domains.IsZero() {
+			for _, rule := range fc.opts.Domains.Rules() {
+				if item.ID != itemID(fc.itemID(item.Path), "site", rule) {
+					continue
+				}
+				if err := pruneFirefoxSites(item.Path, fc.opts.Range, fc.opts.Domains.Matcher(rule)); err != nil {
+					errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
+				}
+			}
+			continue
+		}
+
+		if err := pruneFirefoxHistory(item.Path, fc.opts.Range, nil); err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to clean some Firefox profiles: %v", errors)
+	}
+
+	return nil
+}
+
+// firefoxTime converts t into Firefox's microseconds since the Unix epoch
+func firefoxTime(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+// Firefox cookie and favicon tables selected by site
+var (
+	firefoxCookies = hostTable{
+		Table:      "moz_cookies",
+		Column:     "host",
+		TimeColumn: "creationTime",
+		ToDB:       firefoxTime,
+	}
+	firefoxFavicons = hostTable{
+		Table:  "moz_pages_w_icons",
+		Column: "page_url",
+		IsURL:  true,
+		Cleanup: []string{
+			"DELETE FROM moz_icons_to_pages WHERE page_id NOT IN (SELECT id FROM moz_pages_w_icons)",
+			"DELETE FROM moz_icons WHERE root = 0 AND id NOT IN (SELECT icon_id FROM moz_icons_to_pages)",
+		},
+	}
+)
+
+// firefoxScope holds the conditions limiting a history prune to matching
+// pages. They are empty when every site is selected.
+type firefoxScope struct {
+	visits string
+	places string
+}
+
+// newFirefoxScope selects the pages of matching hosts into a temporary
+// table. A nil match selects everything.
+func newFirefoxScope(db sqlConn, match hostMatcher) (firefoxScope, error) {
+	var scope firefoxScope
+	if match == nil {
+		return scope, nil
+	}
+
+	byURL := func(v string) bool { return match(urlHost(v)) }
+	if err := fillScope(db, "scope_places", "SELECT id, url FROM moz_places", byURL); err != nil {
+		return scope, err
+	}
+	scope.visits = " AND place_id IN (SELECT id FROM temp.scope_places)"
+	scope.places = " AND id IN (SELECT id FROM temp.scope_places)"
+
+	return scope, nil
+}
+
+// firefoxUnbookmarked limits moz_places to pages no bookmark or keyword uses.
+// foreign_count counts bookmarks and keywords pointing at a page; the
+// explicit moz_bookmarks check guards against a stale counter.
+const firefoxUnbookmarked = "foreign_count = 0 AND id NOT IN (SELECT fk FROM moz_bookmarks WHERE fk IS NOT NULL)"
+
+// countFirefoxHistory returns how many rows pruneFirefoxHistory would remove
+func countFirefoxHistory(path string, r TimeRange, match hostMatcher) (historyStats, error) {
+	var stats historyStats
+
+	db, err := openSQLite(path)
+	if err != nil {
+		return stats, err
+	}
+	defer db.Close()
+
+	scope, err := newFirefoxScope(db, match)
+	if err != nil {
+		return stats, err
+	}
+
+	visitWhere, visitArgs := r.where("visit_date", firefoxTime)
+	stats.Visits = countRows(db, "SELECT COUNT(*) FROM moz_historyvisits WHERE "+visitWhere+scope.visits, visitArgs...)
+
+	placeWhere, placeArgs := r.where("last_visit_date", firefoxTime)
+	stats.Pages = countRows(db, "SELECT COUNT(*) FROM moz_places WHERE "+firefoxUnbookmarked+
+		" AND "+placeWhere+scope.places, placeArgs...)
+
+	// Downloads are pages annotated with their destination file
+	if tableExists(db, "moz_anno_attributes") {
+		stats.Downloads = countRows(db, `SELECT COUNT(*) FROM moz_annos a
+			JOIN moz_anno_attributes n ON a.anno_attribute_id = n.id
+			WHERE n.name = 'downloads/destinationFileURI'
+			AND a.place_id IN (SELECT id FROM moz_places WHERE `+firefoxUnbookmarked+" AND "+placeWhere+scope.places+")", placeArgs...)
+	}
+
+	return stats, nil
+}
+
+// pruneFirefoxHistory deletes the visits recorded in the time range from a
+// places.sqlite database, limited to matching hosts when match is non-nil,
+// along with pages that are neither bookmarked nor visited any more. It then
+// removes orphaned origins and favicons. Bookmarks and the pages they point
+// to are left intact.
+func pruneFirefoxHistory(path string, r TimeRange, match hostMatcher) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scope, err := newFirefoxScope(tx, match)
+	if err != nil {
+		return err
+	}
+
+	visitWhere, visitArgs := r.where("visit_date", firefoxTime)
+	if _, err := tx.Exec("DELETE FROM moz_historyvisits WHERE "+visitWhere+scope.visits, visitArgs...); err != nil {
+		return fmt.Errorf("failed to delete visits: %w", err)
+	}
+
+	placeWhere, placeArgs := r.where("last_visit_date", firefoxTime)
+	if _, err := tx.Exec(`DELETE FROM moz_places WHERE `+firefoxUnbookmarked+`
+		AND id NOT IN (SELECT place_id FROM moz_historyvisits)
+		AND (last_visit_date IS NULL OR `+placeWhere+`)`+scope.places, placeArgs...); err != nil {
+		return fmt.Errorf("failed to delete places: %w", err)
+	}
+
+	// Bookmarked pages stay, but their visit counters must match what is left
+	if _, err := tx.Exec(`UPDATE moz_places SET
+		visit_count = (SELECT COUNT(*) FROM moz_historyvisits WHERE place_id = moz_places.id),
+		last_visit_date = (SELECT MAX(visit_date) FROM moz_historyvisits WHERE place_id = moz_places.id)`); err != nil {
+		return fmt.Errorf("failed to update places: %w", err)
+	}
+
+	// Tables that reference pages or origins and may not exist in every version
+	orphans := []struct{ table, stmt string }{
+		{"moz_inputhistory", "DELETE FROM moz_inputhistory WHERE place_id NOT IN (SELECT id FROM moz_places)"},
+		{"moz_annos", "DELETE FROM moz_annos WHERE place_id NOT IN (SELECT id FROM moz_places)"},
+		{"moz_places_metadata", "DELETE FROM moz_places_metadata WHERE place_id NOT IN (SELECT id FROM moz_places)"},
+		{"moz_origins", "DELETE FROM moz_origins WHERE id NOT IN (SELECT origin_id FROM moz_places WHERE origin_id IS NOT NULL)"},
+	}
+	for _, orphan := range orphans {
+		if !tableExists(tx, orphan.table) {
+			continue
+		}
+		if _, err := tx.Exec(orphan.stmt); err != nil {
+			return fmt.Errorf("failed to clean %s: %w", orphan.table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := secureVacuum(db); err != nil {
+		return err
+	}
+
+	favicons := filepath.Join(filepath.Dir(path), "favicons.sqlite")
+	if _, err := os.Stat(favicons); err == nil {
+		if err := pruneFirefoxFavicons(favicons, path); err != nil {
+			return fmt.Errorf("favicons: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pruneFirefoxFavicons removes icons in favicons.sqlite that belong to pages
+// or origins no longer present in the places database.
+func pruneFirefoxFavicons(path, placesPath string) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("ATTACH DATABASE ? AS places", placesPath); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := execAll(tx,
+		"DELETE FROM moz_pages_w_icons WHERE page_url_hash NOT IN (SELECT url_hash FROM places.moz_places)",
+		"DELETE FROM moz_icons_to_pages WHERE page_id NOT IN (SELECT id FROM moz_pages_w_icons)",
+		// Root icons (e.g. /favicon.ico) are linked to an origin rather than a page
+		`DELETE FROM moz_icons WHERE
+			(root = 0 AND id NOT IN (SELECT icon_id FROM moz_icons_to_pages))
+			OR (root = 1 AND NOT EXISTS (SELECT 1 FROM places.moz_origins o
+				WHERE moz_icons.icon_url LIKE o.prefix || o.host || '/%'))`,
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DETACH DATABASE places"); err != nil {
+		return err
+	}
+
+	return secureVacuum(db)
+}
+
+// countFirefoxSites returns the history, cookies and favicons of matching
+// hosts in a Firefox profile
+func countFirefoxSites(placesPath string, r TimeRange, match hostMatcher) (historyStats, error) {
+	stats, err := countFirefoxHistory(placesPath, r, match)
+	if err != nil {
+		return stats, err
+	}
+
+	profileDir := filepath.Dir(placesPath)
+	if path := filepath.Join(profileDir, "cookies.sqlite"); fileExists(path) {
+		stats.Cookies, _ = firefoxCookies.count(path, r, match)
+	}
+	if path := filepath.Join(profileDir, "favicons.sqlite"); fileExists(path) {
+		stats.Favicons, _ = firefoxFavicons.count(path, r, match)
+	}
+
+	return stats, nil
+}
+
+// pruneFirefoxSites removes the history, cookies and favicons of matching
+// hosts from a Firefox profile
+func pruneFirefoxSites(placesPath string, r TimeRange, match hostMatcher) error {
+	errors := make([]error, 0)
+
+	if err := pruneFirefoxHistory(placesPath, r, match); err != nil {
+		errors = append(errors, fmt.Errorf("history: %w", err))
+	}
+
+	profileDir := filepath.Dir(placesPath)
+	if path := filepath.Join(profileDir, "cookies.sqlite"); fileExists(path) {
+		if err := firefoxCookies.prune(path, r, match); err != nil {
+			errors = append(errors, fmt.Errorf("cookies: %w", err))
+		}
+	}
+	if path := filepath.Join(profileDir, "favicons.sqlite"); fileExists(path) {
+		if err := firefoxFavicons.prune(path, r, match); err != nil {
+			errors = append(errors, fmt.Errorf("favicons: %w", err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%v", errors)
+	}
+	return nil
+}
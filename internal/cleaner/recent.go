@@ -1,22 +1,144 @@
 package cleaner
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/shellhist"
+	"github.com/mat/gowipeme/internal/xbel"
 )
 
+// RecentOptions selects the recently-used.xbel bookmarks to remove instead
+// of deleting the whole file. A bookmark is removed when it matches every
+// criterion that is set.
+type RecentOptions struct {
+	// Range limits removal to bookmarks last used within the range
+	Range TimeRange
+	// Dirs are directories (or URI prefixes such as "smb://server/") whose
+	// files are removed
+	Dirs []string
+	// Apps are application names, e.g. "gedit" or "org.gnome.*"
+	Apps []string
+	// MimeTypes are MIME types, e.g. "image/*"
+	MimeTypes []string
+
+	// Places also offers the KDE places and GTK file chooser bookmarks the
+	// user made; it is not a bookmark criterion
+	Places bool
+}
+
+// IsZero reports whether no criteria are set, so lists are deleted whole
+func (o RecentOptions) IsZero() bool {
+	return o.Range.IsZero() && len(o.Dirs) == 0 && len(o.Apps) == 0 && len(o.MimeTypes) == 0
+}
+
+// matches reports whether a bookmark is selected for removal
+func (o RecentOptions) matches(b xbel.Bookmark) bool {
+	if !o.Range.IsZero() && !o.Range.Contains(b.LastUsed()) {
+		return false
+	}
+	if len(o.Dirs) > 0 && !uriUnder(b.Href, b.Path(), o.Dirs) {
+		return false
+	}
+	if len(o.Apps) > 0 && !bookmarkFromApp(b, o.Apps) {
+		return false
+	}
+	if len(o.MimeTypes) > 0 && !matchAny(o.MimeTypes, b.MimeType) {
+		return false
+	}
+	return true
+}
+
+// uriUnder reports whether a URI, whose local path is given for file URIs,
+// points into one of dirs. Dirs containing "://" are matched as URI prefixes.
+func uriUnder(uri, local string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.Contains(dir, "://") {
+			if strings.HasPrefix(uri, strings.TrimSuffix(dir, "/")+"/") {
+				return true
+			}
+			continue
+		}
+		if local != "" && (local == dir || strings.HasPrefix(local, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
+// absDirs expands "~" and makes local directories absolute for uriUnder
+func absDirs(dirs []string) []string {
+	out := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if !strings.Contains(dir, "://") {
+			if expanded, err := platform.ExpandPath(dir); err == nil {
+				dir = expanded
+			}
+			if abs, err := filepath.Abs(dir); err == nil {
+				dir = abs
+			}
+		}
+		out = append(out, dir)
+	}
+	return out
+}
+
+// bookmarkFromApp reports whether one of apps registered a bookmark, by
+// name or by the command in its exec line
+func bookmarkFromApp(b xbel.Bookmark, apps []string) bool {
+	for _, app := range b.Applications {
+		command := ""
+		if fields := strings.Fields(strings.Trim(app.Exec, "'\"")); len(fields) > 0 {
+			command = filepath.Base(fields[0])
+		}
+		if matchAny(apps, app.Name) || (command != "" && matchAny(apps, command)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether value matches one of the case-insensitive globs
+func matchAny(patterns []string, value string) bool {
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		if ok, err := path.Match(strings.ToLower(pattern), value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
 // RecentFilesCleaner handles clearing recent files lists
 type RecentFilesCleaner struct {
 	recentDocsPath string
+	opts           RecentOptions
+	desktop        []desktopTrace
 }
 
 // NewRecentFilesCleaner creates a new recent files cleaner
 func NewRecentFilesCleaner() *RecentFilesCleaner {
-	rc := &RecentFilesCleaner{}
+	return NewRecentFilesCleanerWithOptions(RecentOptions{})
+}
+
+// NewRecentFilesCleanerWithOptions creates a recent files cleaner that
+// removes only the selected recently-used.xbel bookmarks. The other lists
+// cannot be pruned by the same criteria, so with any set they are left out.
+func NewRecentFilesCleanerWithOptions(opts RecentOptions) *RecentFilesCleaner {
+	opts.Dirs = absDirs(opts.Dirs)
+
+	rc := &RecentFilesCleaner{opts: opts}
+	if runtime.GOOS == "linux" && opts.IsZero() {
+		rc.desktop = desktopTraces(opts.Places)
+	}
 
 	// Get recent documents path
 	path, err := platform.GetRecentDocumentsPath()
@@ -32,9 +154,141 @@ func (rc *RecentFilesCleaner) Name() string {
 	return "Recent Files"
 }
 
-// DryRun returns info about what will be cleared
-func (rc *RecentFilesCleaner) DryRun() ([]string, error) {
-	items := make([]string, 0)
+// Locks returns the recent documents path and the desktop trace files
+func (rc *RecentFilesCleaner) Locks() []string {
+	locks := make([]string, 0, len(rc.desktop)+1)
+	if rc.recentDocsPath != "" {
+		locks = append(locks, rc.recentDocsPath)
+	}
+	for _, t := range rc.desktop {
+		if path := t.path(t.Dir); path != "" {
+			locks = append(locks, path)
+		}
+	}
+	return locks
+}
+
+// Processes returns the desktop daemons that keep activity traces open
+func (rc *RecentFilesCleaner) Processes() []string {
+	return desktopProcesses(rc.desktop)
+}
+
+// Item IDs with special cleaning behaviour
+const (
+	recentAppDocumentsID = "recent:application-documents"
+	recentWindowsID      = "recent:windows-recent"
+	recentBookmarkPrefix = "recent:xbel:"
+)
+
+// bookmarkKey returns a short stable key for a bookmark URI
+func bookmarkKey(href string) string {
+	sum := sha256.Sum256([]byte(href))
+	return hex.EncodeToString(sum[:6])
+}
+
+// xbelFiles returns the existing recently-used.xbel and its backup
+func (rc *RecentFilesCleaner) xbelFiles() []string {
+	files := make([]string, 0, 2)
+	for _, file := range []string{rc.recentDocsPath, rc.recentDocsPath + ".bak"} {
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// bookmarkItems lists one item per recently-used.xbel bookmark selected by
+// the options
+func (rc *RecentFilesCleaner) bookmarkItems() ([]Item, error) {
+	items := make([]Item, 0)
+	seen := make(map[string]bool)
+
+	for _, file := range rc.xbelFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return items, err
+		}
+		bookmarks, err := xbel.Parse(data)
+		if err != nil {
+			return items, fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, b := range bookmarks {
+			key := bookmarkKey(b.Href)
+			if seen[key] || !rc.opts.matches(b) {
+				continue
+			}
+			seen[key] = true
+
+			name := b.Path()
+			if name == "" {
+				name = b.Href
+			}
+			details := make([]string, 0, 3)
+			for _, app := range b.Applications {
+				details = append(details, app.Name)
+			}
+			if b.MimeType != "" {
+				details = append(details, b.MimeType)
+			}
+			if last := b.LastUsed(); !last.IsZero() {
+				details = append(details, last.Local().Format("2006-01-02"))
+			}
+
+			items = append(items, Item{
+				ID:       recentBookmarkPrefix + key,
+				Cleaner:  rc.Name(),
+				Category: "Recent Documents",
+				Label:    fmt.Sprintf("%s (%s)", name, strings.Join(details, ", ")),
+				Path:     file,
+				ModTime:  b.LastUsed(),
+			})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ModTime.Before(items[j].ModTime) })
+
+	return items, nil
+}
+
+// removeBookmarks rewrites recently-used.xbel and its backup without the
+// bookmarks whose keys are given
+func (rc *RecentFilesCleaner) removeBookmarks(keys map[string]bool) error {
+	errors := make([]error, 0)
+
+	for _, file := range rc.xbelFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		out, removed, err := xbel.Remove(data, func(b xbel.Bookmark) bool { return keys[bookmarkKey(b.Href)] })
+		if err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		if err := shellhist.WriteFileAtomic(file, out); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%v", errors)
+	}
+	return nil
+}
+
+// DryRun returns the recent files lists that will be cleared
+func (rc *RecentFilesCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0)
+
+	// Only recently-used.xbel can be pruned by the selection criteria
+	if !rc.opts.IsZero() && runtime.GOOS != "linux" {
+		return items, nil
+	}
 
 	switch runtime.GOOS {
 	case "darwin":
@@ -45,67 +299,87 @@ func (rc *RecentFilesCleaner) DryRun() ([]string, error) {
 				entries, err := os.ReadDir(rc.recentDocsPath)
 				if err == nil {
 					count := 0
+					var size int64
 					for _, entry := range entries {
 						if !entry.IsDir() && filepath.Ext(entry.Name()) == ".sfl2" {
 							count++
+							if info, err := entry.Info(); err == nil {
+								size += info.Size()
+							}
 						}
 					}
 					if count > 0 {
-						items = append(items, fmt.Sprintf("Application recent documents (%d apps)", count))
+						item := fileItem(recentAppDocumentsID, rc.Name(), "Recent Documents",
+							fmt.Sprintf("Application recent documents (%d apps)", count), rc.recentDocsPath)
+						item.Size = size
+						items = append(items, item)
 					}
 				}
 			}
 		}
 
-		// Also clear Finder recent items
+		// Shared file lists for servers, hosts and applications
 		home, err := platform.GetHomeDir()
 		if err == nil {
-			finderPlist := filepath.Join(home, "Library/Preferences/com.apple.finder.plist")
-			if _, err := os.Stat(finderPlist); err == nil {
-				items = append(items, "Finder recent items")
-			}
-
-			// Recent servers (network locations)
-			recentServers := filepath.Join(home, "Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentServers.sfl2")
-			if _, err := os.Stat(recentServers); err == nil {
-				items = append(items, "Recent network servers")
+			lists := []struct {
+				id, label, file string
+			}{
+				{"servers", "Recent network servers", "com.apple.LSSharedFileList.RecentServers.sfl2"},
+				{"hosts", "Recent hosts", "com.apple.LSSharedFileList.RecentHosts.sfl2"},
+				{"applications", "Recent applications", "com.apple.LSSharedFileList.RecentApplications.sfl2"},
 			}
-
-			// Recent hosts
-			recentHosts := filepath.Join(home, "Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentHosts.sfl2")
-			if _, err := os.Stat(recentHosts); err == nil {
-				items = append(items, "Recent hosts")
+			for _, list := range lists {
+				path := filepath.Join(home, "Library/Application Support/com.apple.sharedfilelist", list.file)
+				if _, err := os.Stat(path); err == nil {
+					items = append(items, fileItem(itemID("recent", list.id), rc.Name(), "Recent Items", list.label, path))
+				}
 			}
 		}
 
 	case "linux":
-		// recently-used.xbel
-		if rc.recentDocsPath != "" {
+		if rc.recentDocsPath != "" && !rc.opts.IsZero() {
+			// Selected bookmarks only, keeping the rest of the file
+			bookmarks, err := rc.bookmarkItems()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, bookmarks...)
+		} else if rc.recentDocsPath != "" {
+			// recently-used.xbel
 			if _, err := os.Stat(rc.recentDocsPath); err == nil {
-				items = append(items, "Desktop recent items (recently-used.xbel)")
+				items = append(items, fileItem(itemID("recent", "xbel"), rc.Name(), "Recent Documents",
+					"Desktop recent items (recently-used.xbel)", rc.recentDocsPath))
 			}
 			if _, err := os.Stat(rc.recentDocsPath + ".bak"); err == nil {
-				items = append(items, "Desktop recent items backup (recently-used.xbel.bak)")
+				items = append(items, fileItem(itemID("recent", "xbel-backup"), rc.Name(), "Recent Documents",
+					"Desktop recent items backup (recently-used.xbel.bak)", rc.recentDocsPath+".bak"))
 			}
 		}
 
+		// KDE, GNOME and GTK activity traces
+		for _, t := range rc.desktop {
+			items = append(items, t.item(itemID("recent", t.ID), rc.Name(), t.Desktop, t.path(t.Dir)))
+		}
+
 	case "windows":
 		// %APPDATA%\\Microsoft\\Windows\\Recent (+ jump lists)
 		if rc.recentDocsPath != "" {
 			if info, err := os.Stat(rc.recentDocsPath); err == nil && info.IsDir() {
+				label := "Recent items"
 				if entries, err := os.ReadDir(rc.recentDocsPath); err == nil {
-					items = append(items, fmt.Sprintf("Recent items (%d entries)", len(entries)))
-				} else {
-					items = append(items, "Recent items")
+					label = fmt.Sprintf("Recent items (%d entries)", len(entries))
 				}
+				items = append(items, fileItem(recentWindowsID, rc.Name(), "Recent Items", label, rc.recentDocsPath))
 
 				auto := filepath.Join(rc.recentDocsPath, "AutomaticDestinations")
 				if _, err := os.Stat(auto); err == nil {
-					items = append(items, "Jump Lists (AutomaticDestinations)")
+					items = append(items, fileItem(itemID("recent", "automatic-destinations"), rc.Name(), "Jump Lists",
+						"Jump Lists (AutomaticDestinations)", auto))
 				}
 				custom := filepath.Join(rc.recentDocsPath, "CustomDestinations")
 				if _, err := os.Stat(custom); err == nil {
-					items = append(items, "Jump Lists (CustomDestinations)")
+					items = append(items, fileItem(itemID("recent", "custom-destinations"), rc.Name(), "Jump Lists",
+						"Jump Lists (CustomDestinations)", custom))
 				}
 			}
 		}
@@ -114,82 +388,95 @@ func (rc *RecentFilesCleaner) DryRun() ([]string, error) {
 	return items, nil
 }
 
-// Clean removes recent files lists
+// Clean removes all recent files lists
 func (rc *RecentFilesCleaner) Clean() error {
+	items, err := rc.DryRun()
+	if err != nil {
+		return err
+	}
+	return rc.CleanItems(items)
+}
+
+// CleanItems removes the given recent files lists
+func (rc *RecentFilesCleaner) CleanItems(items []Item) error {
 	errors := make([]error, 0)
 
-	switch runtime.GOOS {
-	case "darwin":
-		// Clean application recent documents
-		if rc.recentDocsPath != "" {
-			if _, err := os.Stat(rc.recentDocsPath); err == nil {
-				entries, err := os.ReadDir(rc.recentDocsPath)
-				if err == nil {
-					for _, entry := range entries {
-						if !entry.IsDir() && filepath.Ext(entry.Name()) == ".sfl2" {
-							filePath := filepath.Join(rc.recentDocsPath, entry.Name())
-							if err := os.Remove(filePath); err != nil {
-								errors = append(errors, err)
-							}
-						}
-					}
-				}
+	// Best-effort: clear the contents of a directory
+	clearDir := func(dir string, skip func(os.DirEntry) bool) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			errors = append(errors, err)
+			return
+		}
+		for _, entry := range entries {
+			if skip != nil && skip(entry) {
+				continue
 			}
+			_ = os.RemoveAll(filepath.Join(dir, entry.Name()))
 		}
+	}
 
-		// Clear recent servers/hosts/apps
-		home, err := platform.GetHomeDir()
-		if err == nil {
-			recentServers := filepath.Join(home, "Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentServers.sfl2")
-			if _, err := os.Stat(recentServers); err == nil {
-				_ = os.Remove(recentServers)
-			}
+	desktop := make(map[string]desktopTrace, len(rc.desktop))
+	for _, t := range rc.desktop {
+		desktop[itemID("recent", t.ID)] = t
+	}
 
-			recentHosts := filepath.Join(home, "Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentHosts.sfl2")
-			if _, err := os.Stat(recentHosts); err == nil {
-				_ = os.Remove(recentHosts)
+	bookmarks := make(map[string]bool)
+	for _, item := range items {
+		if strings.HasPrefix(item.ID, recentBookmarkPrefix) {
+			bookmarks[strings.TrimPrefix(item.ID, recentBookmarkPrefix)] = true
+			continue
+		}
+		if t, ok := desktop[item.ID]; ok {
+			if err := t.clean(item.Path); err != nil {
+				errors = append(errors, fmt.Errorf("%s %s: %w", t.Desktop, t.Label, err))
 			}
+			continue
+		}
 
-			recentApps := filepath.Join(home, "Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentApplications.sfl2")
-			if _, err := os.Stat(recentApps); err == nil {
-				_ = os.Remove(recentApps)
+		switch item.ID {
+		case recentAppDocumentsID:
+			// Clean application recent documents
+			entries, err := os.ReadDir(item.Path)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() && filepath.Ext(entry.Name()) == ".sfl2" {
+					if err := os.Remove(filepath.Join(item.Path, entry.Name())); err != nil {
+						errors = append(errors, err)
+					}
+				}
 			}
-		}
 
-	case "linux":
-		// Remove the freedesktop recent files list
-		if rc.recentDocsPath != "" {
-			_ = os.Remove(rc.recentDocsPath)
-			_ = os.Remove(rc.recentDocsPath + ".bak")
-		}
+		case recentWindowsID:
+			// Clear Recent root; jump lists are separate items
+			clearDir(item.Path, func(entry os.DirEntry) bool {
+				name := entry.Name()
+				return entry.IsDir() && (name == "AutomaticDestinations" || name == "CustomDestinations")
+			})
 
-	case "windows":
-		// Best-effort: clear contents of Recent + Jump Lists
-		clearDir := func(dir string) {
-			entries, err := os.ReadDir(dir)
+		default:
+			info, err := os.Stat(item.Path)
+			if os.IsNotExist(err) {
+				continue
+			}
 			if err != nil {
 				errors = append(errors, err)
-				return
+				continue
 			}
-			for _, entry := range entries {
-				_ = os.RemoveAll(filepath.Join(dir, entry.Name()))
+			if info.IsDir() {
+				clearDir(item.Path, nil)
+			} else if err := os.Remove(item.Path); err != nil {
+				errors = append(errors, err)
 			}
 		}
+	}
 
-		if rc.recentDocsPath != "" {
-			if info, err := os.Stat(rc.recentDocsPath); err == nil && info.IsDir() {
-				// Clear Jump Lists first
-				auto := filepath.Join(rc.recentDocsPath, "AutomaticDestinations")
-				if info, err := os.Stat(auto); err == nil && info.IsDir() {
-					clearDir(auto)
-				}
-				custom := filepath.Join(rc.recentDocsPath, "CustomDestinations")
-				if info, err := os.Stat(custom); err == nil && info.IsDir() {
-					clearDir(custom)
-				}
-				// Clear Recent root
-				clearDir(rc.recentDocsPath)
-			}
+	if len(bookmarks) > 0 {
+		if err := rc.removeBookmarks(bookmarks); err != nil {
+			errors = append(errors, err)
 		}
 	}
 
@@ -0,0 +1,331 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/shellhist"
+)
+
+// ReplOptions configures the REPL history cleaner
+type ReplOptions struct {
+	// KeepLast keeps the given number of most recent lines in each history
+	KeepLast int
+	// Range removes only entries recorded in it. REPL histories carry no
+	// timestamps, so a range alone keeps them.
+	Range TimeRange
+}
+
+// retains reports whether histories are pruned instead of truncated
+func (o ReplOptions) retains() bool {
+	return o.KeepLast > 0 || !o.Range.IsZero()
+}
+
+// replHistory describes where an interactive tool keeps its history
+type replHistory struct {
+	Name string
+	// Env names the variable overriding the history file. When it is set,
+	// only that file is used.
+	Env string
+	// Files are candidates relative to the home directory
+	Files []string
+	// StateFiles and DataFiles are candidates relative to $XDG_STATE_HOME
+	// and $XDG_DATA_HOME
+	StateFiles []string
+	DataFiles  []string
+	// Format is used for line-level retention; empty means the file can
+	// only be cleared as a whole
+	Format shellhist.Format
+	// NewestFirst is set for files that list the latest entry first
+	NewestFirst bool
+	Processes   []string
+}
+
+// replHistories are the REPLs and database clients whose history is cleaned
+var replHistories = []replHistory{
+	{
+		Name: "Python", Env: "PYTHON_HISTORY",
+		Files: []string{".python_history"}, StateFiles: []string{"python_history"},
+		Format: shellhist.Lines, Processes: []string{"python", "python3"},
+	},
+	{
+		Name: "Node.js", Env: "NODE_REPL_HISTORY",
+		Files: []string{".node_repl_history"}, StateFiles: []string{"node_repl_history"},
+		Format: shellhist.Lines, NewestFirst: true, Processes: []string{"node"},
+	},
+	{
+		Name: "psql", Env: "PSQL_HISTORY",
+		Files: []string{".psql_history"}, StateFiles: []string{"psql_history"},
+		Format: shellhist.Lines, Processes: []string{"psql"},
+	},
+	{
+		Name: "MySQL", Env: "MYSQL_HISTFILE",
+		Files: []string{".mysql_history"}, StateFiles: []string{"mysql_history"},
+		Format: shellhist.Lines, Processes: []string{"mysql", "mariadb"},
+	},
+	{
+		Name: "SQLite", Env: "SQLITE_HISTORY",
+		Files: []string{".sqlite_history"}, StateFiles: []string{"sqlite_history"},
+		Format: shellhist.Lines, Processes: []string{"sqlite3"},
+	},
+	{
+		Name: "redis-cli", Env: "REDISCLI_HISTFILE",
+		Files: []string{".rediscli_history"}, StateFiles: []string{"rediscli_history"},
+		Format: shellhist.Lines, Processes: []string{"redis-cli"},
+	},
+	{
+		Name:  "IRB",
+		Files: []string{".irb_history"}, DataFiles: []string{"irb/irb_history"},
+		Format: shellhist.Lines, Processes: []string{"irb"},
+	},
+	{
+		// less keeps search and shell command history in sections; it is
+		// cleared as a whole
+		Name: "less", Env: "LESSHISTFILE",
+		Files: []string{".lesshst"}, StateFiles: []string{"lesshst"}, DataFiles: []string{"lesshst"},
+		Processes: []string{"less"},
+	},
+}
+
+// libeditHeader starts history files written by libedit (e.g. Python and
+// psql on macOS); it must stay the first line
+const libeditHeader = "_HiStOrY_V2_"
+
+// replFile is a discovered history file
+type replFile struct {
+	history replHistory
+	path    string
+	// location is where the file was found: "home", "state", "data" or
+	// "env"; less may keep a lesshst in several of them
+	location string
+}
+
+// ReplCleaner handles cleaning REPL and database client histories
+type ReplCleaner struct {
+	files []replFile
+	opts  ReplOptions
+}
+
+// NewReplCleaner creates a new REPL history cleaner
+func NewReplCleaner() *ReplCleaner {
+	return NewReplCleanerWithOptions(ReplOptions{})
+}
+
+// NewReplCleanerWithOptions creates a REPL history cleaner that keeps
+// recent lines according to opts
+func NewReplCleanerWithOptions(opts ReplOptions) *ReplCleaner {
+	rc := &ReplCleaner{opts: opts}
+	rc.discoverHistoryFiles()
+	return rc
+}
+
+// discoverHistoryFiles finds the history files that exist
+func (rc *ReplCleaner) discoverHistoryFiles() {
+	home, _ := platform.GetHomeDir()
+	stateHome, _ := platform.GetStateHomePath()
+	dataHome, _ := platform.GetDataHomePath()
+
+	for _, h := range replHistories {
+		candidates := make([]replFile, 0)
+		if env := os.Getenv(h.Env); h.Env != "" && env != "" {
+			// LESSHISTFILE=- disables the less history
+			if env != "-" {
+				if path, err := platform.ExpandPath(env); err == nil {
+					candidates = append(candidates, replFile{history: h, path: path, location: "env"})
+				}
+			}
+		} else {
+			for _, group := range []struct {
+				location string
+				root     string
+				files    []string
+			}{{"home", home, h.Files}, {"state", stateHome, h.StateFiles}, {"data", dataHome, h.DataFiles}} {
+				if group.root == "" {
+					continue
+				}
+				for _, file := range group.files {
+					path := filepath.Join(group.root, filepath.FromSlash(file))
+					candidates = append(candidates, replFile{history: h, path: path, location: group.location})
+				}
+			}
+		}
+
+		seen := make(map[string]bool)
+		for _, f := range candidates {
+			// A variable may point at /dev/null to disable the history,
+			// which cannot be truncated
+			if info, err := os.Stat(f.path); err == nil && info.Mode().IsRegular() && !seen[f.path] {
+				seen[f.path] = true
+				rc.files = append(rc.files, f)
+			}
+		}
+	}
+}
+
+// Name returns the name of this cleaner
+func (rc *ReplCleaner) Name() string {
+	return "REPL History"
+}
+
+// Processes returns the programs that write the discovered histories
+func (rc *ReplCleaner) Processes() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, f := range rc.files {
+		for _, name := range f.history.Processes {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// itemID returns the item ID of a history file. Files outside the home
+// directory include their location, since the same name may exist in
+// several places.
+func (f replFile) itemID() string {
+	if f.location == "home" {
+		return itemID("repl", f.history.Name, filepath.Base(f.path))
+	}
+	return itemID("repl", f.history.Name, f.location, filepath.Base(f.path))
+}
+
+// DryRun returns the history files that will be cleared, or with retention
+// options the files that lose lines
+func (rc *ReplCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0, len(rc.files))
+
+	for _, f := range rc.files {
+		if !rc.opts.retains() {
+			items = append(items, fileItem(f.itemID(), rc.Name(), "REPL History", f.history.Name, f.path))
+			continue
+		}
+		if f.history.Format == "" {
+			continue
+		}
+
+		entries, err := shellhist.ReadFile(f.path, f.history.Format)
+		if err != nil {
+			continue
+		}
+		_, removed := rc.prune(f, entries)
+		if removed == 0 {
+			continue
+		}
+
+		label := fmt.Sprintf("%s - %d of %d lines", f.history.Name, removed, len(entries))
+		if rc.opts.KeepLast > 0 {
+			label += fmt.Sprintf(" (keeping the last %d)", rc.opts.KeepLast)
+		}
+		item := fileItem(f.itemID(), rc.Name(), "REPL History", label, f.path)
+		item.Size = 0
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return items, nil
+}
+
+// prune applies the retention options to the entries of a history file,
+// keeping a libedit header and honouring newest-first files
+func (rc *ReplCleaner) prune(f replFile, entries []shellhist.Entry) ([]shellhist.Entry, int) {
+	var header []shellhist.Entry
+	if len(entries) > 0 && entries[0].Command == libeditHeader {
+		header, entries = entries[:1], entries[1:]
+	}
+
+	if f.history.NewestFirst {
+		entries = reversed(entries)
+	}
+	policy := shellhist.Policy{KeepLast: rc.opts.KeepLast, Since: rc.opts.Range.Since, Until: rc.opts.Range.Until}
+	kept, removed := policy.Apply(entries)
+	if f.history.NewestFirst {
+		kept = reversed(kept)
+	}
+
+	return append(append([]shellhist.Entry{}, header...), kept...), removed
+}
+
+// reversed returns a reversed copy of entries
+func reversed(entries []shellhist.Entry) []shellhist.Entry {
+	out := make([]shellhist.Entry, len(entries))
+	for i, entry := range entries {
+		out[len(entries)-1-i] = entry
+	}
+	return out
+}
+
+// Clean clears all discovered histories
+func (rc *ReplCleaner) Clean() error {
+	items, err := rc.DryRun()
+	if err != nil {
+		return err
+	}
+	return rc.CleanItems(items)
+}
+
+// CleanItems truncates the given history files, or removes their old lines
+// when retention options are set
+func (rc *ReplCleaner) CleanItems(items []Item) error {
+	errors := make([]error, 0)
+
+	files := make(map[string]replFile, len(rc.files))
+	for _, f := range rc.files {
+		files[f.itemID()] = f
+	}
+
+	for _, item := range items {
+		f, ok := files[item.ID]
+		if !ok {
+			continue
+		}
+
+		if !rc.opts.retains() {
+			// Truncate rather than delete so the tools keep their file
+			if err := os.Truncate(f.path, 0); err != nil && !os.IsNotExist(err) {
+				errors = append(errors, fmt.Errorf("%s: %w", f.history.Name, err))
+			}
+			continue
+		}
+
+		if err := rc.pruneFile(f); err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", f.history.Name, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to clean some REPL histories: %v", errors)
+	}
+
+	return nil
+}
+
+// pruneFile rewrites a history file without the lines removed by the
+// retention options
+func (rc *ReplCleaner) pruneFile(f replFile) error {
+	if f.history.Format == "" {
+		return nil
+	}
+
+	entries, err := shellhist.ReadFile(f.path, f.history.Format)
+	if err != nil {
+		return err
+	}
+	kept, removed := rc.prune(f, entries)
+	if removed == 0 {
+		return nil
+	}
+
+	data, err := shellhist.Marshal(f.history.Format, kept)
+	if err != nil {
+		return err
+	}
+	return shellhist.WriteFileAtomic(f.path, data)
+}
@@ -0,0 +1,53 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverHistoryFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	for _, h := range replHistories {
+		if h.Env != "" {
+			t.Setenv(h.Env, "")
+		}
+	}
+	// Histories disabled through the environment are not files to clean
+	t.Setenv("MYSQL_HISTFILE", os.DevNull)
+	t.Setenv("LESSHISTFILE", "-")
+
+	for _, name := range []string{".python_history", ".lesshst", filepath.Join("state", "psql_history")} {
+		path := filepath.Join(home, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("select 1;\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Nor is a directory with a history file name
+	if err := os.Mkdir(filepath.Join(home, ".node_repl_history"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := NewReplCleaner()
+	got := make([]string, 0, len(rc.files))
+	for _, f := range rc.files {
+		got = append(got, f.itemID())
+	}
+	want := []string{"repl:python:.python_history", "repl:psql:state:psql_history"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("discovered %q, want %q", got, want)
+	}
+
+	// Cleaning leaves /dev/null alone instead of failing to truncate it
+	if err := rc.Clean(); err != nil {
+		t.Errorf("Clean() = %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package cleaner
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanWorkers bounds the goroutines reading directories during a size scan
+var scanWorkers = max(runtime.NumCPU(), 4)
+
+// scanCacheTTL is how long a tree's size is reused while its top directory
+// is unchanged. Changes deeper in the tree do not touch the top directory,
+// so this bounds how stale a size can get.
+const scanCacheTTL = 5 * time.Minute
+
+// dirStats is the size of a file or tree and its newest modification time
+type dirStats struct {
+	size    int64
+	modTime time.Time
+}
+
+// add merges the stats of a file or subtree
+func (s *dirStats) add(o dirStats) {
+	s.size += o.size
+	if o.modTime.After(s.modTime) {
+		s.modTime = o.modTime
+	}
+}
+
+// scanCacheEntry is a cached scan and the top directory it was valid for
+type scanCacheEntry struct {
+	stats   dirStats
+	topTime time.Time
+	scanned time.Time
+}
+
+// scanCache shares tree sizes between dry-runs and cleaning, which runs
+// dry-run again to find the items to remove
+var scanCache = struct {
+	sync.Mutex
+	entries map[string]scanCacheEntry
+}{entries: make(map[string]scanCacheEntry)}
+
+// cachedStats returns the cached stats of path if they are still valid
+func cachedStats(path string, info fs.FileInfo) (dirStats, bool) {
+	scanCache.Lock()
+	defer scanCache.Unlock()
+
+	entry, ok := scanCache.entries[path]
+	if !ok || !entry.topTime.Equal(info.ModTime()) || time.Since(entry.scanned) > scanCacheTTL {
+		return dirStats{}, false
+	}
+	return entry.stats, true
+}
+
+// storeStats caches the stats of path
+func storeStats(path string, info fs.FileInfo, stats dirStats) {
+	scanCache.Lock()
+	defer scanCache.Unlock()
+	scanCache.entries[path] = scanCacheEntry{stats: stats, topTime: info.ModTime(), scanned: time.Now()}
+}
+
+// forgetStats drops the cached stats of path and of the trees below it,
+// after they were removed
+func forgetStats(path string) {
+	scanCache.Lock()
+	defer scanCache.Unlock()
+	for p := range scanCache.entries {
+		if p == path || strings.HasPrefix(p, path+string(filepath.Separator)) {
+			delete(scanCache.entries, p)
+		}
+	}
+}
+
+// sizeScanner sums the sizes of trees with a bounded pool of goroutines.
+// Files with several hard links are counted once per scanner.
+type sizeScanner struct {
+	ctx   context.Context
+	slots chan struct{}
+	// fresh reads every tree instead of reusing cached stats, for callers
+	// that act on modification times: a write deep in a tree does not
+	// change the top directory the cache is checked against
+	fresh bool
+
+	mu   sync.Mutex
+	seen map[fileKey]bool
+}
+
+// newSizeScanner creates a scanner for one dry-run. Once the context is
+// cancelled, it stops reading directories and returns partial sizes.
+func newSizeScanner(ctx context.Context) *sizeScanner {
+	return &sizeScanner{
+		ctx:   ctx,
+		slots: make(chan struct{}, scanWorkers),
+		seen:  make(map[fileKey]bool),
+	}
+}
+
+// firstLink reports whether a file is seen for the first time; files
+// without other hard links always are
+func (s *sizeScanner) firstLink(info fs.FileInfo) bool {
+	key, ok := hardLinkKey(info)
+	if !ok {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	return true
+}
+
+// stats returns the stats of a file or tree, from the cache when valid.
+// Symbolic links are not followed.
+func (s *sizeScanner) stats(path string) dirStats {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return dirStats{}
+	}
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() || !s.firstLink(info) {
+			return dirStats{modTime: info.ModTime()}
+		}
+		return dirStats{size: info.Size(), modTime: info.ModTime()}
+	}
+
+	if stats, ok := cachedStats(path, info); ok && !s.fresh {
+		return stats
+	}
+	stats := dirStats{modTime: info.ModTime()}
+	stats.add(s.walk(path))
+	if s.ctx.Err() == nil {
+		storeStats(path, info, stats)
+	}
+	return stats
+}
+
+// walk sums a directory, handing subdirectories to idle workers and
+// reading them itself when none is free
+func (s *sizeScanner) walk(dir string) dirStats {
+	var stats dirStats
+	if s.ctx.Err() != nil {
+		return stats
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return stats // Skip unreadable directories
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			file := dirStats{modTime: info.ModTime()}
+			if info.Mode().IsRegular() && s.firstLink(info) {
+				file.size = info.Size()
+			}
+			mu.Lock()
+			stats.add(file)
+			mu.Unlock()
+			continue
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-s.slots }()
+				sub := s.subtree(path, entry)
+				mu.Lock()
+				stats.add(sub)
+				mu.Unlock()
+			}()
+		default:
+			sub := s.subtree(path, entry)
+			mu.Lock()
+			stats.add(sub)
+			mu.Unlock()
+		}
+	}
+	wg.Wait()
+
+	return stats
+}
+
+// subtree returns the stats of a subdirectory including its own mtime
+func (s *sizeScanner) subtree(path string, entry fs.DirEntry) dirStats {
+	var stats dirStats
+	if info, err := entry.Info(); err == nil {
+		stats.modTime = info.ModTime()
+	}
+	stats.add(s.walk(path))
+	return stats
+}
+
+// scanAll computes the stats of several files or trees concurrently and
+// calls found, from a single goroutine at a time, as each one completes.
+// It returns the context's error, without calling found for the trees
+// being read, when the context is cancelled.
+func (s *sizeScanner) scanAll(paths []string, found func(path string, stats dirStats)) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, path := range paths {
+		select {
+		case s.slots <- struct{}{}:
+		case <-s.ctx.Done():
+		}
+		if s.ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats := s.stats(path)
+			<-s.slots
+			mu.Lock()
+			defer mu.Unlock()
+			if s.ctx.Err() == nil {
+				found(path, stats)
+			}
+		}()
+	}
+	wg.Wait()
+	return s.ctx.Err()
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package cleaner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileKey identifies a file across its hard links
+type fileKey struct {
+	dev uint64
+	ino uint64
+}
+
+// hardLinkKey returns the key of a file that has other hard links
+func hardLinkKey(info fs.FileInfo) (fileKey, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink <= 1 {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
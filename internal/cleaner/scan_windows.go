@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package cleaner
+
+import "io/fs"
+
+// fileKey identifies a file across its hard links
+type fileKey struct{}
+
+// hardLinkKey returns false: FileInfo carries no file index on Windows, so
+// hard links are counted once per link
+func hardLinkKey(info fs.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
@@ -0,0 +1,181 @@
+package cleaner
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mat/gowipeme/internal/event"
+)
+
+// DefaultConcurrency is how many cleaners a manager runs at once by default
+const DefaultConcurrency = 4
+
+// Locker is implemented by cleaners that share files or directories with
+// other cleaners. Cleaners whose locks overlap, the same path or one inside
+// the other, never clean at the same time. Cleaners that are not Lockers
+// are assumed to touch only files no other cleaner does.
+type Locker interface {
+	// Locks returns the files and directories the cleaner removes or edits
+	Locks() []string
+}
+
+// SetConcurrency sets how many cleaners clean at once; 1 runs them one
+// after another
+func (cm *CleanerManager) SetConcurrency(n int) {
+	cm.concurrency = max(n, 1)
+}
+
+// locksOverlap reports whether two lock sets share a path or a path inside
+// another
+func locksOverlap(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y || pathInside(x, y) || pathInside(y, x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathInside reports whether path is below dir
+func pathInside(path, dir string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// clean runs the cleaners on their dry-run items, limited to the selected
+// IDs when selected is non-nil. Up to the manager's concurrency cleaners run
+// at once, in order, except that a cleaner whose locks overlap a running
+// one waits for it while later cleaners may start. Results keep the order
+// of the cleaners; events are passed to report one at a time.
+func (cm *CleanerManager) clean(ctx context.Context, selected map[string]bool, report event.Func) []CleanResult {
+	var mu sync.Mutex
+	send := func(e event.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		report.Send(e)
+	}
+
+	locks := make([][]string, len(cm.cleaners))
+	for i, cleaner := range cm.cleaners {
+		if locker, ok := cleaner.(Locker); ok {
+			locks[i] = locker.Locks()
+		}
+	}
+
+	results := make([]*CleanResult, len(cm.cleaners))
+	pending := make([]int, len(cm.cleaners))
+	for i := range pending {
+		pending[i] = i
+	}
+	running := make(map[int]bool)
+	done := make(chan int)
+
+	for {
+		// Start every pending cleaner that fits, unless cancelled
+		for i := 0; i < len(pending) && len(running) < cm.concurrency && ctx.Err() == nil; {
+			idx := pending[i]
+			conflict := false
+			for r := range running {
+				if locksOverlap(locks[idx], locks[r]) {
+					conflict = true
+					break
+				}
+			}
+			if conflict {
+				i++
+				continue
+			}
+
+			pending = append(pending[:i], pending[i+1:]...)
+			running[idx] = true
+			go func() {
+				results[idx] = cleanOne(ctx, cm.cleaners[idx], selected, send)
+				done <- idx
+			}()
+		}
+
+		if len(running) == 0 {
+			break
+		}
+		delete(running, <-done)
+	}
+
+	ordered := make([]CleanResult, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			ordered = append(ordered, *result)
+		}
+	}
+	return ordered
+}
+
+// cleanOne runs a cleaner on its dry-run items, limited to the selected IDs
+// when selected is non-nil. It returns nil when no item is selected.
+func cleanOne(ctx context.Context, cleaner Cleaner, selected map[string]bool, report event.Func) *CleanResult {
+	result := &CleanResult{
+		CleanerName: cleaner.Name(),
+	}
+
+	items, err := cleaner.DryRun()
+	if err != nil {
+		result.Error = err
+		report.Send(event.Event{Kind: event.Finished, Source: cleaner.Name(), Unit: event.Items, Err: err})
+		return result
+	}
+
+	if selected != nil {
+		items = filterItems(items, selected)
+		if len(items) == 0 {
+			return nil
+		}
+	}
+
+	// Perform actual cleaning
+	result.ItemsCleaned, result.Error = cleanItems(ctx, cleaner, items, report)
+	result.BytesFreed = bytesFreed(items, result.ItemsCleaned)
+
+	return result
+}
+
+// bytesFreed sums the sizes of the first done items
+func bytesFreed(items []Item, done int) int64 {
+	var freed int64
+	for _, item := range items[:done] {
+		freed += item.Size
+	}
+	return freed
+}
+
+// cleanItems cleans items with a cleaner between Started and Finished
+// events and returns how many were handled before it stopped
+func cleanItems(ctx context.Context, cleaner Cleaner, items []Item, report event.Func) (int, error) {
+	start := time.Now()
+	send := func(e event.Event) {
+		e.Source = cleaner.Name()
+		e.Total = int64(len(items))
+		e.Unit = event.Items
+		e.Freed = bytesFreed(items, int(e.Done))
+		e.Elapsed = time.Since(start)
+		report.Send(e)
+	}
+	send(event.Event{Kind: event.Started})
+
+	done := len(items)
+	var err error
+	if cc, ok := cleaner.(ContextCleaner); ok {
+		done = 0
+		err = cc.CleanItemsContext(ctx, items, func(e event.Event) {
+			done = int(e.Done)
+			send(e)
+		})
+	} else {
+		err = cleaner.CleanItems(items)
+	}
+
+	send(event.Event{Kind: event.Finished, Done: int64(done), Err: err})
+	return done, err
+}
@@ -4,23 +4,105 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/atotto/clipboard"
 
 	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/secrets"
+	"github.com/mat/gowipeme/internal/shellhist"
 )
 
+// ShellOptions configures the shell history cleaner
+type ShellOptions struct {
+	// KeepLast keeps the given number of most recent commands in each history
+	KeepLast int
+	// Range removes only the commands recorded in it. Commands in files
+	// without timestamps are kept.
+	Range TimeRange
+	// Redact limits cleaning to commands containing secrets: RedactMask
+	// replaces the secrets, RedactRemove drops the commands. When empty,
+	// secrets are listed alongside the histories and masked if selected alone.
+	Redact string
+	// SecretRules detect secrets; nil uses the built-in and configured rules
+	SecretRules []secrets.Rule
+}
+
+// Ways to redact shell history entries containing secrets
+const (
+	RedactMask   = "mask"
+	RedactRemove = "remove"
+)
+
+// retains reports whether histories are pruned instead of truncated
+func (o ShellOptions) retains() bool {
+	return o.KeepLast > 0 || !o.Range.IsZero()
+}
+
+// policy returns the shellhist retention policy for the options
+func (o ShellOptions) policy() shellhist.Policy {
+	return shellhist.Policy{KeepLast: o.KeepLast, Since: o.Range.Since, Until: o.Range.Until}
+}
+
+// historyFormats maps shells to the format of their history files. Shells
+// missing here and in historyDatabases can only be truncated.
+var historyFormats = map[string]shellhist.Format{
+	"Bash":         shellhist.Bash,
+	"Zsh":          shellhist.Zsh,
+	"Zsh Sessions": shellhist.Zsh,
+	"Fish":         shellhist.Fish,
+	"PowerShell":   shellhist.PowerShell,
+	"Tcsh":         shellhist.Tcsh,
+	"Nushell":      shellhist.Lines,
+}
+
+// historyDatabases maps shells keeping their history in SQLite to its schema
+var historyDatabases = map[string]sqliteHistory{
+	"Nushell SQLite": nushellHistory,
+	"Xonsh SQLite":   xonshHistory,
+}
+
+// shellProcesses maps shells to the executables that write their history
+var shellProcesses = map[string][]string{
+	"Bash":           {"bash"},
+	"Zsh":            {"zsh"},
+	"Zsh Sessions":   {"zsh"},
+	"Fish":           {"fish"},
+	"PowerShell":     {"pwsh", "powershell"},
+	"Ksh":            {"ksh", "ksh93", "mksh"},
+	"Tcsh":           {"tcsh", "csh"},
+	"Nushell":        {"nu"},
+	"Nushell SQLite": {"nu"},
+	"Xonsh":          {"xonsh"},
+	"Xonsh SQLite":   {"xonsh"},
+}
+
 // ShellCleaner handles cleaning shell history
 type ShellCleaner struct {
 	historyFiles map[string]string // shell name -> path
+	opts         ShellOptions
+	secrets      *secrets.Scanner
 }
 
 // NewShellCleaner creates a new shell history cleaner
 func NewShellCleaner() *ShellCleaner {
+	return NewShellCleanerWithOptions(ShellOptions{})
+}
+
+// NewShellCleanerWithOptions creates a shell history cleaner that keeps
+// recent commands according to opts
+func NewShellCleanerWithOptions(opts ShellOptions) *ShellCleaner {
 	sc := &ShellCleaner{
 		historyFiles: make(map[string]string),
+		opts:         opts,
+	}
+
+	rules := opts.SecretRules
+	if rules == nil {
+		rules = loadSecretRules()
 	}
+	sc.secrets = secrets.NewScanner(rules)
 
 	// Discover shell history files
 	sc.discoverHistoryFiles()
@@ -30,50 +112,63 @@ func NewShellCleaner() *ShellCleaner {
 
 // discoverHistoryFiles finds shell history files
 func (sc *ShellCleaner) discoverHistoryFiles() {
-	// Bash history
-	if path, err := platform.GetBashHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			sc.historyFiles["Bash"] = path
-		}
+	sc.addHistory("Bash", platform.GetBashHistoryPath)
+	sc.addHistory("Zsh", platform.GetZshHistoryPath)
+	sc.addHistoryDir("Zsh Sessions", platform.GetZshSessionsPath)
+	sc.addHistory("Fish", platform.GetFishHistoryPath)
+
+	// PSReadLine history of Windows PowerShell and pwsh on every platform
+	sc.addHistory("PowerShell", platform.GetPowerShellHistoryPath)
+
+	sc.addHistory("Ksh", platform.GetKshHistoryPath)
+	sc.addHistory("Tcsh", platform.GetTcshHistoryPath)
+
+	// nushell keeps either a text or an SQLite history, depending on its config
+	if dir, err := platform.GetNushellConfigPath(); err == nil {
+		sc.addHistory("Nushell", func() (string, error) { return filepath.Join(dir, "history.txt"), nil })
+		sc.addHistory("Nushell SQLite", func() (string, error) { return filepath.Join(dir, "history.sqlite3"), nil })
 	}
 
-	// Zsh history
-	if path, err := platform.GetZshHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			sc.historyFiles["Zsh"] = path
-		}
+	// xonsh writes one JSON file per session, or a single SQLite database
+	if dir, err := platform.GetXonshDataPath(); err == nil {
+		sc.addHistoryDir("Xonsh", func() (string, error) { return filepath.Join(dir, "history_json"), nil })
+		sc.addHistory("Xonsh SQLite", func() (string, error) { return filepath.Join(dir, "xonsh-history.sqlite"), nil })
 	}
+}
 
-	// Zsh sessions directory
-	if path, err := platform.GetZshSessionsPath(); err == nil {
-		if info, err := os.Stat(path); err == nil && info.IsDir() {
-			sc.historyFiles["Zsh Sessions"] = path
+// addHistory records the history file returned by path if it exists
+func (sc *ShellCleaner) addHistory(shell string, path func() (string, error)) {
+	if p, err := path(); err == nil {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			sc.historyFiles[shell] = p
 		}
 	}
+}
 
-	// Fish history
-	if path, err := platform.GetFishHistoryPath(); err == nil {
-		if _, err := os.Stat(path); err == nil {
-			sc.historyFiles["Fish"] = path
+// addHistoryDir records the history directory returned by path if it exists
+func (sc *ShellCleaner) addHistoryDir(shell string, path func() (string, error)) {
+	if p, err := path(); err == nil {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			sc.historyFiles[shell] = p
 		}
 	}
+}
 
-	// PowerShell history (Windows)
-	if runtime.GOOS == "windows" {
-		// PSReadLine history (PowerShell 5+ / 7+)
-		appData := os.Getenv("APPDATA")
-		if appData == "" {
-			if home, err := platform.GetHomeDir(); err == nil {
-				appData = filepath.Join(home, "AppData", "Roaming")
-			}
-		}
-		if appData != "" {
-			psHistory := filepath.Join(appData, "Microsoft", "Windows", "PowerShell", "PSReadLine", "ConsoleHost_history.txt")
-			if _, err := os.Stat(psHistory); err == nil {
-				sc.historyFiles["PowerShell"] = psHistory
+// Processes returns the executable names of the shells whose history is
+// cleaned; a running shell writes its history back when it exits
+func (sc *ShellCleaner) Processes() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(sc.historyFiles))
+	for shell := range sc.historyFiles {
+		for _, name := range shellProcesses[shell] {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
 			}
 		}
 	}
+	sort.Strings(names)
+	return names
 }
 
 // Name returns the name of this cleaner
@@ -81,27 +176,144 @@ func (sc *ShellCleaner) Name() string {
 	return "Shell History"
 }
 
-// DryRun returns a list of shell history files that will be cleaned
-func (sc *ShellCleaner) DryRun() ([]string, error) {
-	items := make([]string, 0, len(sc.historyFiles))
+// DryRun returns the shell history files that will be cleaned
+func (sc *ShellCleaner) DryRun() ([]Item, error) {
+	if sc.opts.Redact != "" {
+		return sc.secretItems(), nil
+	}
+
+	var items []Item
+	if sc.opts.retains() {
+		items, _ = sc.retentionItems()
+	} else {
+		items = make([]Item, 0, len(sc.historyFiles))
+		for shell, path := range sc.historyFiles {
+			items = append(items, fileItem(itemID("shell", shell), sc.Name(), "Shell History", shell, path))
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return append(items, sc.secretItems()...), nil
+}
+
+// Clean removes all shell history files
+func (sc *ShellCleaner) Clean() error {
+	items, err := sc.DryRun()
+	if err != nil {
+		return err
+	}
+	return sc.CleanItems(items)
+}
+
+// retentionItems lists the histories that lose commands under the
+// retention options, with the number of commands removed
+func (sc *ShellCleaner) retentionItems() ([]Item, error) {
+	items := make([]Item, 0, len(sc.historyFiles))
+	policy := sc.opts.policy()
 
 	for shell, path := range sc.historyFiles {
-		items = append(items, fmt.Sprintf("%s (%s)", shell, path))
+		var total, removed int64
+		if db, ok := historyDatabases[shell]; ok {
+			removed, total, _ = db.count(path, sc.opts)
+		} else if format, ok := historyFormats[shell]; ok {
+			for _, file := range historyFilesIn(path) {
+				entries, err := shellhist.ReadFile(file, format)
+				if err != nil {
+					continue
+				}
+				_, n := policy.Apply(entries)
+				total += int64(len(entries))
+				removed += int64(n)
+			}
+		}
+		if removed == 0 {
+			continue
+		}
+
+		label := fmt.Sprintf("%s - %d of %d commands", shell, removed, total)
+		if sc.opts.KeepLast > 0 {
+			label += fmt.Sprintf(" (keeping the last %d)", sc.opts.KeepLast)
+		}
+		if !sc.opts.Range.IsZero() {
+			label += fmt.Sprintf(" (%s)", sc.opts.Range)
+		}
+		item := fileItem(itemID("shell", shell), sc.Name(), "Shell History", label, path)
+		// Rewriting frees the removed lines, not the file
+		item.Size = 0
+		items = append(items, item)
 	}
 
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
 	return items, nil
 }
 
-// Clean removes shell history files
-func (sc *ShellCleaner) Clean() error {
+// historyFilesIn returns path, or the *.history files in it when it is a
+// directory such as ~/.zsh_sessions
+func historyFilesIn(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return []string{path}
+	}
+	files, _ := filepath.Glob(filepath.Join(path, "*.history"))
+	return files
+}
+
+// shell returns the shell whose history an item covers
+func (sc *ShellCleaner) shell(item Item) string {
+	for shell := range sc.historyFiles {
+		id := itemID("shell", shell)
+		if item.ID == id || strings.HasPrefix(item.ID, id+":") {
+			return shell
+		}
+	}
+	return item.Label
+}
+
+// CleanItems clears the given shell history files, or prunes them when
+// retention options are set. Selected secrets are redacted first.
+func (sc *ShellCleaner) CleanItems(items []Item) error {
 	errors := make([]error, 0)
 
-	for shell, path := range sc.historyFiles {
+	items, err := sc.redactItems(items)
+	if err != nil {
+		errors = append(errors, err)
+	}
+
+	for _, item := range items {
+		shell, path := sc.shell(item), item.Path
+
+		// Databases lose their rows, all of them without retention options
+		if db, ok := historyDatabases[shell]; ok {
+			if err := db.prune(path, sc.opts); err != nil && !os.IsNotExist(err) {
+				errors = append(errors, fmt.Errorf("%s: %w", shell, err))
+			}
+			continue
+		}
+
+		if sc.opts.retains() {
+			format, ok := historyFormats[shell]
+			if !ok {
+				continue
+			}
+			for _, file := range historyFilesIn(path) {
+				if _, err := shellhist.Prune(file, format, sc.opts.policy()); err != nil && !os.IsNotExist(err) {
+					errors = append(errors, fmt.Errorf("%s: %w", shell, err))
+				}
+			}
+			continue
+		}
+
 		// Check if path exists
 		info, err := os.Stat(path)
 		if os.IsNotExist(err) {
 			continue
 		}
+		if err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", shell, err))
+			continue
+		}
 
 		// If it's a directory, remove all contents
 		if info.IsDir() {
@@ -146,8 +358,21 @@ func (cc *ClipboardCleaner) Name() string {
 }
 
 // DryRun returns info about clipboard cleaning
-func (cc *ClipboardCleaner) DryRun() ([]string, error) {
-	return []string{"System clipboard will be cleared"}, nil
+func (cc *ClipboardCleaner) DryRun() ([]Item, error) {
+	return []Item{{
+		ID:       itemID("clipboard"),
+		Cleaner:  cc.Name(),
+		Category: "Clipboard",
+		Label:    "System clipboard will be cleared",
+	}}, nil
+}
+
+// CleanItems clears the clipboard; it has a single item
+func (cc *ClipboardCleaner) CleanItems(items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return cc.Clean()
 }
 
 // Clean clears the system clipboard using a cross-platform clipboard provider.
@@ -0,0 +1,93 @@
+package cleaner
+
+import (
+	"fmt"
+	"time"
+)
+
+// sqliteHistory describes a shell history kept in an SQLite database.
+// Rows are deleted and the database vacuumed, so the shell keeps a valid
+// (possibly empty) history.
+type sqliteHistory struct {
+	Table      string
+	TimeColumn string
+	ToDB       func(time.Time) int64
+}
+
+// Shell histories stored in SQLite
+var (
+	// nushell's history.sqlite3 (reedline) stores milliseconds
+	nushellHistory = sqliteHistory{
+		Table:      "history",
+		TimeColumn: "start_timestamp",
+		ToDB:       func(t time.Time) int64 { return t.UnixMilli() },
+	}
+	// xonsh's SQLite backend stores the start time in seconds
+	xonshHistory = sqliteHistory{
+		Table:      "xonsh_history",
+		TimeColumn: "tsb",
+		ToDB:       func(t time.Time) int64 { return t.Unix() },
+	}
+)
+
+// where returns the condition selecting the rows removed under opts. Without
+// retention options every row is selected.
+func (h sqliteHistory) where(opts ShellOptions) (string, []interface{}) {
+	if !opts.retains() {
+		return "1 = 1", nil
+	}
+
+	conds := make([]string, 0, 2)
+	args := make([]interface{}, 0, 3)
+	if !opts.Range.IsZero() {
+		cond, rangeArgs := opts.Range.where(h.TimeColumn, h.ToDB)
+		conds = append(conds, "("+cond+")")
+		args = append(args, rangeArgs...)
+	}
+	if opts.KeepLast > 0 {
+		conds = append(conds, "rowid NOT IN (SELECT rowid FROM "+h.Table+" ORDER BY rowid DESC LIMIT ?)")
+		args = append(args, opts.KeepLast)
+	}
+
+	where := conds[0]
+	if len(conds) > 1 {
+		where += " OR " + conds[1]
+	}
+	return where, args
+}
+
+// count returns how many commands would be removed and how many there are
+func (h sqliteHistory) count(path string, opts ShellOptions) (int64, int64, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+
+	if !tableExists(db, h.Table) {
+		return 0, 0, nil
+	}
+	where, args := h.where(opts)
+	removed := countRows(db, "SELECT COUNT(*) FROM "+h.Table+" WHERE "+where, args...)
+	total := countRows(db, "SELECT COUNT(*) FROM "+h.Table)
+	return removed, total, nil
+}
+
+// prune deletes the selected commands and vacuums the database
+func (h sqliteHistory) prune(path string, opts ShellOptions) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if !tableExists(db, h.Table) {
+		return nil
+	}
+	where, args := h.where(opts)
+	if _, err := db.Exec("DELETE FROM "+h.Table+" WHERE "+where, args...); err != nil {
+		return fmt.Errorf("failed to delete from %s: %w", h.Table, err)
+	}
+
+	return secureVacuum(db)
+}
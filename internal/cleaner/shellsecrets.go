@@ -0,0 +1,168 @@
+package cleaner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/config"
+	"github.com/mat/gowipeme/internal/secrets"
+	"github.com/mat/gowipeme/internal/shellhist"
+)
+
+// secretCategory is the item category of history entries containing secrets
+const secretCategory = "Secrets"
+
+// loadSecretRules returns the built-in rules extended by the user
+// configuration, or only the built-in rules if the configuration is invalid
+func loadSecretRules() []secrets.Rule {
+	cfg, err := config.Load()
+	if err != nil {
+		return secrets.DefaultRules()
+	}
+	rules, err := secrets.RulesFromConfig(cfg.Secrets)
+	if err != nil {
+		return secrets.DefaultRules()
+	}
+	return rules
+}
+
+// secretKey identifies a history entry by its file and command, so the
+// same entry is found again when the selected items are cleaned
+func secretKey(file, command string) string {
+	sum := sha256.Sum256([]byte(file + "\x00" + command))
+	return hex.EncodeToString(sum[:6])
+}
+
+// secretItems lists one item per history entry containing a secret. Labels
+// show the command with the secret masked.
+func (sc *ShellCleaner) secretItems() []Item {
+	items := make([]Item, 0)
+	seen := make(map[string]bool)
+
+	for shell, path := range sc.historyFiles {
+		format, ok := historyFormats[shell]
+		if !ok {
+			continue
+		}
+
+		for _, file := range historyFilesIn(path) {
+			entries, err := shellhist.ReadFile(file, format)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				findings := sc.secrets.Scan(entry.Command)
+				if len(findings) == 0 {
+					continue
+				}
+
+				id := itemID("shell", shell, "secret", secretKey(file, entry.Command))
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+
+				rules := make([]string, 0, len(findings))
+				for _, f := range findings {
+					rules = append(rules, f.Rule)
+				}
+				command := strings.ReplaceAll(secrets.MaskAll(entry.Command, findings), "\n", " ↵ ")
+				if runes := []rune(command); len(runes) > 80 {
+					command = string(runes[:77]) + "..."
+				}
+
+				item := fileItem(id, sc.Name(), secretCategory,
+					fmt.Sprintf("%s - %s: %s", shell, strings.Join(rules, ", "), command), file)
+				item.Size = 0
+				items = append(items, item)
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// redactItems masks or removes the history entries of the selected secret
+// items and returns the remaining items. Files that are truncated anyway
+// are left alone.
+func (sc *ShellCleaner) redactItems(items []Item) ([]Item, error) {
+	rest := make([]Item, 0, len(items))
+	truncated := make(map[string]bool)
+	selected := make(map[string]map[string]bool) // file -> secret keys
+	shells := make(map[string]string)            // file -> shell
+
+	for _, item := range items {
+		if item.Category != secretCategory {
+			rest = append(rest, item)
+			if !sc.opts.retains() && sc.opts.Redact == "" {
+				truncated[item.Path] = true
+			}
+			continue
+		}
+		if selected[item.Path] == nil {
+			selected[item.Path] = make(map[string]bool)
+		}
+		selected[item.Path][item.ID[strings.LastIndex(item.ID, ":")+1:]] = true
+		shells[item.Path] = sc.shell(item)
+	}
+
+	errors := make([]error, 0)
+	files := make([]string, 0, len(selected))
+	for file := range selected {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		shell := shells[file]
+		format, ok := historyFormats[shell]
+		if !ok || truncated[file] || truncated[sc.historyFiles[shell]] {
+			continue
+		}
+		if err := sc.redactFile(file, format, selected[file]); err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", shell, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return rest, fmt.Errorf("failed to redact secrets: %v", errors)
+	}
+	return rest, nil
+}
+
+// redactFile masks or removes the entries of a history file whose keys are
+// selected, then rewrites it atomically
+func (sc *ShellCleaner) redactFile(file string, format shellhist.Format, keys map[string]bool) error {
+	entries, err := shellhist.ReadFile(file, format)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]shellhist.Entry, 0, len(entries))
+	changed := false
+	for _, entry := range entries {
+		if !keys[secretKey(file, entry.Command)] {
+			kept = append(kept, entry)
+			continue
+		}
+		changed = true
+		if sc.opts.Redact == RedactRemove {
+			continue
+		}
+		entry.Command = secrets.Redact(entry.Command, sc.secrets.Scan(entry.Command))
+		kept = append(kept, entry)
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := shellhist.Marshal(format, kept)
+	if err != nil {
+		return err
+	}
+	return shellhist.WriteFileAtomic(file, data)
+}
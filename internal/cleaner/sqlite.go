@@ -0,0 +1,72 @@
+package cleaner
+
+import (
+	"database/sql"
+	"fmt"
+
+	// Pure-Go SQLite driver, so builds keep working with CGO_ENABLED=0
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite opens a browser database for in-place editing. A busy timeout
+// lets us wait briefly for locks held by a running browser.
+func openSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	// A single connection keeps PRAGMAs and transactions on the same handle
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// sqlConn is implemented by *sql.DB and *sql.Tx
+type sqlConn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// tableExists reports whether the database has the given table. Browser
+// schemas change between versions, so optional tables are checked first.
+func tableExists(db sqlConn, table string) bool {
+	var name string
+	err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+	return err == nil
+}
+
+// countRows returns the result of a COUNT query, or 0 if it fails
+func countRows(db sqlConn, query string, args ...interface{}) int64 {
+	var n int64
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		return 0
+	}
+	return n
+}
+
+// execAll runs statements in order, stopping at the first error
+func execAll(db sqlConn, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// secureVacuum rebuilds the database with secure_delete on, so removed rows
+// do not survive in free pages, and folds the WAL back into the main file.
+func secureVacuum(db *sql.DB) error {
+	return execAll(db,
+		"PRAGMA secure_delete = ON",
+		"VACUUM",
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+	)
+}
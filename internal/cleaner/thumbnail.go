@@ -0,0 +1,177 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/thumbnail"
+)
+
+// Thumbnail cleaning modes
+const (
+	// ThumbnailsMissing removes thumbnails whose original file was deleted
+	ThumbnailsMissing = "missing"
+	// ThumbnailsUnder removes thumbnails of files under ThumbnailOptions.Dirs
+	ThumbnailsUnder = "under"
+	// ThumbnailsAll removes every thumbnail
+	ThumbnailsAll = "all"
+)
+
+// ThumbnailOptions selects the thumbnails to remove
+type ThumbnailOptions struct {
+	// Mode is ThumbnailsMissing (the default), ThumbnailsUnder or ThumbnailsAll
+	Mode string
+	// Dirs are directories (or URI prefixes) for ThumbnailsUnder
+	Dirs []string
+}
+
+// thumbnailSizes are the subdirectories of a thumbnail cache; fail holds
+// one directory per thumbnailer
+var thumbnailSizes = []string{"normal", "large", "x-large", "xx-large", "fail"}
+
+// thumbnailPrefix starts the IDs of thumbnail items, see itemID
+const thumbnailPrefix = "thumbnail:"
+
+// ThumbnailCleaner removes freedesktop thumbnails by their original files
+type ThumbnailCleaner struct {
+	roots []string
+	opts  ThumbnailOptions
+}
+
+// NewThumbnailCleaner creates a thumbnail cleaner that removes thumbnails
+// of deleted files
+func NewThumbnailCleaner() *ThumbnailCleaner {
+	return NewThumbnailCleanerWithOptions(ThumbnailOptions{})
+}
+
+// NewThumbnailCleanerWithOptions creates a thumbnail cleaner with the given mode
+func NewThumbnailCleanerWithOptions(opts ThumbnailOptions) *ThumbnailCleaner {
+	if opts.Mode == "" {
+		opts.Mode = ThumbnailsMissing
+	}
+	opts.Dirs = absDirs(opts.Dirs)
+
+	tc := &ThumbnailCleaner{opts: opts}
+	if roots, err := platform.GetThumbnailsPaths(); err == nil {
+		for _, root := range roots {
+			if info, err := os.Stat(root); err == nil && info.IsDir() {
+				tc.roots = append(tc.roots, root)
+			}
+		}
+	}
+	return tc
+}
+
+// Name returns the name of this cleaner
+func (tc *ThumbnailCleaner) Name() string {
+	return "Thumbnails"
+}
+
+// Locks returns the thumbnail directories
+func (tc *ThumbnailCleaner) Locks() []string {
+	return tc.roots
+}
+
+// selects reports whether the thumbnail of an original is removed
+func (tc *ThumbnailCleaner) selects(info thumbnail.Info) bool {
+	switch tc.opts.Mode {
+	case ThumbnailsAll:
+		return true
+	case ThumbnailsUnder:
+		return info.URI != "" && uriUnder(info.URI, info.Path(), tc.opts.Dirs)
+	default:
+		// Only local files can be checked; remote and unmounted volumes
+		// look missing too, as they do to GLib's own cleanup
+		path := info.Path()
+		if path == "" {
+			return false
+		}
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	}
+}
+
+// thumbnailFiles returns the thumbnails in a cache relative to root
+func thumbnailFiles(root string) []string {
+	files := make([]string, 0)
+	for _, size := range thumbnailSizes {
+		pattern := filepath.Join(root, size, "*.png")
+		if size == "fail" {
+			pattern = filepath.Join(root, size, "*", "*.png")
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			if rel, err := filepath.Rel(root, match); err == nil {
+				files = append(files, rel)
+			}
+		}
+	}
+	return files
+}
+
+// DryRun lists the selected thumbnails labelled with their original files
+func (tc *ThumbnailCleaner) DryRun() ([]Item, error) {
+	items := make([]Item, 0)
+
+	for _, root := range tc.roots {
+		for _, rel := range thumbnailFiles(root) {
+			path := filepath.Join(root, rel)
+			info, err := thumbnail.Read(path)
+			if err != nil || !tc.selects(info) {
+				continue
+			}
+
+			original := info.Path()
+			switch {
+			case info.URI == "":
+				original = "unknown original"
+			case original == "":
+				original = info.URI
+			}
+			size := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+			if tc.opts.Mode == ThumbnailsMissing {
+				size += ", deleted"
+			}
+
+			id := itemID("thumbnail", filepath.Base(root), strings.TrimSuffix(filepath.ToSlash(rel), ".png"))
+			items = append(items, fileItem(id, tc.Name(), "Thumbnails", fmt.Sprintf("%s (%s)", original, size), path))
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+
+	return items, nil
+}
+
+// Clean removes all selected thumbnails
+func (tc *ThumbnailCleaner) Clean() error {
+	items, err := tc.DryRun()
+	if err != nil {
+		return err
+	}
+	return tc.CleanItems(items)
+}
+
+// CleanItems removes the given thumbnails
+func (tc *ThumbnailCleaner) CleanItems(items []Item) error {
+	errors := make([]error, 0)
+
+	for _, item := range items {
+		if !strings.HasPrefix(item.ID, thumbnailPrefix) {
+			continue
+		}
+		if err := os.Remove(item.Path); err != nil && !os.IsNotExist(err) {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to remove some thumbnails: %v", errors)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package cleaner
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeRange limits cleaning to entries recorded in [Since, Until).
+// A zero bound is open, so the zero TimeRange covers all time.
+type TimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// OlderThan returns a range covering everything older than the given number of days
+func OlderThan(days int) TimeRange {
+	return TimeRange{Until: time.Now().AddDate(0, 0, -days)}
+}
+
+// IsZero reports whether the range covers all time
+func (r TimeRange) IsZero() bool {
+	return r.Since.IsZero() && r.Until.IsZero()
+}
+
+// Contains reports whether t falls inside the range
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && !t.Before(r.Until) {
+		return false
+	}
+	return true
+}
+
+// String describes the range, e.g. "before 2024-01-02"
+func (r TimeRange) String() string {
+	const layout = "2006-01-02 15:04"
+	switch {
+	case r.IsZero():
+		return "all time"
+	case r.Since.IsZero():
+		return fmt.Sprintf("before %s", r.Until.Local().Format(layout))
+	case r.Until.IsZero():
+		return fmt.Sprintf("since %s", r.Since.Local().Format(layout))
+	default:
+		return fmt.Sprintf("%s to %s", r.Since.Local().Format(layout), r.Until.Local().Format(layout))
+	}
+}
+
+// where returns an SQL condition limiting column to the range. toDB converts
+// a bound into the column's unit, e.g. microseconds since some epoch.
+func (r TimeRange) where(column string, toDB func(time.Time) int64) (string, []interface{}) {
+	switch {
+	case r.IsZero():
+		return "1 = 1", nil
+	case r.Since.IsZero():
+		return column + " < ?", []interface{}{toDB(r.Until)}
+	case r.Until.IsZero():
+		return column + " >= ?", []interface{}{toDB(r.Since)}
+	default:
+		return column + " >= ? AND " + column + " < ?", []interface{}{toDB(r.Since), toDB(r.Until)}
+	}
+}
@@ -0,0 +1,224 @@
+package cleaner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/event"
+	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/trash"
+	"github.com/mat/gowipeme/internal/wiper"
+)
+
+// TrashOptions selects the trashed items to remove and how
+type TrashOptions struct {
+	// Range limits removal to items trashed within the range; items without
+	// a deletion date are kept when it is set
+	Range TimeRange
+	// Shred overwrites trashed files with Method before unlinking them
+	Shred  bool
+	Method wiper.WipeMethod
+}
+
+// trashPrefix starts the IDs of trash items, see itemID
+const trashPrefix = "trash:"
+
+// trashKey returns a short stable key for an item in a trash directory.
+// Names are hashed since item IDs are lowercase and names are not.
+func trashKey(dir, name string) string {
+	sum := sha256.Sum256([]byte(dir + "\x00" + name))
+	return hex.EncodeToString(sum[:6])
+}
+
+// TrashCleaner empties the freedesktop trash of the home directory and of
+// mounted volumes
+type TrashCleaner struct {
+	dirs []trash.Dir
+	opts TrashOptions
+}
+
+// NewTrashCleaner creates a new trash cleaner
+func NewTrashCleaner() *TrashCleaner {
+	return NewTrashCleanerWithOptions(TrashOptions{})
+}
+
+// NewTrashCleanerWithOptions creates a trash cleaner limited to a time range
+// that optionally overwrites files before removing them
+func NewTrashCleanerWithOptions(opts TrashOptions) *TrashCleaner {
+	tc := &TrashCleaner{opts: opts}
+	if paths, err := platform.GetTrashPaths(); err == nil {
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				tc.dirs = append(tc.dirs, trash.NewDir(path))
+			}
+		}
+	}
+	return tc
+}
+
+// Name returns the name of this cleaner
+func (tc *TrashCleaner) Name() string {
+	return "Trash"
+}
+
+// Locks returns the trash directories
+func (tc *TrashCleaner) Locks() []string {
+	locks := make([]string, 0, len(tc.dirs))
+	for _, dir := range tc.dirs {
+		locks = append(locks, dir.Path)
+	}
+	return locks
+}
+
+// trashEntry is a trashed item and the trash directory holding it
+type trashEntry struct {
+	dir   trash.Dir
+	entry trash.Entry
+}
+
+// entries returns the trashed items selected by the options, by item ID
+func (tc *TrashCleaner) entries() (map[string]trashEntry, []string) {
+	selected := make(map[string]trashEntry)
+	ids := make([]string, 0)
+
+	for _, dir := range tc.dirs {
+		entries, err := dir.Entries()
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !tc.opts.Range.IsZero() && (e.Info.DeletionDate.IsZero() || !tc.opts.Range.Contains(e.Info.DeletionDate)) {
+				continue
+			}
+			id := itemID("trash", trashKey(dir.Path, e.Name))
+			selected[id] = trashEntry{dir: dir, entry: e}
+			ids = append(ids, id)
+		}
+	}
+
+	return selected, ids
+}
+
+// DryRun lists the trashed items with their original paths and deletion dates
+func (tc *TrashCleaner) DryRun() ([]Item, error) {
+	selected, ids := tc.entries()
+	items := make([]Item, 0, len(ids))
+
+	for _, id := range ids {
+		t := selected[id]
+		label := t.dir.OriginalPath(t.entry)
+		switch {
+		case label == "":
+			label = t.entry.Name + " (no trash info)"
+		case t.entry.Info.DeletionDate.IsZero():
+			label += " (deleted at an unknown time)"
+		default:
+			label += fmt.Sprintf(" (deleted %s)", t.entry.Info.DeletionDate.Format("2006-01-02 15:04"))
+		}
+
+		item := fileItem(id, tc.Name(), "Trash", label, t.dir.FilePath(t.entry.Name))
+		if !t.entry.Info.DeletionDate.IsZero() {
+			item.ModTime = t.entry.Info.DeletionDate
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// notOverwritten reports whether shredding removed files without
+// overwriting them, such as hard-linked files
+func notOverwritten(err error) bool {
+	return errors.Is(err, wiper.ErrNotOverwritten)
+}
+
+// remove deletes a trashed file or directory, overwriting it first when
+// shredding
+func (tc *TrashCleaner) remove(ctx context.Context, path string) error {
+	if !tc.opts.Shred {
+		return removeAllContext(ctx, path)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return wiper.ShredTreeContext(ctx, path, tc.opts.Method)
+	}
+	return wiper.ShredFileContext(ctx, path, tc.opts.Method)
+}
+
+// Clean empties the trash within the configured range
+func (tc *TrashCleaner) Clean() error {
+	items, err := tc.DryRun()
+	if err != nil {
+		return err
+	}
+	return tc.CleanItems(items)
+}
+
+// CleanItems removes the given trashed items and their info files
+func (tc *TrashCleaner) CleanItems(items []Item) error {
+	return tc.CleanItemsContext(context.Background(), items, nil)
+}
+
+// CleanItemsContext removes the given trashed items until the context is
+// cancelled. An item being removed when it is keeps its info file. When
+// shredding, items with files that could not be overwritten are still
+// removed and reported in the returned error.
+func (tc *TrashCleaner) CleanItemsContext(ctx context.Context, items []Item, report event.Func) error {
+	errors := make([]error, 0)
+	unshredded := make([]error, 0)
+
+	selected, _ := tc.entries()
+	touched := make(map[string]trash.Dir)
+
+	for i, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		t, ok := selected[item.ID]
+		if ok && strings.HasPrefix(item.ID, trashPrefix) {
+			remove := func(path string) error {
+				err := tc.remove(ctx, path)
+				if notOverwritten(err) {
+					// The files are gone, so the info file goes too
+					unshredded = append(unshredded, fmt.Errorf("%s: %w", item.Label, err))
+					return nil
+				}
+				return err
+			}
+			if err := t.dir.Remove(t.entry.Name, remove); err != nil && ctx.Err() == nil {
+				errors = append(errors, fmt.Errorf("%s: %w", item.Label, err))
+			}
+			touched[t.dir.Path] = t.dir
+		}
+		if ctx.Err() == nil {
+			report.Send(event.Event{Kind: event.Progress, Step: item.Label, Done: int64(i + 1)})
+		}
+	}
+
+	// Removed directories must not linger in the size cache
+	for _, dir := range touched {
+		if err := dir.SyncDirectorySizes(); err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", dir.Path, err))
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to empty some trash: %v", append(errors, unshredded...))
+	}
+	if len(unshredded) > 0 {
+		return fmt.Errorf("removed some trash without overwriting it: %v", unshredded)
+	}
+
+	return nil
+}
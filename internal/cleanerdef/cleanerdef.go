@@ -0,0 +1,399 @@
+// Package cleanerdef reads declarative cleaner definitions, the YAML or JSON
+// files in ~/.gowipeme/cleaners.d/ that describe the traces an application
+// leaves and how to remove them. BleachBit CleanerML files are imported
+// into the same form.
+package cleanerdef
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mat/gowipeme/internal/platform"
+	"github.com/mat/gowipeme/internal/wiper"
+)
+
+// Action is what a rule does to the files it matches
+type Action string
+
+const (
+	// Delete removes files and directory trees
+	Delete Action = "delete"
+	// Truncate empties files, for logs an application keeps open
+	Truncate Action = "truncate"
+	// Shred overwrites files with a wipe method before removing them
+	Shred Action = "shred"
+	// SQLiteDeleteWhere deletes the rows of a table matching a condition
+	SQLiteDeleteWhere Action = "sqlite-delete-where"
+	// JSONKeyDelete removes keys from JSON files, keeping the others
+	JSONKeyDelete Action = "json-key-delete"
+	// SQLiteVacuum rebuilds SQLite databases so deleted rows do not linger
+	// in free pages
+	SQLiteVacuum Action = "sqlite-vacuum"
+)
+
+// actions lists the valid actions in the order error messages name them
+var actions = []Action{Delete, Truncate, Shred, SQLiteDeleteWhere, JSONKeyDelete, SQLiteVacuum}
+
+// operatingSystems are the valid keys of per-OS sections
+var operatingSystems = []string{"linux", "darwin", "windows"}
+
+// Definition describes one cleaner
+type Definition struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description"`
+	// Processes are executable names of the application; cleaning warns or
+	// waits while they run
+	Processes []string `json:"processes,omitempty" yaml:"processes"`
+	// Rules apply on every operating system
+	Rules []Rule `json:"rules,omitempty" yaml:"rules"`
+	// OS holds rules for one operating system, keyed linux, darwin or windows
+	OS map[string][]Rule `json:"os,omitempty" yaml:"os"`
+
+	// File is the file the definition was read from
+	File string `json:"-" yaml:"-"`
+	// Warnings lists parts of an imported file that were left out, such as
+	// unsupported CleanerML actions
+	Warnings []string `json:"-" yaml:"-"`
+}
+
+// Rule applies an action to the files matching its paths
+type Rule struct {
+	// Description labels the rule's item, e.g. "crash reports"
+	Description string `json:"description,omitempty" yaml:"description"`
+	// Category groups the item with others, e.g. "Cache"; "Custom" by default
+	Category string `json:"category,omitempty" yaml:"category"`
+	// Paths are globs starting with ~, a variable such as $XDG_CONFIG_HOME
+	// or an absolute path; * and ? do not cross directory separators
+	Paths  []string `json:"paths" yaml:"paths"`
+	Action Action   `json:"action" yaml:"action"`
+	// Recurse applies delete, truncate or shred to every file below the
+	// matched directories, keeping the directories themselves
+	Recurse bool `json:"recurse,omitempty" yaml:"recurse"`
+	// Risk is low (default), medium or high
+	Risk string `json:"risk,omitempty" yaml:"risk"`
+
+	// Method is the wipe method of shred: zeros (default), dod or gutmann
+	Method string `json:"method,omitempty" yaml:"method"`
+	// Table and Where select the rows of sqlite-delete-where
+	Table string `json:"table,omitempty" yaml:"table"`
+	Where string `json:"where,omitempty" yaml:"where"`
+	// Keys are the dot-separated keys of json-key-delete, e.g. "recent.files"
+	Keys []string `json:"keys,omitempty" yaml:"keys"`
+}
+
+// RulesFor returns the rules that apply on an operating system: the common
+// rules followed by those of its section
+func (d *Definition) RulesFor(goos string) []Rule {
+	rules := make([]Rule, 0, len(d.Rules)+len(d.OS[goos]))
+	rules = append(rules, d.Rules...)
+	return append(rules, d.OS[goos]...)
+}
+
+// Dir returns the directory definitions are loaded from
+func Dir() (string, error) {
+	home, err := platform.GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gowipeme", "cleaners.d"), nil
+}
+
+// IsDefinitionFile reports whether a file name has a definition extension
+func IsDefinitionFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json", ".xml":
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse decodes a definition, as JSON, YAML or CleanerML by the file name's
+// extension. Unknown fields are errors, so typos do not go unnoticed.
+func Parse(name string, data []byte) (*Definition, error) {
+	def := &Definition{File: name}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xml":
+		return ImportCleanerML(name, data)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(def); err != nil {
+			return nil, err
+		}
+		return def, nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(def); err == io.EOF {
+		return nil, fmt.Errorf("file is empty")
+	} else if err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// identifier matches SQLite table names that need no quoting
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks a definition and returns every problem found, each
+// naming the offending field, e.g. `os.linux[0].action: unknown action "wipe"`
+func (d *Definition) Validate() []error {
+	problems := make([]error, 0)
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if strings.TrimSpace(d.Name) == "" {
+		add("name", "is required")
+	}
+	for i, name := range d.Processes {
+		if strings.TrimSpace(name) == "" {
+			add(fmt.Sprintf("processes[%d]", i), "is empty")
+		}
+	}
+
+	count := len(d.Rules)
+	for i, rule := range d.Rules {
+		problems = append(problems, rule.validate(fmt.Sprintf("rules[%d]", i))...)
+	}
+
+	oses := make([]string, 0, len(d.OS))
+	for goos := range d.OS {
+		oses = append(oses, goos)
+	}
+	sort.Strings(oses)
+	for _, goos := range oses {
+		if !contains(operatingSystems, goos) {
+			add("os."+goos, "unknown operating system (want %s)", strings.Join(operatingSystems, ", "))
+			continue
+		}
+		count += len(d.OS[goos])
+		for i, rule := range d.OS[goos] {
+			problems = append(problems, rule.validate(fmt.Sprintf("os.%s[%d]", goos, i))...)
+		}
+	}
+
+	if count == 0 {
+		add("rules", "at least one rule is required")
+	}
+
+	return problems
+}
+
+// Validate checks a single rule, naming the offending field of each
+// problem, e.g. `paths[0]: unknown variable $WINDIR`
+func (r Rule) Validate() []error {
+	return r.validate("")
+}
+
+// validate checks a rule; field is its position, e.g. "rules[2]"
+func (r Rule) validate(field string) []error {
+	problems := make([]error, 0)
+	add := func(sub, format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf("%s: %s", strings.TrimPrefix(field+sub, "."), fmt.Sprintf(format, args...)))
+	}
+
+	if len(r.Paths) == 0 {
+		add(".paths", "at least one path is required")
+	}
+	for i, pattern := range r.Paths {
+		if err := checkPath(pattern); err != nil {
+			add(fmt.Sprintf(".paths[%d]", i), "%v", err)
+		}
+	}
+
+	switch r.Risk {
+	case "", "low", "medium", "high":
+	default:
+		add(".risk", "unknown risk %q (want low, medium or high)", r.Risk)
+	}
+
+	if !containsAction(r.Action) {
+		names := make([]string, len(actions))
+		for i, a := range actions {
+			names[i] = string(a)
+		}
+		if r.Action == "" {
+			add(".action", "is required (want %s)", strings.Join(names, ", "))
+		} else {
+			add(".action", "unknown action %q (want %s)", r.Action, strings.Join(names, ", "))
+		}
+		return problems
+	}
+
+	// Fields belonging to other actions are mistakes, not options
+	if r.Method != "" && r.Action != Shred {
+		add(".method", "only applies to shred")
+	}
+	if r.Table != "" && r.Action != SQLiteDeleteWhere {
+		add(".table", "only applies to sqlite-delete-where")
+	}
+	if r.Where != "" && r.Action != SQLiteDeleteWhere {
+		add(".where", "only applies to sqlite-delete-where")
+	}
+	if len(r.Keys) > 0 && r.Action != JSONKeyDelete {
+		add(".keys", "only applies to json-key-delete")
+	}
+	if r.Recurse && r.Action != Delete && r.Action != Truncate && r.Action != Shred {
+		add(".recurse", "only applies to delete, truncate and shred")
+	}
+
+	switch r.Action {
+	case Shred:
+		if r.Method != "" {
+			if _, err := wiper.ParseMethod(r.Method); err != nil {
+				add(".method", "%v", err)
+			}
+		}
+	case SQLiteDeleteWhere:
+		if r.Table == "" {
+			add(".table", "is required")
+		} else if !identifier.MatchString(r.Table) {
+			add(".table", "%q is not a plain table name", r.Table)
+		}
+		if strings.TrimSpace(r.Where) == "" {
+			add(".where", "is required; use \"1\" to delete every row")
+		} else if strings.Contains(r.Where, ";") {
+			add(".where", "must be a single condition without ';'")
+		}
+	case JSONKeyDelete:
+		if len(r.Keys) == 0 {
+			add(".keys", "at least one key is required")
+		}
+		for i, key := range r.Keys {
+			if key == "" || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") || strings.Contains(key, "..") {
+				add(fmt.Sprintf(".keys[%d]", i), "%q is not a dot-separated key", key)
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkPath reports why a path pattern cannot be used
+func checkPath(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("is empty")
+	}
+	if !strings.HasPrefix(pattern, "~") && !strings.HasPrefix(pattern, "$") && !isAbs(pattern) {
+		return fmt.Errorf("%q must start with ~, a variable or an absolute path", pattern)
+	}
+	expanded, err := Expand(pattern)
+	if errors.Is(err, ErrUnset) {
+		// Only the syntax can be checked for another system's variables
+		expanded = pattern
+	} else if err != nil {
+		return err
+	}
+	if _, err := filepath.Match(expanded, ""); err != nil {
+		return fmt.Errorf("%q: %v", pattern, err)
+	}
+	if TooBroad(Base(expanded)) {
+		return fmt.Errorf("%q would match directly in the home or root directory; name a subdirectory", pattern)
+	}
+	return nil
+}
+
+// isAbs reports whether a path is absolute on any operating system, so
+// Windows sections can be checked elsewhere
+func isAbs(path string) bool {
+	if strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\\`) {
+		return true
+	}
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '/' || path[2] == '\\')
+}
+
+// LoadDir reads every definition file in dir, in name order. Files that do
+// not parse or validate are left out and reported, as are definitions
+// reusing another's name. A missing directory yields no definitions.
+func LoadDir(dir string) ([]*Definition, []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	defs := make([]*Definition, 0)
+	problems := make([]error, 0)
+	names := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !IsDefinitionFile(entry.Name()) {
+			continue
+		}
+		def, errs := LoadFile(filepath.Join(dir, entry.Name()))
+		if len(errs) > 0 {
+			problems = append(problems, errs...)
+			continue
+		}
+		key := strings.ToLower(def.Name)
+		if other, ok := names[key]; ok {
+			problems = append(problems, fmt.Errorf("%s: name: %q is already used by %s", entry.Name(), def.Name, other))
+			continue
+		}
+		names[key] = entry.Name()
+		defs = append(defs, def)
+	}
+
+	return defs, problems
+}
+
+// LoadFile reads and validates one definition file. Errors start with the
+// file's name.
+func LoadFile(path string) (*Definition, []error) {
+	name := filepath.Base(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{err}
+	}
+	def, err := Parse(path, data)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", name, err)}
+	}
+
+	errs := def.Validate()
+	for i, err := range errs {
+		errs[i] = fmt.Errorf("%s: %w", name, err)
+	}
+	if len(errs) > 0 {
+		// An import with nothing left to do failed because of what it left out
+		for _, warning := range def.Warnings {
+			errs = append(errs, fmt.Errorf("%s: %s", name, warning))
+		}
+		return nil, errs
+	}
+	return def, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAction(a Action) bool {
+	for _, v := range actions {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package cleanerdef
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setHome points the home directory at /home/u and unsets the variables
+// that would otherwise override the defaults below it
+func setHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", filepath.FromSlash("/home/u"))
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "APPDATA", "LOCALAPPDATA", "USERPROFILE"} {
+		t.Setenv(name, "")
+	}
+}
+
+// messages returns the text of errs
+func messages(errs []error) []string {
+	texts := make([]string, 0, len(errs))
+	for _, err := range errs {
+		texts = append(texts, err.Error())
+	}
+	return texts
+}
+
+func TestExpand(t *testing.T) {
+	setHome(t)
+	t.Setenv("XDG_DATA_HOME", filepath.FromSlash("/data"))
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"~", "/home/u"},
+		{"~/.app/cache/*", "/home/u/.app/cache/*"},
+		{"$HOME/.app", "/home/u/.app"},
+		{"${XDG_CONFIG_HOME}/app/*.log", "/home/u/.config/app/*.log"},
+		{"$XDG_CACHE_HOME/app", "/home/u/.cache/app"},
+		{"$XDG_STATE_HOME/app", "/home/u/.local/state/app"},
+		{"$XDG_DATA_HOME/app", "/data/app"},
+		{"/var/tmp/app-*", "/var/tmp/app-*"},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.pattern)
+		if err != nil {
+			t.Errorf("Expand(%q): %v", tt.pattern, err)
+			continue
+		}
+		if want := filepath.FromSlash(tt.want); got != want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.pattern, got, want)
+		}
+	}
+
+	if _, err := Expand("$APPDATA/App"); !errors.Is(err, ErrUnset) {
+		t.Errorf("Expand($APPDATA) error = %v, want ErrUnset", err)
+	}
+	want := "unknown variable $WINDIR (want $APPDATA, $HOME, $LOCALAPPDATA, $TMPDIR, $USERPROFILE, $XDG_CACHE_HOME, $XDG_CONFIG_HOME, $XDG_DATA_HOME, $XDG_STATE_HOME)"
+	if _, err := Expand("$WINDIR/Temp"); err == nil || err.Error() != want {
+		t.Errorf("Expand($WINDIR) error = %v, want %q", err, want)
+	}
+}
+
+func TestBase(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"/home/u/.app/cache/*", "/home/u/.app/cache"},
+		{"/home/u/.app/*/Cache/*", "/home/u/.app"},
+		{"/home/u/.app/log-?.txt", "/home/u/.app"},
+		{"/home/u/[ab]", "/home/u"},
+		{"/*", "/"},
+		{"/home/u/.app/cache", "/home/u/.app/cache"},
+	}
+	for _, tt := range tests {
+		if got, want := Base(filepath.FromSlash(tt.pattern)), filepath.FromSlash(tt.want); got != want {
+			t.Errorf("Base(%q) = %q, want %q", tt.pattern, got, want)
+		}
+	}
+}
+
+func TestTooBroad(t *testing.T) {
+	setHome(t)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{".", true},
+		{"/home", true},
+		{"/home/u", true},
+		{"/home/u/", true},
+		{"/home/u/.cache/..", true},
+		{"/home/u/.cache", false},
+		{"/home/user2", false},
+		{"/tmp", false},
+	}
+	for _, tt := range tests {
+		if got := TooBroad(filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("TooBroad(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	setHome(t)
+
+	valid := Rule{Paths: []string{"~/.app/cache/*"}, Action: Delete}
+	actionList := "delete, truncate, shred, sqlite-delete-where, json-key-delete, sqlite-vacuum"
+
+	tests := []struct {
+		name string
+		def  Definition
+		want []string
+	}{
+		{
+			name: "valid",
+			def: Definition{
+				Name:      "App",
+				Processes: []string{"app"},
+				Rules:     []Rule{valid},
+				OS: map[string][]Rule{
+					"linux":   {{Paths: []string{"$XDG_CONFIG_HOME/app/state.json"}, Action: JSONKeyDelete, Keys: []string{"recent.files"}}},
+					"windows": {{Paths: []string{"$APPDATA/App/history.db"}, Action: SQLiteDeleteWhere, Table: "visits", Where: "1", Risk: "medium"}},
+				},
+			},
+			want: []string{},
+		},
+		{
+			name: "empty",
+			def:  Definition{},
+			want: []string{"name: is required", "rules: at least one rule is required"},
+		},
+		{
+			name: "empty process name",
+			def:  Definition{Name: "App", Processes: []string{"app", " "}, Rules: []Rule{valid}},
+			want: []string{"processes[1]: is empty"},
+		},
+		{
+			name: "unknown operating system",
+			def:  Definition{Name: "App", OS: map[string][]Rule{"plan9": {valid}}},
+			want: []string{
+				"os.plan9: unknown operating system (want linux, darwin, windows)",
+				"rules: at least one rule is required",
+			},
+		},
+		{
+			name: "actions",
+			def: Definition{Name: "App", Rules: []Rule{valid, {Paths: valid.Paths}}, OS: map[string][]Rule{
+				"darwin": {{Paths: valid.Paths, Action: "wipe"}},
+			}},
+			want: []string{
+				"rules[1].action: is required (want " + actionList + ")",
+				`os.darwin[0].action: unknown action "wipe" (want ` + actionList + ")",
+			},
+		},
+		{
+			name: "paths",
+			def: Definition{Name: "App", Rules: []Rule{
+				{Action: Delete},
+				{Paths: []string{"", "app/cache", "~/*", "/", "~/.app/[", "$WINDIR/Temp", "$LOCALAPPDATA/App/*"}, Action: Delete},
+			}},
+			want: []string{
+				"rules[0].paths: at least one path is required",
+				"rules[1].paths[0]: is empty",
+				`rules[1].paths[1]: "app/cache" must start with ~, a variable or an absolute path`,
+				`rules[1].paths[2]: "~/*" would match directly in the home or root directory; name a subdirectory`,
+				`rules[1].paths[3]: "/" would match directly in the home or root directory; name a subdirectory`,
+				`rules[1].paths[4]: "~/.app/[": syntax error in pattern`,
+				"rules[1].paths[5]: unknown variable $WINDIR (want $APPDATA, $HOME, $LOCALAPPDATA, $TMPDIR, $USERPROFILE, $XDG_CACHE_HOME, $XDG_CONFIG_HOME, $XDG_DATA_HOME, $XDG_STATE_HOME)",
+			},
+		},
+		{
+			name: "fields of other actions",
+			def: Definition{Name: "App", Rules: []Rule{
+				{Paths: valid.Paths, Action: JSONKeyDelete, Keys: []string{"a..b", ".a", "ok"}, Risk: "extreme", Method: "dod", Recurse: true},
+			}},
+			want: []string{
+				`rules[0].risk: unknown risk "extreme" (want low, medium or high)`,
+				"rules[0].method: only applies to shred",
+				"rules[0].recurse: only applies to delete, truncate and shred",
+				`rules[0].keys[0]: "a..b" is not a dot-separated key`,
+				`rules[0].keys[1]: ".a" is not a dot-separated key`,
+			},
+		},
+		{
+			name: "action settings",
+			def: Definition{Name: "App", Rules: []Rule{
+				{Paths: valid.Paths, Action: SQLiteDeleteWhere},
+				{Paths: valid.Paths, Action: SQLiteDeleteWhere, Table: "my table", Where: "1; DROP TABLE visits"},
+				{Paths: valid.Paths, Action: JSONKeyDelete},
+				{Paths: valid.Paths, Action: Shred, Method: "fast", Recurse: true},
+			}},
+			want: []string{
+				"rules[0].table: is required",
+				`rules[0].where: is required; use "1" to delete every row`,
+				`rules[1].table: "my table" is not a plain table name`,
+				"rules[1].where: must be a single condition without ';'",
+				"rules[2].keys: at least one key is required",
+				`rules[3].method: unknown wipe method "fast" (expected zeros, dod or gutmann)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messages(tt.def.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	setHome(t)
+
+	got := messages(Rule{Paths: []string{"~"}, Action: Truncate, Table: "t"}.Validate())
+	want := []string{
+		`paths[0]: "~" would match directly in the home or root directory; name a subdirectory`,
+		"table: only applies to sqlite-delete-where",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rule.Validate() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,393 @@
+package cleanerdef
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// xmlCleaner is the root element of a BleachBit CleanerML file
+type xmlCleaner struct {
+	XMLName     xml.Name     `xml:"cleaner"`
+	ID          string       `xml:"id,attr"`
+	OS          string       `xml:"os,attr"`
+	Label       string       `xml:"label"`
+	Description string       `xml:"description"`
+	Running     []xmlRunning `xml:"running"`
+	Vars        []xmlVar     `xml:"var"`
+	Options     []xmlOption  `xml:"option"`
+}
+
+type xmlRunning struct {
+	Type string `xml:"type,attr"`
+	OS   string `xml:"os,attr"`
+	Name string `xml:",chardata"`
+}
+
+type xmlVar struct {
+	Name   string     `xml:"name,attr"`
+	Values []xmlValue `xml:"value"`
+}
+
+type xmlValue struct {
+	OS    string `xml:"os,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlOption struct {
+	ID          string      `xml:"id,attr"`
+	Label       string      `xml:"label"`
+	Description string      `xml:"description"`
+	Warning     string      `xml:"warning"`
+	Actions     []xmlAction `xml:"action"`
+}
+
+type xmlAction struct {
+	Command     string `xml:"command,attr"`
+	Search      string `xml:"search,attr"`
+	Path        string `xml:"path,attr"`
+	OS          string `xml:"os,attr"`
+	Address     string `xml:"address,attr"`
+	Regex       string `xml:"regex,attr"`
+	NRegex      string `xml:"nregex,attr"`
+	WholeRegex  string `xml:"wholeregex,attr"`
+	NWholeRegex string `xml:"nwholeregex,attr"`
+	Type        string `xml:"type,attr"`
+}
+
+// cleanerMLOS maps CleanerML os attributes to the operating systems they
+// cover; an empty attribute covers all of them
+var cleanerMLOS = map[string][]string{
+	"":        operatingSystems,
+	"linux":   {"linux"},
+	"darwin":  {"darwin"},
+	"windows": {"windows"},
+	"unix":    {"linux", "darwin"},
+	"posix":   {"linux", "darwin"},
+}
+
+// ImportCleanerML converts a BleachBit CleanerML file into a definition.
+// Each option becomes rules labelled with the option's label; delete,
+// truncate, json and sqlite.vacuum actions are supported. Actions,
+// searches and filters without an equivalent are left out and listed in
+// the definition's Warnings.
+func ImportCleanerML(name string, data []byte) (*Definition, error) {
+	var doc xmlCleaner
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid CleanerML: %v", err)
+	}
+
+	def := &Definition{
+		Name:        strings.TrimSpace(doc.Label),
+		Description: strings.TrimSpace(doc.Description),
+		File:        name,
+		OS:          make(map[string][]Rule),
+	}
+	if def.Name == "" {
+		def.Name = doc.ID
+	}
+	warn := func(format string, args ...interface{}) {
+		def.Warnings = append(def.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if _, ok := cleanerMLOS[doc.OS]; !ok {
+		warn("cleaner: os %q is not supported", doc.OS)
+		return def, nil
+	}
+
+	for _, running := range doc.Running {
+		process := strings.TrimSpace(running.Name)
+		switch {
+		case running.Type != "exe":
+			warn("running %q: type %q is not supported", process, running.Type)
+		case !contains(def.Processes, process):
+			def.Processes = append(def.Processes, process)
+		}
+	}
+
+	vars := make(map[string][]xmlValue, len(doc.Vars))
+	for _, v := range doc.Vars {
+		vars[v.Name] = append(vars[v.Name], v.Values...)
+	}
+
+	for _, option := range doc.Options {
+		label := strings.TrimSpace(option.Label)
+		if label == "" {
+			label = option.ID
+		}
+		// BleachBit only runs options with a warning on request; high risk
+		// asks a second time before they are cleaned
+		risk := ""
+		if strings.TrimSpace(option.Warning) != "" {
+			risk = "high"
+		}
+
+		// Rules of an option differing only in their paths are merged, so
+		// the option shows as one item where possible
+		common := make([]Rule, 0)
+		byOS := make(map[string][]Rule)
+		for i, action := range option.Actions {
+			where := fmt.Sprintf("option %q: action %d", option.ID, i+1)
+			rule, err := convertAction(action)
+			if err != nil {
+				warn("%s: %v", where, err)
+				continue
+			}
+			rule.Description = label
+			rule.Risk = risk
+
+			actionOS := action.OS
+			if actionOS == "" {
+				actionOS = doc.OS
+			}
+			oses, ok := cleanerMLOS[actionOS]
+			if !ok {
+				warn("%s: os %q is not supported", where, actionOS)
+				continue
+			}
+
+			paths := make(map[string][]string, len(oses))
+			for _, goos := range oses {
+				expanded, err := expandCleanerMLVars(rule.Paths[0], vars, goos)
+				if err != nil {
+					warn("%s: %v", where, err)
+					paths = nil
+					break
+				}
+				paths[goos] = expanded
+			}
+			if paths == nil {
+				continue
+			}
+
+			sections := oses
+			if len(oses) == len(operatingSystems) && samePaths(paths) {
+				sections = []string{""}
+				paths[""] = paths[oses[0]]
+			}
+			for _, goos := range sections {
+				for _, path := range paths[goos] {
+					r := rule
+					r.Paths = []string{path}
+					if errs := r.Validate(); len(errs) > 0 {
+						warn("%s: %v", where, errs[0])
+						continue
+					}
+					if goos == "" {
+						common = mergeRule(common, r)
+					} else {
+						byOS[goos] = mergeRule(byOS[goos], r)
+					}
+				}
+			}
+		}
+
+		def.Rules = append(def.Rules, common...)
+		for goos, rules := range byOS {
+			def.OS[goos] = append(def.OS[goos], rules...)
+		}
+	}
+
+	return def, nil
+}
+
+// convertAction converts one action into a rule with a single path still
+// holding CleanerML variables, or reports why it has no equivalent
+func convertAction(action xmlAction) (Rule, error) {
+	filters := []struct{ attr, value string }{
+		{"regex", action.Regex},
+		{"nregex", action.NRegex},
+		{"wholeregex", action.WholeRegex},
+		{"nwholeregex", action.NWholeRegex},
+		{"type", action.Type},
+	}
+	for _, filter := range filters {
+		if filter.value != "" {
+			return Rule{}, fmt.Errorf("%s filters are not supported", filter.attr)
+		}
+	}
+
+	path := strings.TrimSpace(action.Path)
+	if path == "" {
+		return Rule{}, fmt.Errorf("%s has no path", action.Command)
+	}
+	path = toSlashVars(path)
+
+	search := action.Search
+	if search == "" {
+		search = "file"
+	}
+
+	rule := Rule{}
+	switch action.Command {
+	case "delete", "truncate":
+		rule.Action = Action(action.Command)
+		switch search {
+		case "file", "glob":
+		case "walk.top":
+			// The directory goes along with everything below it
+			if rule.Action == Truncate {
+				rule.Recurse = true
+			}
+		case "walk.all":
+			if rule.Action == Truncate {
+				rule.Recurse = true
+			} else {
+				path = strings.TrimSuffix(path, "/") + "/*"
+			}
+		case "walk.files":
+			rule.Recurse = true
+		default:
+			return Rule{}, fmt.Errorf("search %q is not supported", search)
+		}
+	case "json", "sqlite.vacuum":
+		if search != "file" && search != "glob" {
+			return Rule{}, fmt.Errorf("search %q is not supported for %s", search, action.Command)
+		}
+		rule.Action = SQLiteVacuum
+		if action.Command == "json" {
+			if action.Address == "" || strings.Contains(action.Address, ".") {
+				return Rule{}, fmt.Errorf("json address %q is not supported", action.Address)
+			}
+			rule.Action = JSONKeyDelete
+			rule.Keys = []string{strings.ReplaceAll(strings.Trim(action.Address, "/"), "/", ".")}
+		}
+	default:
+		return Rule{}, fmt.Errorf("command %q is not supported", action.Command)
+	}
+
+	rule.Paths = []string{strings.TrimSuffix(path, "/")}
+	return rule, nil
+}
+
+// toSlashVars converts a CleanerML path to the form of definition paths:
+// forward slashes, and %VAR% or $var naming a known variable in any case
+// written as ${VAR}. $$name$$ cleaner variables are kept for later.
+func toSlashVars(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+
+	// %VAR% is the Windows spelling
+	for {
+		start := strings.Index(path, "%")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(path[start+1:], "%")
+		if end < 0 {
+			break
+		}
+		name := path[start+1 : start+1+end]
+		path = path[:start] + "$" + name + path[start+2+end:]
+	}
+
+	var b strings.Builder
+	rest := path
+	for rest != "" {
+		i := strings.Index(rest, "$")
+		if i < 0 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:i])
+		rest = rest[i:]
+		if strings.HasPrefix(rest, "$$") {
+			// A cleaner variable runs up to the closing $$
+			end := strings.Index(rest[2:], "$$")
+			if end < 0 {
+				b.WriteString(rest)
+				break
+			}
+			b.WriteString(rest[:end+4])
+			rest = rest[end+4:]
+			continue
+		}
+		name, width := variableAt(rest)
+		if upper := strings.ToUpper(name); variables[upper] != nil {
+			name = upper
+		}
+		if name == "" {
+			b.WriteString("$")
+			rest = rest[1:]
+			continue
+		}
+		b.WriteString("${" + name + "}")
+		rest = rest[width:]
+	}
+	return b.String()
+}
+
+// variableAt returns the name of the $NAME or ${NAME} variable at the start
+// of s and how many bytes it spans
+func variableAt(s string) (string, int) {
+	if strings.HasPrefix(s, "${") {
+		end := strings.Index(s, "}")
+		if end < 0 {
+			return "", 0
+		}
+		return s[2:end], end + 1
+	}
+	i := 1
+	for i < len(s) && (s[i] == '_' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || i > 1 && s[i] >= '0' && s[i] <= '9') {
+		i++
+	}
+	return s[1:i], i
+}
+
+// expandCleanerMLVars replaces $$name$$ cleaner variables with each of
+// their values for an operating system, returning one path per value
+func expandCleanerMLVars(path string, vars map[string][]xmlValue, goos string) ([]string, error) {
+	start := strings.Index(path, "$$")
+	if start < 0 {
+		return []string{path}, nil
+	}
+	end := strings.Index(path[start+2:], "$$")
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated variable in %q", path)
+	}
+	name := path[start+2 : start+2+end]
+	values, ok := vars[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable $$%s$$", name)
+	}
+
+	paths := make([]string, 0)
+	for _, value := range values {
+		if oses, ok := cleanerMLOS[value.OS]; !ok || !contains(oses, goos) {
+			continue
+		}
+		replaced := path[:start] + toSlashVars(strings.TrimSpace(value.Value)) + path[start+4+end:]
+		expanded, err := expandCleanerMLVars(replaced, vars, goos)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, expanded...)
+	}
+	return paths, nil
+}
+
+// samePaths reports whether every operating system has the same paths
+func samePaths(paths map[string][]string) bool {
+	for _, goos := range operatingSystems[1:] {
+		if !reflect.DeepEqual(paths[goos], paths[operatingSystems[0]]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeRule adds a single-path rule to rules, joining the paths of a rule
+// differing only in them
+func mergeRule(rules []Rule, r Rule) []Rule {
+	for i, other := range rules {
+		paths := other.Paths
+		other.Paths = r.Paths
+		if reflect.DeepEqual(other, r) {
+			if !contains(paths, r.Paths[0]) {
+				rules[i].Paths = append(paths, r.Paths[0])
+			}
+			return rules
+		}
+	}
+	return append(rules, r)
+}
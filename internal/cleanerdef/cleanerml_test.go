@@ -0,0 +1,285 @@
+package cleanerdef
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  xmlAction
+		want    Rule
+		wantErr string
+	}{
+		{
+			name:   "file",
+			action: xmlAction{Command: "delete", Search: "file", Path: "~/.app/crash.txt"},
+			want:   Rule{Action: Delete, Paths: []string{"~/.app/crash.txt"}},
+		},
+		{
+			name:   "default search",
+			action: xmlAction{Command: "truncate", Path: " ~/.app/app.log "},
+			want:   Rule{Action: Truncate, Paths: []string{"~/.app/app.log"}},
+		},
+		{
+			name:   "glob",
+			action: xmlAction{Command: "delete", Search: "glob", Path: "~/.app/*.log"},
+			want:   Rule{Action: Delete, Paths: []string{"~/.app/*.log"}},
+		},
+		{
+			name:   "walk.all keeps the directory",
+			action: xmlAction{Command: "delete", Search: "walk.all", Path: "~/.app/cache/"},
+			want:   Rule{Action: Delete, Paths: []string{"~/.app/cache/*"}},
+		},
+		{
+			name:   "walk.top removes the directory",
+			action: xmlAction{Command: "delete", Search: "walk.top", Path: "~/.app/cache/"},
+			want:   Rule{Action: Delete, Paths: []string{"~/.app/cache"}},
+		},
+		{
+			name:   "walk.files",
+			action: xmlAction{Command: "delete", Search: "walk.files", Path: "~/.app/cache"},
+			want:   Rule{Action: Delete, Paths: []string{"~/.app/cache"}, Recurse: true},
+		},
+		{
+			name:   "truncate walk.all",
+			action: xmlAction{Command: "truncate", Search: "walk.all", Path: "~/.app/logs"},
+			want:   Rule{Action: Truncate, Paths: []string{"~/.app/logs"}, Recurse: true},
+		},
+		{
+			name:   "truncate walk.top",
+			action: xmlAction{Command: "truncate", Search: "walk.top", Path: "~/.app/logs"},
+			want:   Rule{Action: Truncate, Paths: []string{"~/.app/logs"}, Recurse: true},
+		},
+		{
+			name:   "windows path",
+			action: xmlAction{Command: "delete", Search: "walk.all", Path: `%LocalAppData%\App\Cache`},
+			want:   Rule{Action: Delete, Paths: []string{"${LOCALAPPDATA}/App/Cache/*"}},
+		},
+		{
+			name:   "json",
+			action: xmlAction{Command: "json", Path: "$$profile$$/state.json", Address: "/recent/files/"},
+			want:   Rule{Action: JSONKeyDelete, Paths: []string{"$$profile$$/state.json"}, Keys: []string{"recent.files"}},
+		},
+		{
+			name:   "sqlite.vacuum",
+			action: xmlAction{Command: "sqlite.vacuum", Search: "glob", Path: "~/.app/*.sqlite"},
+			want:   Rule{Action: SQLiteVacuum, Paths: []string{"~/.app/*.sqlite"}},
+		},
+		{
+			name:    "dotted json address",
+			action:  xmlAction{Command: "json", Path: "~/.app/state.json", Address: "recent.files"},
+			wantErr: `json address "recent.files" is not supported`,
+		},
+		{
+			name:    "json without address",
+			action:  xmlAction{Command: "json", Path: "~/.app/state.json"},
+			wantErr: `json address "" is not supported`,
+		},
+		{
+			name:    "walk on a database",
+			action:  xmlAction{Command: "sqlite.vacuum", Search: "walk.all", Path: "~/.app"},
+			wantErr: `search "walk.all" is not supported for sqlite.vacuum`,
+		},
+		{
+			name:    "unknown search",
+			action:  xmlAction{Command: "delete", Search: "deep", Path: "~/.app"},
+			wantErr: `search "deep" is not supported`,
+		},
+		{
+			name:    "unknown command",
+			action:  xmlAction{Command: "winreg", Path: `HKCU\Software\App`},
+			wantErr: `command "winreg" is not supported`,
+		},
+		{
+			name:    "no path",
+			action:  xmlAction{Command: "delete", Path: " "},
+			wantErr: "delete has no path",
+		},
+		{
+			name:    "regex filter",
+			action:  xmlAction{Command: "delete", Search: "walk.files", Path: "~/.app", Regex: `\.tmp$`},
+			wantErr: "regex filters are not supported",
+		},
+		{
+			name:    "type filter",
+			action:  xmlAction{Command: "delete", Search: "walk.all", Path: "~/.app", Type: "d"},
+			wantErr: "type filters are not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertAction(tt.action)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("convertAction() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertAction() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToSlashVars(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`%AppData%\x`, "${APPDATA}/x"},
+		{`%USERPROFILE%\AppData\%unknown%`, "${USERPROFILE}/AppData/${unknown}"},
+		{"$home/.app", "${HOME}/.app"},
+		{"$XDG_CONFIG_HOME/app", "${XDG_CONFIG_HOME}/app"},
+		{"${xdg_cache_home}/app", "${XDG_CACHE_HOME}/app"},
+		{"$$profile$$/cache", "$$profile$$/cache"},
+		{"$$profile$$/$$sub$$", "$$profile$$/$$sub$$"},
+		{"$$open/cache", "$$open/cache"},
+		{"~/price$/a", "~/price$/a"},
+		{"~/100%", "~/100%"},
+		{`~/a\b`, "~/a/b"},
+	}
+	for _, tt := range tests {
+		if got := toSlashVars(tt.path); got != tt.want {
+			t.Errorf("toSlashVars(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandCleanerMLVars(t *testing.T) {
+	vars := map[string][]xmlValue{
+		"profile": {
+			{OS: "linux", Value: "~/.mozilla/firefox/*"},
+			{OS: "darwin", Value: "~/Library/Application Support/Firefox/Profiles/*"},
+			{OS: "windows", Value: `%AppData%\Mozilla\Firefox\Profiles\*`},
+		},
+		"base": {
+			{Value: " $$profile$$ "},
+			{OS: "unix", Value: "~/.cache/mozilla"},
+		},
+		"linuxonly": {{OS: "linux", Value: "~/.local/share/app"}},
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		goos    string
+		want    []string
+		wantErr string
+	}{
+		{"no variable", "~/.app/cache", "linux", []string{"~/.app/cache"}, ""},
+		{"linux", "$$profile$$/cache2", "linux", []string{"~/.mozilla/firefox/*/cache2"}, ""},
+		{"windows", "$$profile$$/cache2", "windows", []string{"${APPDATA}/Mozilla/Firefox/Profiles/*/cache2"}, ""},
+		{"nested and unix", "$$base$$/x", "darwin", []string{"~/Library/Application Support/Firefox/Profiles/*/x", "~/.cache/mozilla/x"}, ""},
+		{"nested on windows", "$$base$$/x", "windows", []string{"${APPDATA}/Mozilla/Firefox/Profiles/*/x"}, ""},
+		{"no value for the system", "$$linuxonly$$/x", "windows", []string{}, ""},
+		{"unknown", "$$nope$$/x", "linux", nil, "unknown variable $$nope$$"},
+		{"unterminated", "$$profile/x", "linux", nil, `unterminated variable in "$$profile/x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandCleanerMLVars(tt.path, vars, tt.goos)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expandCleanerMLVars() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandCleanerMLVars(%q, %s) = %q, want %q", tt.path, tt.goos, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportCleanerML(t *testing.T) {
+	setHome(t)
+
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<cleaner id="app">
+  <label>App</label>
+  <description>Example application</description>
+  <running type="exe">app</running>
+  <running type="exe">app</running>
+  <running type="pathname">/usr/bin/app</running>
+  <var name="cache">
+    <value os="linux">~/.cache/app</value>
+    <value os="darwin">~/Library/Caches/app</value>
+    <value os="windows">%LocalAppData%\App\Cache</value>
+  </var>
+  <option id="cache">
+    <label>Cache</label>
+    <action command="delete" search="walk.all" path="$$cache$$"/>
+  </option>
+  <option id="logs">
+    <label>Logs</label>
+    <warning>Diagnostics are lost.</warning>
+    <action command="delete" search="walk.top" path="~/.app/logs"/>
+    <action command="delete" search="glob" path="~/.app/*.log"/>
+    <action command="delete" search="walk.files" path="~/.app/tmp" regex="\.tmp$"/>
+  </option>
+  <option id="recent">
+    <label>Recent</label>
+    <action command="json" path="~/.config/app/state.json" address="recent/files" os="linux"/>
+    <action command="delete" path="~/*" os="unix"/>
+    <action command="winreg" path="HKCU\Software\App"/>
+  </option>
+</cleaner>`
+
+	def, err := ImportCleanerML("app.xml", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Definition{
+		Name:        "App",
+		Description: "Example application",
+		Processes:   []string{"app"},
+		Rules: []Rule{
+			{Description: "Logs", Paths: []string{"~/.app/logs", "~/.app/*.log"}, Action: Delete, Risk: "high"},
+		},
+		OS: map[string][]Rule{
+			"linux": {
+				{Description: "Cache", Paths: []string{"~/.cache/app/*"}, Action: Delete},
+				{Description: "Recent", Paths: []string{"~/.config/app/state.json"}, Action: JSONKeyDelete, Keys: []string{"recent.files"}},
+			},
+			"darwin":  {{Description: "Cache", Paths: []string{"~/Library/Caches/app/*"}, Action: Delete}},
+			"windows": {{Description: "Cache", Paths: []string{"${LOCALAPPDATA}/App/Cache/*"}, Action: Delete}},
+		},
+		File: "app.xml",
+		Warnings: []string{
+			`running "/usr/bin/app": type "pathname" is not supported`,
+			`option "logs": action 3: regex filters are not supported`,
+			// once for each system unix covers
+			`option "recent": action 2: paths[0]: "~/*" would match directly in the home or root directory; name a subdirectory`,
+			`option "recent": action 2: paths[0]: "~/*" would match directly in the home or root directory; name a subdirectory`,
+			`option "recent": action 3: command "winreg" is not supported`,
+		},
+	}
+	if !reflect.DeepEqual(def, want) {
+		t.Errorf("ImportCleanerML() =\n%+v\nwant\n%+v", def, want)
+	}
+	if errs := def.Validate(); len(errs) > 0 {
+		t.Errorf("imported definition does not validate: %v", errs)
+	}
+
+	def, err = ImportCleanerML("bsd.xml", []byte(`<cleaner id="bsd" os="freebsd"><option id="a"/></cleaner>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`cleaner: os "freebsd" is not supported`}; def.Name != "bsd" || !reflect.DeepEqual(def.Warnings, want) {
+		t.Errorf("ImportCleanerML(freebsd) = %q with warnings %q, want bsd with %q", def.Name, def.Warnings, want)
+	}
+
+	if _, err := ImportCleanerML("bad.xml", []byte("<cleaner>")); err == nil {
+		t.Error("ImportCleanerML() of malformed XML succeeded, want an error")
+	}
+}
@@ -0,0 +1,114 @@
+package cleanerdef
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/platform"
+)
+
+// ErrUnset is returned by Expand for variables that are valid but not set
+// here, such as %APPDATA% outside Windows
+var ErrUnset = errors.New("variable is not set")
+
+// variables resolves the variables paths may use
+var variables = map[string]func() (string, error){
+	"HOME":            platform.GetHomeDir,
+	"XDG_CONFIG_HOME": platform.GetConfigHomePath,
+	"XDG_DATA_HOME":   platform.GetDataHomePath,
+	"XDG_STATE_HOME":  platform.GetStateHomePath,
+	"XDG_CACHE_HOME": func() (string, error) {
+		if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+			return xdg, nil
+		}
+		return platform.ExpandPath("~/.cache")
+	},
+	"TMPDIR":       func() (string, error) { return os.TempDir(), nil },
+	"APPDATA":      envVariable("APPDATA"),
+	"LOCALAPPDATA": envVariable("LOCALAPPDATA"),
+	"USERPROFILE":  envVariable("USERPROFILE"),
+}
+
+// envVariable resolves a variable that only exists on some systems
+func envVariable(name string) func() (string, error) {
+	return func() (string, error) {
+		if value := os.Getenv(name); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("$%s: %w", name, ErrUnset)
+	}
+}
+
+// variableNames lists the supported variables for error messages
+func variableNames() string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, "$"+name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Expand replaces a leading ~ and $VAR or ${VAR} variables in a path
+// pattern and converts slashes to the platform's separator
+func Expand(pattern string) (string, error) {
+	var err error
+	expanded := os.Expand(pattern, func(name string) string {
+		resolve, ok := variables[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("unknown variable $%s (want %s)", name, variableNames())
+			}
+			return ""
+		}
+		value, verr := resolve()
+		if verr != nil && err == nil {
+			err = verr
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if expanded, err = platform.ExpandPath(expanded); err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(expanded), nil
+}
+
+// Base returns the directory of an expanded pattern above its first glob,
+// which every match is inside; a pattern without globs is returned as is
+func Base(pattern string) string {
+	sep := string(filepath.Separator)
+	parts := strings.Split(pattern, sep)
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			base := strings.Join(parts[:i], sep)
+			if base == "" {
+				return sep
+			}
+			return base
+		}
+	}
+	return pattern
+}
+
+// TooBroad reports whether a path is the root or home directory, or above
+// the home directory, where no rule may act
+func TooBroad(path string) bool {
+	path = filepath.Clean(path)
+	if path == "." || path == filepath.Dir(path) {
+		return true
+	}
+	home, err := platform.GetHomeDir()
+	if err != nil {
+		return false
+	}
+	home = filepath.Clean(home)
+	return path == home || strings.HasPrefix(home, path+string(filepath.Separator))
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mat/gowipeme/internal/backup"
+	"github.com/mat/gowipeme/internal/wiper"
+)
+
+// backupReport is the JSON representation of a backup
+type backupReport struct {
+	ID        string   `json:"id"`
+	Timestamp string   `json:"timestamp"`
+	Items     []string `json:"items"`
+	Size      int64    `json:"size"`
+}
+
+func newBackupReport(info backup.BackupInfo) backupReport {
+	return backupReport{
+		ID:        info.ID,
+		Timestamp: info.Timestamp.Format(time.RFC3339),
+		Items:     info.Items,
+		Size:      info.Size,
+	}
+}
+
+const backupUsage = `Usage:
+  gowipeme backup create [--json]
+  gowipeme backup list [--json]
+  gowipeme backup preview [--json]
+  gowipeme backup restore <id> [--yes] [--json]
+  gowipeme backup delete <id> [--yes] [--json]
+`
+
+func (c *cli) runBackup(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(c.errOut, backupUsage)
+		return ExitUsage
+	}
+
+	sub, rest := args[0], args[1:]
+	fs := c.newFlagSet("backup " + sub)
+	asJSON := fs.Bool("json", false, "print JSON output")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.BoolVar(yes, "y", false, "shorthand for --yes")
+	positional, code := parseFlags(fs, rest)
+	if code >= 0 {
+		return code
+	}
+
+	// Subcommands that operate on a single backup need its ID
+	needsID := sub == "restore" || sub == "delete"
+	if (needsID && len(positional) != 1) || (!needsID && len(positional) != 0) {
+		fmt.Fprint(c.errOut, backupUsage)
+		return ExitUsage
+	}
+
+	switch sub {
+	case "create", "list", "preview", "restore", "delete":
+	default:
+		fmt.Fprintf(c.errOut, "Error: unknown backup command %q\n\n", sub)
+		fmt.Fprint(c.errOut, backupUsage)
+		return ExitUsage
+	}
+
+	bm, err := backup.NewBackupManager()
+	if err != nil {
+		return c.fail(*asJSON, fmt.Errorf("backup manager not available: %w", err))
+	}
+
+	switch sub {
+	case "create":
+		return c.backupCreate(bm, *asJSON)
+	case "list":
+		return c.backupList(bm, *asJSON)
+	case "preview":
+		return c.backupPreview(bm, *asJSON)
+	case "restore":
+		return c.backupRestore(bm, positional[0], *yes, *asJSON)
+	default:
+		return c.backupDelete(bm, positional[0], *yes, *asJSON)
+	}
+}
+
+func (c *cli) backupCreate(bm *backup.BackupManager, asJSON bool) int {
+	info, err := bm.CreateBackup()
+	if err != nil {
+		return c.fail(asJSON, err)
+	}
+
+	if asJSON {
+		if err := c.printJSON(newBackupReport(*info)); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	fmt.Fprintf(c.out, "✓ Backup created: %s\n", info.ID)
+	fmt.Fprintf(c.out, "✓ Items: %d\n", len(info.Items))
+	fmt.Fprintf(c.out, "✓ Size: %s\n", wiper.FormatBytes(info.Size))
+	return ExitOK
+}
+
+func (c *cli) backupList(bm *backup.BackupManager, asJSON bool) int {
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return c.fail(asJSON, err)
+	}
+
+	if asJSON {
+		reports := make([]backupReport, 0, len(backups))
+		for _, b := range backups {
+			reports = append(reports, newBackupReport(b))
+		}
+		if err := c.printJSON(reports); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	if len(backups) == 0 {
+		fmt.Fprintln(c.out, "No backups found.")
+		return ExitOK
+	}
+	for _, b := range backups {
+		fmt.Fprintf(c.out, "%s  %s  %s  %d items\n",
+			b.ID, b.Timestamp.Local().Format("2006-01-02 15:04"), wiper.FormatBytes(b.Size), len(b.Items))
+	}
+	return ExitOK
+}
+
+func (c *cli) backupPreview(bm *backup.BackupManager, asJSON bool) int {
+	items, err := bm.PreviewBackup()
+	if err != nil {
+		return c.fail(asJSON, err)
+	}
+
+	if asJSON {
+		if items == nil {
+			items = []string{}
+		}
+		if err := c.printJSON(map[string][]string{"items": items}); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	if len(items) == 0 {
+		fmt.Fprintln(c.out, "Nothing found to backup.")
+		return ExitOK
+	}
+	for _, name := range items {
+		fmt.Fprintf(c.out, "• %s\n", name)
+	}
+	return ExitOK
+}
+
+func (c *cli) backupRestore(bm *backup.BackupManager, id string, yes, asJSON bool) int {
+	if _, err := bm.GetBackup(id); err != nil {
+		return c.fail(asJSON, err)
+	}
+
+	if !yes && !c.confirm(fmt.Sprintf("Restore backup %s? This will overwrite existing files.", id)) {
+		fmt.Fprintln(c.errOut, "Aborted.")
+		return ExitAborted
+	}
+
+	if err := bm.RestoreBackup(id); err != nil {
+		return c.fail(asJSON, err)
+	}
+
+	if asJSON {
+		_ = c.printJSON(map[string]string{"restored": id})
+	} else {
+		fmt.Fprintf(c.out, "✓ Restored backup: %s\n", id)
+	}
+	return ExitOK
+}
+
+func (c *cli) backupDelete(bm *backup.BackupManager, id string, yes, asJSON bool) int {
+	if _, err := bm.GetBackup(id); err != nil {
+		return c.fail(asJSON, err)
+	}
+
+	if !yes && !c.confirm(fmt.Sprintf("Delete backup %s?", id)) {
+		fmt.Fprintln(c.errOut, "Aborted.")
+		return ExitAborted
+	}
+
+	if err := bm.DeleteBackup(id); err != nil {
+		return c.fail(asJSON, err)
+	}
+
+	if asJSON {
+		_ = c.printJSON(map[string]string{"deleted": id})
+	} else {
+		fmt.Fprintf(c.out, "✓ Deleted backup: %s\n", id)
+	}
+	return ExitOK
+}
@@ -0,0 +1,567 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mat/gowipeme/internal/cleaner"
+	"github.com/mat/gowipeme/internal/config"
+	"github.com/mat/gowipeme/internal/secrets"
+	"github.com/mat/gowipeme/internal/tui"
+	"github.com/mat/gowipeme/internal/wiper"
+)
+
+// cleanerReport is the JSON representation of a cleaner's dry-run
+type cleanerReport struct {
+	Name  string         `json:"name"`
+	Items []cleaner.Item `json:"items"`
+	Count int            `json:"count"`
+	Size  int64          `json:"size"`
+}
+
+// dryRunReport is the JSON output of the dry-run command
+type dryRunReport struct {
+	Cleaners   []cleanerReport `json:"cleaners"`
+	TotalItems int             `json:"totalItems"`
+	TotalSize  int64           `json:"totalSize"`
+	// Running lists, by cleaner, programs that are running and use its files
+	Running map[string][]string `json:"running,omitempty"`
+	// Parents lists, by cleaner, the running programs gowipeme was started
+	// from, such as the shell; they are also in Running
+	Parents map[string][]string `json:"parents,omitempty"`
+}
+
+// cleanResultReport is the JSON representation of a CleanResult
+type cleanResultReport struct {
+	Name         string `json:"name"`
+	ItemsCleaned int    `json:"itemsCleaned"`
+	BytesFreed   int64  `json:"bytesFreed"`
+	Error        string `json:"error,omitempty"`
+}
+
+// cleanReport is the JSON output of the clean command
+type cleanReport struct {
+	Results []cleanResultReport `json:"results"`
+	Failed  int                 `json:"failed"`
+	// Skipped lists cleaners left out because their programs were running
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// cleanerOptions configures the cleaners built by newCleanerManager
+type cleanerOptions struct {
+	Browser cleaner.BrowserOptions
+	Firefox cleaner.FirefoxOptions
+	Shell   cleaner.ShellOptions
+	Repl    cleaner.ReplOptions
+	Recent  cleaner.RecentOptions
+	Cache   cleaner.CacheOptions
+
+	Thumbnails cleaner.ThumbnailOptions
+	Trash      cleaner.TrashOptions
+}
+
+// cleanerFlags holds the flags shared by dry-run and clean that configure cleaners
+type cleanerFlags struct {
+	inPlace   *bool
+	olderThan *int
+	since     *string
+	until     *string
+
+	removeDomains *string
+	keepDomains   *string
+
+	firefoxDeleteFile *bool
+	profileData       *bool
+	passwords         *bool
+
+	keepLast *int
+	redact   *string
+
+	recentDirs  *string
+	recentApps  *string
+	recentMimes *string
+	places      *bool
+
+	thumbnails    *string
+	thumbnailDirs *string
+
+	shred *string
+}
+
+// addCleanerFlags registers the cleaner configuration flags on fs
+func addCleanerFlags(fs *flag.FlagSet) *cleanerFlags {
+	return &cleanerFlags{
+		inPlace:   fs.Bool("in-place", false, "delete history rows instead of the database file (Chromium)"),
+		olderThan: fs.Int("older-than", 0, "only remove history older than this many days"),
+		since:     fs.String("since", "", "only remove history recorded on or after this date"),
+		until:     fs.String("until", "", "only remove history recorded before this date"),
+
+		removeDomains: fs.String("remove-domain", "", "comma-separated sites whose browser data is removed (wildcards allowed)"),
+		keepDomains:   fs.String("keep-domain", "", "comma-separated sites whose browser data is always kept (wildcards allowed)"),
+
+		firefoxDeleteFile: fs.Bool("firefox-delete-file", false, "delete places.sqlite, including all Firefox bookmarks"),
+		profileData:       fs.Bool("include-profile-data", false, "also offer browser cookies, autofill, sessions and other profile data"),
+		passwords:         fs.Bool("include-passwords", false, "also offer saved passwords (Chromium Login Data)"),
+
+		keepLast: fs.Int("keep-last", 0, "keep this many of the most recent shell and REPL history entries"),
+		redact:   fs.String("redact", "", "only clean shell history entries containing secrets: mask or remove"),
+
+		recentDirs:  fs.String("recent-dir", "", "comma-separated directories whose recent files entries are removed"),
+		recentApps:  fs.String("recent-app", "", "comma-separated applications whose recent files entries are removed (wildcards allowed)"),
+		recentMimes: fs.String("recent-mime", "", "comma-separated MIME types whose recent files entries are removed, e.g. image/*"),
+		places:      fs.Bool("include-places", false, "also offer KDE places and GTK file chooser bookmarks"),
+
+		thumbnails:    fs.String("thumbnails", "", "thumbnails to remove: missing (default), under or all"),
+		thumbnailDirs: fs.String("thumbnail-dir", "", "comma-separated directories whose thumbnails are removed"),
+
+		shred: fs.String("shred", "", "overwrite trashed files before deleting them: zeros, dod or gutmann"),
+	}
+}
+
+// options converts the flags into cleaner options
+func (f *cleanerFlags) options() (cleanerOptions, error) {
+	var opts cleanerOptions
+	opts.Browser.InPlace = *f.inPlace
+
+	if *f.olderThan < 0 {
+		return opts, fmt.Errorf("--older-than must not be negative")
+	}
+	if *f.olderThan > 0 && *f.until != "" {
+		return opts, fmt.Errorf("--older-than and --until cannot be combined")
+	}
+	if *f.olderThan > 0 {
+		opts.Browser.Range = cleaner.OlderThan(*f.olderThan)
+	}
+
+	var err error
+	if *f.since != "" {
+		if opts.Browser.Range.Since, err = parseDate(*f.since); err != nil {
+			return opts, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if *f.until != "" {
+		if opts.Browser.Range.Until, err = parseDate(*f.until); err != nil {
+			return opts, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	r := opts.Browser.Range
+	if !r.Since.IsZero() && !r.Until.IsZero() && !r.Since.Before(r.Until) {
+		return opts, fmt.Errorf("--since must be before --until")
+	}
+
+	domains := cleaner.DomainRules{
+		Remove: splitList(*f.removeDomains),
+		Keep:   splitList(*f.keepDomains),
+	}
+	opts.Browser.Domains = domains
+	opts.Browser.ProfileData = *f.profileData
+	opts.Browser.Passwords = *f.passwords
+
+	opts.Firefox.DeleteFile = *f.firefoxDeleteFile
+	opts.Firefox.Range = r
+	opts.Firefox.Domains = domains
+	opts.Firefox.ProfileData = *f.profileData
+	if opts.Firefox.DeleteFile && (!r.IsZero() || !domains.IsZero()) {
+		return opts, fmt.Errorf("--firefox-delete-file cannot be combined with a time range or domain rules")
+	}
+
+	if *f.keepLast < 0 {
+		return opts, fmt.Errorf("--keep-last must not be negative")
+	}
+	opts.Shell.KeepLast = *f.keepLast
+	opts.Shell.Range = r
+	opts.Repl.KeepLast = *f.keepLast
+	opts.Repl.Range = r
+
+	switch *f.redact {
+	case "", cleaner.RedactMask, cleaner.RedactRemove:
+	default:
+		return opts, fmt.Errorf("invalid --redact %q (want mask or remove)", *f.redact)
+	}
+	if *f.redact != "" && *f.keepLast > 0 {
+		return opts, fmt.Errorf("--redact and --keep-last cannot be combined")
+	}
+	if *f.redact != "" && !r.IsZero() {
+		// Redaction scans every entry; it does not honour a time range
+		return opts, fmt.Errorf("--redact cannot be combined with --older-than, --since or --until")
+	}
+	opts.Shell.Redact = *f.redact
+
+	opts.Recent.Range = r
+	opts.Recent.Dirs = splitList(*f.recentDirs)
+	opts.Recent.Apps = splitList(*f.recentApps)
+	opts.Recent.MimeTypes = splitList(*f.recentMimes)
+	opts.Recent.Places = *f.places
+
+	opts.Thumbnails.Mode = *f.thumbnails
+	opts.Thumbnails.Dirs = splitList(*f.thumbnailDirs)
+	if opts.Thumbnails.Mode == "" && len(opts.Thumbnails.Dirs) > 0 {
+		opts.Thumbnails.Mode = cleaner.ThumbnailsUnder
+	}
+	switch opts.Thumbnails.Mode {
+	case "", cleaner.ThumbnailsMissing, cleaner.ThumbnailsAll:
+		if len(opts.Thumbnails.Dirs) > 0 {
+			return opts, fmt.Errorf("--thumbnail-dir needs --thumbnails under")
+		}
+	case cleaner.ThumbnailsUnder:
+		if len(opts.Thumbnails.Dirs) == 0 {
+			return opts, fmt.Errorf("--thumbnails under needs --thumbnail-dir")
+		}
+	default:
+		return opts, fmt.Errorf("invalid --thumbnails %q (want missing, under or all)", opts.Thumbnails.Mode)
+	}
+
+	opts.Trash.Range = r
+	if *f.shred != "" {
+		if opts.Trash.Method, err = wiper.ParseMethod(*f.shred); err != nil {
+			return opts, fmt.Errorf("invalid --shred: %w", err)
+		}
+		opts.Trash.Shred = true
+	}
+
+	// Report configuration errors instead of silently using the built-in rules
+	cfg, err := config.Load()
+	if err != nil {
+		return opts, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if opts.Shell.SecretRules, err = secrets.RulesFromConfig(cfg.Secrets); err != nil {
+		return opts, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if opts.Cache, err = cleaner.CacheOptionsFromConfig(cfg.Cache); err != nil {
+		return opts, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return opts, nil
+}
+
+// parseDate parses a local date (YYYY-MM-DD) or an RFC 3339 timestamp
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// selectCleaners builds a manager limited to the comma-separated cleaner
+// names in only. An empty list selects every cleaner.
+func (c *cli) selectCleaners(only string, opts cleanerOptions) (*cleaner.CleanerManager, error) {
+	all := c.allCleaners(opts)
+	if len(splitList(only)) == 0 {
+		return all, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range splitList(only) {
+		wanted[strings.ToLower(name)] = true
+	}
+
+	cm := cleaner.NewCleanerManager()
+	for _, cl := range all.GetCleaners() {
+		key := strings.ToLower(cl.Name())
+		if wanted[key] {
+			cm.AddCleaner(cl)
+			delete(wanted, key)
+		}
+	}
+
+	if len(wanted) > 0 {
+		unknown := make([]string, 0, len(wanted))
+		for name := range wanted {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown cleaner(s): %s", strings.Join(unknown, ", "))
+	}
+
+	return cm, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// selectItems limits dry-run results to the items with the given IDs
+func selectItems(results map[string][]cleaner.Item, ids []string) map[string][]cleaner.Item {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	selected := make(map[string][]cleaner.Item)
+	for name, items := range results {
+		for _, item := range items {
+			if wanted[item.ID] {
+				selected[name] = append(selected[name], item)
+			}
+		}
+	}
+	return selected
+}
+
+// highRiskItems returns the items whose removal cannot be undone by the browser
+func highRiskItems(results map[string][]cleaner.Item) []cleaner.Item {
+	risky := make([]cleaner.Item, 0)
+	for _, items := range results {
+		for _, item := range items {
+			if item.Risk == cleaner.RiskHigh {
+				risky = append(risky, item)
+			}
+		}
+	}
+	sort.Slice(risky, func(i, j int) bool { return risky[i].ID < risky[j].ID })
+	return risky
+}
+
+// buildDryRunReport converts dry-run results into a sorted report
+func buildDryRunReport(results map[string][]cleaner.Item) dryRunReport {
+	report := dryRunReport{Cleaners: make([]cleanerReport, 0, len(results))}
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		items := results[name]
+		cr := cleanerReport{
+			Name:  name,
+			Items: items,
+			Count: len(items),
+		}
+		for _, item := range items {
+			cr.Size += item.Size
+		}
+		report.Cleaners = append(report.Cleaners, cr)
+		report.TotalItems += len(items)
+		report.TotalSize += cr.Size
+	}
+
+	return report
+}
+
+func (c *cli) runDryRun(args []string) int {
+	fs := c.newFlagSet("dry-run")
+	asJSON := fs.Bool("json", false, "print JSON output")
+	only := fs.String("only", "", "comma-separated list of cleaner names to include")
+	cf := addCleanerFlags(fs)
+	if _, code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	opts, err := cf.options()
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+		return ExitUsage
+	}
+
+	cm, err := c.selectCleaners(*only, opts)
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+		return ExitUsage
+	}
+
+	results, err := cm.DryRunAll()
+	if err != nil {
+		return c.fail(*asJSON, err)
+	}
+
+	running, parents := c.runningPrograms(cm)
+
+	if *asJSON {
+		report := buildDryRunReport(results)
+		if len(running) > 0 {
+			report.Running = running
+		}
+		if len(parents) > 0 {
+			report.Parents = parents
+		}
+		if err := c.printJSON(report); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	c.warnRunning(running, parents)
+
+	if len(results) == 0 {
+		fmt.Fprintln(c.out, "Nothing to clean.")
+		return ExitOK
+	}
+	fmt.Fprint(c.out, cleaner.Summary(results))
+	return ExitOK
+}
+
+func (c *cli) runClean(args []string) int {
+	fs := c.newFlagSet("clean")
+	asJSON := fs.Bool("json", false, "print JSON output")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.BoolVar(yes, "y", false, "shorthand for --yes")
+	yesHighRisk := fs.Bool("yes-high-risk", false, "do not ask again before deleting items that cannot be recreated")
+	only := fs.String("only", "", "comma-separated list of cleaner names to run")
+	idList := fs.String("ids", "", "comma-separated list of item IDs to clean (see dry-run --json)")
+	ifRunning := fs.String("if-running", runningAsk, "ask, skip, wait or continue when programs using the files are running")
+	jobs := fs.Int("jobs", cleaner.DefaultConcurrency, "number of cleaners to run at once")
+	cf := addCleanerFlags(fs)
+	if _, code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	if *jobs < 1 {
+		fmt.Fprintf(c.errOut, "Error: invalid --jobs %d (want at least 1)\n", *jobs)
+		return ExitUsage
+	}
+
+	switch *ifRunning {
+	case runningAsk, runningSkip, runningWait, runningContinue:
+	default:
+		fmt.Fprintf(c.errOut, "Error: invalid --if-running %q (want ask, skip, wait or continue)\n", *ifRunning)
+		return ExitUsage
+	}
+
+	ids := splitList(*idList)
+
+	opts, err := cf.options()
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+		return ExitUsage
+	}
+
+	cm, err := c.selectCleaners(*only, opts)
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+		return ExitUsage
+	}
+
+	cm, skipped, code := c.resolveRunning(cm, *ifRunning)
+	if code >= 0 {
+		return code
+	}
+	cm.SetConcurrency(*jobs)
+
+	ctx, stop := interruptible()
+	defer stop()
+
+	results, err := cm.DryRunAllStream(ctx, nil)
+	if ctx.Err() != nil {
+		fmt.Fprintln(c.errOut, "Interrupted.")
+		return ExitAborted
+	}
+	if err != nil {
+		return c.fail(*asJSON, err)
+	}
+
+	if len(ids) > 0 {
+		results = selectItems(results, ids)
+	}
+
+	if len(results) == 0 {
+		if *asJSON {
+			_ = c.printJSON(cleanReport{Results: []cleanResultReport{}, Skipped: skipped})
+		} else {
+			fmt.Fprintln(c.out, "Nothing to clean.")
+		}
+		if len(skipped) > 0 {
+			return ExitPartial
+		}
+		return ExitOK
+	}
+
+	if !*yes {
+		// Show the preview on stderr so stdout stays machine-readable
+		fmt.Fprint(c.errOut, cleaner.Summary(results))
+		if !c.confirm("This action cannot be undone. Continue?") {
+			fmt.Fprintln(c.errOut, "Aborted.")
+			return ExitAborted
+		}
+	}
+
+	// Items such as saved passwords or bookmarks need a second confirmation,
+	// which --yes does not give
+	if risky := highRiskItems(results); len(risky) > 0 && !*yesHighRisk {
+		fmt.Fprintln(c.errOut, "\nThe following items cannot be recreated:")
+		for _, item := range risky {
+			fmt.Fprintf(c.errOut, "  ! %s\n", item.Label)
+		}
+		if !c.interactive {
+			fmt.Fprintln(c.errOut, "Error: confirmation required; re-run with --yes-high-risk to delete them non-interactively")
+			return ExitAborted
+		}
+		if !c.confirm("Really delete them?") {
+			fmt.Fprintln(c.errOut, "Aborted.")
+			return ExitAborted
+		}
+	}
+
+	var cleanResults []cleaner.CleanResult
+	if len(ids) > 0 {
+		cleanResults = cm.CleanSelectedContext(ctx, ids, nil)
+	} else {
+		cleanResults = cm.CleanAllContext(ctx, nil)
+	}
+
+	report := cleanReport{Results: make([]cleanResultReport, 0, len(cleanResults)), Skipped: skipped}
+	for _, result := range cleanResults {
+		r := cleanResultReport{
+			Name:         result.CleanerName,
+			ItemsCleaned: result.ItemsCleaned,
+			BytesFreed:   result.BytesFreed,
+		}
+		if result.Error != nil {
+			r.Error = result.Error.Error()
+			report.Failed++
+		}
+		report.Results = append(report.Results, r)
+	}
+
+	if *asJSON {
+		if err := c.printJSON(report); err != nil {
+			return ExitError
+		}
+	} else {
+		for _, r := range report.Results {
+			if r.Error != "" {
+				fmt.Fprintf(c.out, "✗ %s: %s\n", r.Name, r.Error)
+			} else {
+				fmt.Fprintf(c.out, "✓ %s: cleaned %d items\n", r.Name, r.ItemsCleaned)
+			}
+		}
+		for _, name := range report.Skipped {
+			fmt.Fprintf(c.out, "- %s: skipped, still running\n", name)
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Fprintln(c.errOut, "Interrupted; the remaining cleaners were not run.")
+		return ExitAborted
+	}
+
+	// Skipped cleaners leave traces behind, like failed ones
+	if report.Failed > 0 || len(report.Skipped) > 0 {
+		return ExitPartial
+	}
+	return ExitOK
+}
+
+func (c *cli) runTUI(args []string) int {
+	fs := c.newFlagSet("tui")
+	if _, code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	if err := tui.Run(); err != nil {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
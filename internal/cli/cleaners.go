@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/cleaner"
+	"github.com/mat/gowipeme/internal/cleanerdef"
+)
+
+// customCleanerReport is the JSON representation of a defined cleaner
+type customCleanerReport struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	Description string `json:"description,omitempty"`
+	Rules       int    `json:"rules"`
+}
+
+// checkReport is the JSON result of cleaners check
+type checkReport struct {
+	Cleaners []customCleanerReport `json:"cleaners"`
+	Errors   []string              `json:"errors"`
+	// Warnings list what was left out of imported CleanerML files
+	Warnings []string `json:"warnings"`
+}
+
+const cleanersUsage = `Usage:
+  gowipeme cleaners list [--json]
+  gowipeme cleaners check [file...] [--json]
+
+Cleaner definitions are YAML, JSON or BleachBit CleanerML (.xml) files in
+~/.gowipeme/cleaners.d/. check validates the given files, or every
+definition in that directory.
+`
+
+func (c *cli) runCleaners(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(c.errOut, cleanersUsage)
+		return ExitUsage
+	}
+
+	sub, rest := args[0], args[1:]
+	fs := c.newFlagSet("cleaners " + sub)
+	asJSON := fs.Bool("json", false, "print JSON output")
+	files, code := parseFlags(fs, rest)
+	if code >= 0 {
+		return code
+	}
+
+	switch sub {
+	case "list":
+		if len(files) != 0 {
+			fmt.Fprint(c.errOut, cleanersUsage)
+			return ExitUsage
+		}
+		return c.cleanersList(*asJSON)
+	case "check":
+		return c.cleanersCheck(files, *asJSON)
+	default:
+		fmt.Fprintf(c.errOut, "Error: unknown cleaners command %q\n\n", sub)
+		fmt.Fprint(c.errOut, cleanersUsage)
+		return ExitUsage
+	}
+}
+
+// loadCustomCleaners loads the defined cleaners as they would be registered
+// next to the built-in ones, returning the errors of skipped definitions
+// apart from import warnings
+func loadCustomCleaners() ([]customCleanerReport, []string, []string) {
+	cm := newCleanerManager(cleanerOptions{})
+	errs := cm.AddCustomCleaners()
+
+	reports := make([]customCleanerReport, 0)
+	for _, cl := range cm.GetCleaners() {
+		if custom, ok := cl.(*cleaner.CustomCleaner); ok {
+			def := custom.Definition()
+			reports = append(reports, customCleanerReport{
+				Name:        def.Name,
+				File:        def.File,
+				Description: def.Description,
+				Rules:       custom.RuleCount(),
+			})
+		}
+	}
+
+	messages := make([]string, 0, len(errs))
+	warnings := make([]string, 0)
+	for _, err := range errs {
+		if !errors.Is(err, cleaner.ErrDefinitionSkipped) {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		messages = append(messages, strings.TrimPrefix(err.Error(), cleaner.ErrDefinitionSkipped.Error()+" "))
+	}
+	return reports, messages, warnings
+}
+
+func (c *cli) cleanersList(asJSON bool) int {
+	reports, errs, warnings := loadCustomCleaners()
+	for _, err := range errs {
+		fmt.Fprintf(c.errOut, "Warning: %v %s\n", cleaner.ErrDefinitionSkipped, err)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(c.errOut, "Warning: %s\n", warning)
+	}
+
+	if asJSON {
+		if err := c.printJSON(reports); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	if len(reports) == 0 {
+		dir, _ := cleanerdef.Dir()
+		fmt.Fprintf(c.out, "No cleaner definitions in %s\n", dir)
+		return ExitOK
+	}
+	for _, r := range reports {
+		fmt.Fprintf(c.out, "%s  (%s, %d rules)\n", r.Name, filepath.Base(r.File), r.Rules)
+		if r.Description != "" {
+			fmt.Fprintf(c.out, "  %s\n", r.Description)
+		}
+	}
+	return ExitOK
+}
+
+func (c *cli) cleanersCheck(files []string, asJSON bool) int {
+	report := checkReport{Cleaners: make([]customCleanerReport, 0), Errors: make([]string, 0), Warnings: make([]string, 0)}
+	if len(files) == 0 {
+		report.Cleaners, report.Errors, report.Warnings = loadCustomCleaners()
+	} else {
+		for _, file := range files {
+			def, errs := cleanerdef.LoadFile(file)
+			for _, err := range errs {
+				report.Errors = append(report.Errors, err.Error())
+			}
+			if def != nil {
+				for _, warning := range def.Warnings {
+					report.Warnings = append(report.Warnings, fmt.Sprintf("cleaner definition %s: %s", filepath.Base(file), warning))
+				}
+				custom := cleaner.NewCustomCleaner(def)
+				report.Cleaners = append(report.Cleaners, customCleanerReport{
+					Name:        def.Name,
+					File:        def.File,
+					Description: def.Description,
+					Rules:       custom.RuleCount(),
+				})
+			}
+		}
+	}
+
+	if asJSON {
+		if err := c.printJSON(report); err != nil {
+			return ExitError
+		}
+	} else {
+		for _, r := range report.Cleaners {
+			fmt.Fprintf(c.out, "✓ %s: %s, %d rules on this system\n", filepath.Base(r.File), r.Name, r.Rules)
+		}
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(c.out, "! %s\n", warning)
+		}
+		for _, err := range report.Errors {
+			fmt.Fprintf(c.out, "✗ %s\n", err)
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return ExitError
+	}
+	return ExitOK
+}
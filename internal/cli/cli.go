@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/mat/gowipeme/internal/cleaner"
+	"github.com/mat/gowipeme/internal/process"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK      = 0 // Command completed successfully
+	ExitError   = 1 // Command failed
+	ExitUsage   = 2 // Invalid command line
+	ExitPartial = 3 // Command completed but some items failed
+	ExitAborted = 4 // Confirmation was declined or not possible, or interrupted
+)
+
+const usage = `goWipeMe - Privacy Tool
+
+Usage:
+  gowipeme                     Start the interactive TUI (when run on a terminal)
+  gowipeme <command> [flags]
+
+Commands:
+  dry-run                      List everything that would be cleaned
+  clean                        Clean browser, shell, REPL, editor, cache, recent files and clipboard traces
+  wipe                         Securely wipe free space on a volume
+  backup create                Back up browser and shell history
+  backup list                  List available backups
+  backup preview               Show what would be backed up
+  backup restore <id>          Restore a backup
+  backup delete <id>           Delete a backup
+  cleaners list                List the cleaners defined in ~/.gowipeme/cleaners.d/
+  cleaners check [file...]     Validate cleaner definitions
+  tui                          Start the interactive TUI
+  help                         Show this help
+
+Common flags:
+  --json                       Print machine-readable JSON output
+  --yes, -y                    Do not ask for confirmation
+
+History flags (dry-run, clean):
+  --in-place                   Delete history rows instead of the database file (Chromium)
+  --older-than DAYS            Only remove history older than DAYS days (implies --in-place;
+                               also prunes bash, zsh and fish history instead of truncating it)
+  --since DATE                 Only remove history recorded on or after DATE (YYYY-MM-DD or RFC 3339)
+  --until DATE                 Only remove history recorded before DATE
+  --remove-domain LIST         Only remove data of these sites, e.g. "intranet.example,*.corp.example"
+  --keep-domain LIST           Never remove data of these sites; alone, removes every other site
+  --firefox-delete-file        Delete Firefox places.sqlite, including bookmarks (default keeps bookmarks)
+  --if-running MODE            clean only: ask, skip, wait or continue when a browser or shell
+                               using the files is running (default ask; skip without a terminal)
+  --jobs N                     clean only: run up to N cleaners at once (default 4); cleaners
+                               sharing files never run together
+  --include-profile-data       Also offer browser cookies, autofill, sessions, caches and other
+                               profile data besides history (Chromium, Firefox)
+  --include-passwords          Also offer saved passwords (Chromium Login Data); asks twice before deleting
+  --keep-last N                Keep the N most recent shell commands and REPL/database client
+                               history lines instead of truncating
+  --redact MODE                Only clean shell history entries containing secrets (API keys, tokens,
+                               passwords): mask replaces the secret, remove drops the command
+  --recent-dir LIST            Only remove recently-used.xbel entries under these directories (Linux)
+  --recent-app LIST            Only remove recently-used.xbel entries registered by these applications
+  --recent-mime LIST           Only remove recently-used.xbel entries of these MIME types, e.g. "image/*"
+  --include-places             Also offer KDE places and GTK file chooser bookmarks (Linux); asks twice
+  --yes-high-risk              clean only: do not ask again before deleting saved passwords, places
+                               and other items that cannot be recreated (--yes alone does not)
+  --thumbnails MODE            Thumbnails to remove: missing (of deleted files, default), under or all
+  --thumbnail-dir LIST         Remove thumbnails of files under these directories (implies under)
+  --shred METHOD               Overwrite trashed files before deleting them (zeros, dod or gutmann)
+
+Exit codes:
+  0  success
+  1  error
+  2  invalid usage
+  3  completed with some failures
+  4  aborted (confirmation declined or required)
+`
+
+// cli holds the input and output streams used by commands
+type cli struct {
+	in     *bufio.Reader
+	out    io.Writer
+	errOut io.Writer
+
+	// interactive reports whether confirmation prompts can be shown
+	interactive bool
+
+	// procs lists running programs that may hold files being cleaned
+	procs *process.Scanner
+}
+
+// Run executes the command line interface with the given arguments
+// (excluding the program name) and returns the process exit code.
+func Run(args []string) int {
+	c := &cli{
+		in:          bufio.NewReader(os.Stdin),
+		out:         os.Stdout,
+		errOut:      os.Stderr,
+		interactive: isTerminal(os.Stdin),
+		procs:       process.NewScanner(),
+	}
+	return c.run(args)
+}
+
+// IsInteractive reports whether both stdin and stdout are attached to a terminal.
+func IsInteractive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// Usage returns the command line help text
+func Usage() string {
+	return usage
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(c.errOut, usage)
+		return ExitUsage
+	}
+
+	command, rest := args[0], args[1:]
+	switch command {
+	case "dry-run", "dryrun":
+		return c.runDryRun(rest)
+	case "clean":
+		return c.runClean(rest)
+	case "wipe":
+		return c.runWipe(rest)
+	case "backup":
+		return c.runBackup(rest)
+	case "cleaners":
+		return c.runCleaners(rest)
+	case "tui":
+		return c.runTUI(rest)
+	case "help", "-h", "--help":
+		fmt.Fprint(c.out, usage)
+		return ExitOK
+	default:
+		fmt.Fprintf(c.errOut, "Error: unknown command %q\n\n", command)
+		fmt.Fprint(c.errOut, usage)
+		return ExitUsage
+	}
+}
+
+// newFlagSet creates a flag set that reports errors to stderr
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	return fs
+}
+
+// parseFlags parses flags and converts errors into an exit code. Flags may
+// appear before or after positional arguments, which are returned in order.
+// The exit code is -1 when parsing succeeded.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, int) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, ExitOK
+			}
+			return nil, ExitUsage
+		}
+		if fs.NArg() == 0 {
+			return positional, -1
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// confirm asks the user a yes/no question. Without a terminal the question
+// cannot be asked and the answer is no.
+func (c *cli) confirm(question string) bool {
+	if !c.interactive {
+		fmt.Fprintln(c.errOut, "Error: confirmation required; re-run with --yes to proceed non-interactively")
+		return false
+	}
+
+	fmt.Fprintf(c.errOut, "%s [y/N]: ", question)
+	answer, err := c.in.ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// printJSON writes v as indented JSON to stdout
+func (c *cli) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// fail reports an error in the requested output format and returns ExitError
+func (c *cli) fail(asJSON bool, err error) int {
+	if asJSON {
+		_ = c.printJSON(map[string]string{"error": err.Error()})
+	} else {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+	}
+	return ExitError
+}
+
+// interruptible returns a context cancelled by Ctrl+C or SIGTERM, so that
+// long operations stop and clean up instead of dying half way
+func interruptible() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// newCleanerManager creates a cleaner manager with all default cleaners
+func newCleanerManager(opts cleanerOptions) *cleaner.CleanerManager {
+	cm := cleaner.NewCleanerManager()
+	cm.AddCleaner(cleaner.NewBrowserCleanerWithOptions(opts.Browser))
+	cm.AddCleaner(cleaner.NewFirefoxCleanerWithOptions(opts.Firefox))
+	cm.AddCleaner(cleaner.NewShellCleanerWithOptions(opts.Shell))
+	cm.AddCleaner(cleaner.NewReplCleanerWithOptions(opts.Repl))
+	cm.AddCleaner(cleaner.NewEditorCleaner())
+	cm.AddCleaner(cleaner.NewCacheCleanerWithOptions(opts.Cache))
+	cm.AddCleaner(cleaner.NewThumbnailCleanerWithOptions(opts.Thumbnails))
+	cm.AddCleaner(cleaner.NewTrashCleanerWithOptions(opts.Trash))
+	cm.AddCleaner(cleaner.NewRecentFilesCleanerWithOptions(opts.Recent))
+	cm.AddCleaner(cleaner.NewClipboardCleaner())
+	return cm
+}
+
+// allCleaners creates a cleaner manager with the default cleaners and those
+// defined in ~/.gowipeme/cleaners.d/, warning about skipped definitions
+func (c *cli) allCleaners(opts cleanerOptions) *cleaner.CleanerManager {
+	cm := newCleanerManager(opts)
+	for _, err := range cm.AddCustomCleaners() {
+		fmt.Fprintf(c.errOut, "Warning: %v\n", err)
+	}
+	return cm
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mat/gowipeme/internal/cleaner"
+	"github.com/mat/gowipeme/internal/process"
+)
+
+// What clean does about programs running while their files are cleaned
+const (
+	runningAsk      = "ask"      // prompt on a terminal, otherwise skip
+	runningSkip     = "skip"     // leave the affected cleaners out
+	runningWait     = "wait"     // wait for the programs to exit
+	runningContinue = "continue" // clean anyway
+)
+
+// How often and how long --if-running=wait polls for programs to exit
+const (
+	runningPollInterval = 2 * time.Second
+	runningWaitTimeout  = 10 * time.Minute
+)
+
+// runningPrograms returns the running programs per cleaner and those among
+// them gowipeme runs under, or nil when processes cannot be listed on this
+// system
+func (c *cli) runningPrograms(cm *cleaner.CleanerManager) (map[string][]string, map[string][]string) {
+	running, err := cm.RunningPrograms(c.procs)
+	if err != nil {
+		if !errors.Is(err, process.ErrNoProcfs) {
+			fmt.Fprintf(c.errOut, "Warning: could not list running programs: %v\n", err)
+		}
+		return nil, nil
+	}
+	parents, _ := cm.ParentPrograms(c.procs)
+	return running, parents
+}
+
+// warnRunning prints a warning for each cleaner whose programs are running
+func (c *cli) warnRunning(running, parents map[string][]string) {
+	for _, name := range sortedKeys(running) {
+		fmt.Fprintf(c.errOut, "Warning: %s is running; cleaning %s may fail or be undone when it exits\n",
+			strings.Join(running[name], ", "), name)
+	}
+	c.adviseParents(parents)
+}
+
+// adviseParents tells how to keep the shells gowipeme runs under from
+// writing their history back
+func (c *cli) adviseParents(parents map[string][]string) {
+	for _, name := range sortedKeys(parents) {
+		fmt.Fprintf(c.errOut, "Warning: gowipeme runs in %s; %s\n", strings.Join(parents[name], ", "), cleaner.ParentAdvice)
+	}
+}
+
+// resolveRunning handles cleaners whose programs are running according to
+// mode. It returns the manager to clean with and the names of skipped
+// cleaners, or an exit code >= 0 when cleaning should stop.
+func (c *cli) resolveRunning(cm *cleaner.CleanerManager, mode string) (*cleaner.CleanerManager, []string, int) {
+	running, parents := c.runningPrograms(cm)
+	if len(running) == 0 {
+		return cm, nil, -1
+	}
+	if mode == runningContinue {
+		c.adviseParents(parents)
+		return cm, nil, -1
+	}
+
+	c.warnRunning(running, parents)
+
+	if mode == runningAsk {
+		mode = runningSkip
+		if c.interactive {
+			mode = c.askRunning()
+		}
+	}
+
+	switch mode {
+	case runningSkip:
+		skipped := sortedKeys(running)
+		fmt.Fprintf(c.errOut, "Skipping: %s\n", strings.Join(skipped, ", "))
+		return withoutCleaners(cm, running), skipped, -1
+
+	case runningWait:
+		// The shells gowipeme runs under cannot exit first; they were
+		// advised about above
+		fmt.Fprintln(c.errOut, "Waiting for running programs to exit...")
+		ctx, cancel := context.WithTimeout(context.Background(), runningWaitTimeout)
+		defer cancel()
+		if err := cm.WaitForPrograms(ctx, c.procs, runningPollInterval); errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintln(c.errOut, "Error: timed out waiting for programs to exit")
+			return nil, nil, ExitAborted
+		} else if err != nil {
+			fmt.Fprintf(c.errOut, "Error: %v\n", err)
+			return nil, nil, ExitAborted
+		}
+		return cm, nil, -1
+
+	case runningContinue:
+		return cm, nil, -1
+
+	default:
+		fmt.Fprintln(c.errOut, "Aborted.")
+		return nil, nil, ExitAborted
+	}
+}
+
+// askRunning asks what to do about running programs
+func (c *cli) askRunning() string {
+	fmt.Fprint(c.errOut, "[s]kip affected cleaners, [w]ait for the programs to exit, [c]ontinue anyway, [a]bort? ")
+	answer, err := c.in.ReadString('\n')
+	if err != nil && answer == "" {
+		return "abort"
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "s", "skip":
+		return runningSkip
+	case "w", "wait":
+		return runningWait
+	case "c", "continue":
+		return runningContinue
+	default:
+		return "abort"
+	}
+}
+
+// withoutCleaners returns a manager without the named cleaners
+func withoutCleaners(cm *cleaner.CleanerManager, skip map[string][]string) *cleaner.CleanerManager {
+	filtered := cleaner.NewCleanerManager()
+	for _, c := range cm.GetCleaners() {
+		if _, ok := skip[c.Name()]; !ok {
+			filtered.AddCleaner(c)
+		}
+	}
+	return filtered
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mat/gowipeme/internal/wiper"
+)
+
+// wipeReport is the JSON output of the wipe command
+type wipeReport struct {
+	Volume       string  `json:"volume"`
+	Method       string  `json:"method"`
+	BytesWritten int64   `json:"bytesWritten"`
+	Seconds      float64 `json:"seconds"`
+	Error        string  `json:"error,omitempty"`
+}
+
+func (c *cli) runWipe(args []string) int {
+	fs := c.newFlagSet("wipe")
+	asJSON := fs.Bool("json", false, "print JSON output")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.BoolVar(yes, "y", false, "shorthand for --yes")
+	methodName := fs.String("method", "zeros", "wipe method: zeros, dod or gutmann")
+	volume := fs.String("volume", "", "directory on the volume to wipe (default: home directory)")
+	if _, code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	method, err := wiper.ParseMethod(*methodName)
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+		return ExitUsage
+	}
+
+	volumePath := *volume
+	if volumePath == "" {
+		volumePath, err = wiper.GetHomeDir()
+		if err != nil {
+			return c.fail(*asJSON, err)
+		}
+	}
+
+	w, err := wiper.NewWiper(volumePath, method)
+	if err != nil {
+		return c.fail(*asJSON, err)
+	}
+
+	totalSpace, freeSpace, err := w.GetVolumeInfo()
+	if err != nil {
+		return c.fail(*asJSON, err)
+	}
+
+	if !*yes {
+		fmt.Fprintf(c.errOut, "Volume: %s\n", w.VolumePath)
+		fmt.Fprintf(c.errOut, "Total Space: %s\n", wiper.FormatBytes(totalSpace))
+		fmt.Fprintf(c.errOut, "Free Space: %s\n", wiper.FormatBytes(freeSpace))
+		fmt.Fprintf(c.errOut, "Method: %s\n\n", method.String())
+		if !c.confirm("This will fill all free space on the volume and may take a long time. Continue?") {
+			fmt.Fprintln(c.errOut, "Aborted.")
+			return ExitAborted
+		}
+	}
+
+	// Ctrl+C stops the wipe and removes the wipe files
+	ctx, stop := interruptible()
+	defer stop()
+
+	progressChan := make(chan wiper.Progress)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- w.WipeFreeSpaceContext(ctx, progressChan)
+		close(progressChan)
+	}()
+
+	var last wiper.Progress
+	var lastPrint time.Time
+	for prog := range progressChan {
+		last = prog
+		if *asJSON || time.Since(lastPrint) < 500*time.Millisecond {
+			continue
+		}
+		lastPrint = time.Now()
+		fmt.Fprintf(c.errOut, "\r%s: %5.1f%% (%s / %s)   ",
+			prog.CurrentMethod, prog.Percentage(),
+			wiper.FormatBytes(prog.BytesWritten), wiper.FormatBytes(prog.TotalBytes))
+	}
+	wipeErr := <-errChan
+	if !*asJSON && !lastPrint.IsZero() {
+		fmt.Fprintln(c.errOut)
+	}
+
+	report := wipeReport{
+		Volume:       w.VolumePath,
+		Method:       method.String(),
+		BytesWritten: last.BytesWritten,
+		Seconds:      last.TimeElapsed.Seconds(),
+	}
+	if wipeErr != nil {
+		report.Error = wipeErr.Error()
+	}
+
+	if *asJSON {
+		if err := c.printJSON(report); err != nil {
+			return ExitError
+		}
+	} else if wipeErr == nil {
+		fmt.Fprintf(c.out, "✓ Wiped %s using %s in %s\n",
+			wiper.FormatBytes(report.BytesWritten), report.Method, last.TimeElapsed.Round(time.Second))
+	} else if ctx.Err() != nil {
+		fmt.Fprintf(c.errOut, "Interrupted after %s; the wipe files were removed.\n", wiper.FormatBytes(report.BytesWritten))
+	} else {
+		fmt.Fprintf(c.errOut, "Error: %v\n", wipeErr)
+	}
+
+	if wipeErr != nil && ctx.Err() != nil {
+		return ExitAborted
+	}
+	if wipeErr != nil {
+		return ExitError
+	}
+	return ExitOK
+}
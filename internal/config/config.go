@@ -0,0 +1,87 @@
+// Package config loads the optional user configuration from
+// ~/.gowipeme/config.json.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mat/gowipeme/internal/platform"
+)
+
+// Config is the user configuration. Every section is optional.
+type Config struct {
+	Secrets SecretsConfig `json:"secrets"`
+	Cache   CacheConfig   `json:"cache"`
+}
+
+// SecretsConfig extends the secret detection rules used on shell histories
+type SecretsConfig struct {
+	// Rules are added to the built-in rules
+	Rules []SecretRule `json:"rules"`
+	// Disable lists built-in rules to turn off by name
+	Disable []string `json:"disable"`
+}
+
+// SecretRule is a user-defined secret pattern. The first capture group is
+// the secret; without groups the whole match is.
+type SecretRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	// MinEntropy ignores matches whose secret has fewer bits of Shannon
+	// entropy per character, e.g. 3.5 for random tokens
+	MinEntropy float64 `json:"minEntropy"`
+}
+
+// CacheConfig adjusts which application cache entries are cleaned. Globs
+// match entry names in the cache directory, case-insensitively.
+type CacheConfig struct {
+	// Include lists entries to clean even if kept by default
+	Include []string `json:"include"`
+	// Exclude lists entries never to clean; it wins over Include
+	Exclude []string `json:"exclude"`
+	// OlderThanDays only cleans entries with nothing modified for this
+	// many days
+	OlderThanDays int `json:"olderThanDays"`
+	// MinSize only cleans entries of at least this size, e.g. "50MB"
+	MinSize string `json:"minSize"`
+}
+
+// Path returns the location of the configuration file
+func Path() (string, error) {
+	home, err := platform.GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gowipeme", "config.json"), nil
+}
+
+// Load reads the configuration file. A missing file yields an empty
+// configuration.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads the configuration from path
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
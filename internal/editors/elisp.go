@@ -0,0 +1,96 @@
+package editors
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// elispSetq matches the start of a top-level (setq VARIABLE ...) form
+var elispSetq = regexp.MustCompile(`^\(\s*setq\s+([^\s()"]+)\s+`)
+
+// errUnbalanced is returned for Lisp data with unbalanced parentheses or
+// unterminated strings
+var errUnbalanced = errors.New("elisp: unbalanced form")
+
+// ClearLispVariables sets the variables matched by match to nil in a file of
+// top-level (setq VARIABLE VALUE) forms, such as emacs' recentf or savehist
+// files. Other forms and comments are kept. It returns the new contents and
+// the number of variables cleared.
+func ClearLispVariables(data []byte, match func(name string) bool) ([]byte, int, error) {
+	var out bytes.Buffer
+	cleared := 0
+
+	for i := 0; i < len(data); {
+		switch data[i] {
+		case ';':
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 {
+				end = len(data) - i
+			}
+			out.Write(data[i : i+end])
+			i += end
+		case '(':
+			end, err := elispFormEnd(data, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			form := data[i:end]
+			if m := elispSetq.FindSubmatch(form); m != nil && match(string(m[1])) {
+				value := strings.TrimSpace(string(form[len(m[0]) : len(form)-1]))
+				if value != "nil" {
+					form = []byte("(setq " + string(m[1]) + " nil)")
+					cleared++
+				}
+			}
+			out.Write(form)
+			i = end
+		default:
+			out.WriteByte(data[i])
+			i++
+		}
+	}
+
+	return out.Bytes(), cleared, nil
+}
+
+// elispFormEnd returns the offset just past the form that starts with the
+// parenthesis at start, skipping strings, character literals and comments
+func elispFormEnd(data []byte, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		case '"':
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(data) {
+				return 0, errUnbalanced
+			}
+		case '?':
+			// Character literal such as ?( or ?\", unless part of a symbol
+			if !strings.ContainsRune(" \t\n('", rune(data[i-1])) {
+				continue
+			}
+			if i+1 < len(data) && data[i+1] == '\\' {
+				i++
+			}
+			i++
+		case ';':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		}
+	}
+	return 0, errUnbalanced
+}
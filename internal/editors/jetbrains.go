@@ -0,0 +1,104 @@
+package editors
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// jetbrainsRecentOptions are the options of RecentProjectsManager (and
+// RecentSolutionsManager in Rider) that list recently opened projects
+var jetbrainsRecentOptions = map[string]bool{
+	"additionalInfo":    true,
+	"recentPaths":       true,
+	"openPaths":         true,
+	"groups":            true,
+	"lastOpenedProject": true,
+}
+
+// span is a byte range of the input
+type span struct{ start, end int }
+
+// CleanRecentProjects removes the recently opened projects from a JetBrains
+// recentProjects.xml (or recentSolutions.xml). The matching <option>
+// elements are cut out of the original bytes, so the rest of the file,
+// including settings such as lastProjectLocation, is unchanged. It returns
+// the new contents and the number of projects removed.
+func CleanRecentProjects(data []byte) ([]byte, int, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	spans := make([]span, 0)
+	removed := 0
+	depth := 0
+	dropDepth := 0 // depth of the option being removed, 0 when none
+	var start int64
+
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if dropDepth == 0 && t.Name.Local == "option" && jetbrainsRecentOptions[attr(t, "name")] {
+				dropDepth = depth
+				start = offset
+				continue
+			}
+			// Projects are map entries or list options inside the option
+			if dropDepth > 0 && (t.Name.Local == "entry" || (t.Name.Local == "option" && attr(t, "value") != "")) {
+				removed++
+			}
+		case xml.EndElement:
+			if depth == dropDepth {
+				spans = append(spans, span{int(start), int(d.InputOffset())})
+				dropDepth = 0
+			}
+			depth--
+		}
+	}
+	if depth != 0 {
+		return nil, 0, errors.New("recent projects: unexpected end of file")
+	}
+
+	return cutSpans(data, spans), removed, nil
+}
+
+// attr returns the value of the named attribute of an element
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// cutSpans removes the spans from data together with the indentation
+// before each span and the line break after it
+func cutSpans(data []byte, spans []span) []byte {
+	out := make([]byte, 0, len(data))
+	last := 0
+	for _, s := range spans {
+		start, end := s.start, s.end
+		for start > last && (data[start-1] == ' ' || data[start-1] == '\t') {
+			start--
+		}
+		if end < len(data) && data[end] == '\r' {
+			end++
+		}
+		if end < len(data) && data[end] == '\n' {
+			end++
+		}
+		out = append(out, data[last:start]...)
+		last = end
+	}
+	return append(out, data[last:]...)
+}
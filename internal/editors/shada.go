@@ -0,0 +1,94 @@
+package editors
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ShaDa entry types, see :help shada-format
+const (
+	shadaHeader        = 1
+	shadaSearchPattern = 2
+	shadaSubString     = 3
+	shadaHistoryEntry  = 4
+	shadaRegister      = 5
+	shadaVariable      = 6
+	shadaGlobalMark    = 7
+	shadaJump          = 8
+	shadaBufferList    = 9
+	shadaLocalMark     = 10
+	shadaChange        = 11
+)
+
+// shadaHistoryTypes are the entry types removed by CleanShada
+var shadaHistoryTypes = map[uint64]bool{
+	shadaSearchPattern: true,
+	shadaSubString:     true,
+	shadaHistoryEntry:  true,
+	shadaGlobalMark:    true,
+	shadaJump:          true,
+	shadaBufferList:    true,
+	shadaLocalMark:     true,
+	shadaChange:        true,
+}
+
+// errShadaCorrupt is returned for ShaDa data that cannot be parsed
+var errShadaCorrupt = errors.New("shada: corrupt file")
+
+// CleanShada removes history, search, mark, jump, change and buffer list
+// entries from a neovim ShaDa file. The header, registers, variables and
+// unknown entries are copied unchanged. Each entry is three msgpack
+// integers (type, timestamp, length) followed by length bytes of data.
+func CleanShada(data []byte) ([]byte, int, error) {
+	out := make([]byte, 0, len(data))
+	removed := 0
+
+	for i := 0; i < len(data); {
+		start := i
+		var fields [3]uint64
+		for f := range fields {
+			n, size, err := readMsgpackUint(data[i:])
+			if err != nil {
+				return nil, 0, err
+			}
+			fields[f] = n
+			i += size
+		}
+
+		length := fields[2]
+		if length > uint64(len(data)-i) {
+			return nil, 0, errShadaCorrupt
+		}
+		i += int(length)
+
+		if shadaHistoryTypes[fields[0]] {
+			removed++
+			continue
+		}
+		out = append(out, data[start:i]...)
+	}
+
+	return out, removed, nil
+}
+
+// readMsgpackUint decodes a msgpack positive integer and returns it with
+// its encoded size
+func readMsgpackUint(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errShadaCorrupt
+	}
+	switch c := b[0]; {
+	case c <= 0x7f:
+		return uint64(c), 1, nil
+	case c == 0xcc && len(b) >= 2:
+		return uint64(b[1]), 2, nil
+	case c == 0xcd && len(b) >= 3:
+		return uint64(binary.BigEndian.Uint16(b[1:])), 3, nil
+	case c == 0xce && len(b) >= 5:
+		return uint64(binary.BigEndian.Uint32(b[1:])), 5, nil
+	case c == 0xcf && len(b) >= 9:
+		return binary.BigEndian.Uint64(b[1:]), 9, nil
+	default:
+		return 0, 0, errShadaCorrupt
+	}
+}
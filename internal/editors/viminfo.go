@@ -0,0 +1,42 @@
+// Package editors removes history traces from editor state files (vim,
+// neovim, emacs, VS Code and JetBrains IDEs) while leaving settings and
+// other state untouched.
+package editors
+
+import (
+	"bytes"
+	"strings"
+)
+
+// viminfoHistory holds the first characters of viminfo entries that record
+// history: command line (:), search (/ ?), expression (=) and input (@)
+// history, last search patterns (~), file marks ('), the jumplist (-),
+// marks within files (>) and the buffer list (%)
+const viminfoHistory = ":/?=@~'->%"
+
+// CleanViminfo removes the history, mark, jumplist and buffer list entries
+// from a viminfo file and returns the new contents and the number of
+// entries removed. Registers, variables and comments are kept. Lines that
+// start with a tab or "|" belong to the entry before them.
+func CleanViminfo(data []byte) ([]byte, int) {
+	var out bytes.Buffer
+	removed := 0
+	dropping := false
+
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != '\t' && line[0] != '|' {
+			dropping = strings.IndexByte(viminfoHistory, line[0]) >= 0
+			if dropping {
+				removed++
+			}
+		}
+		if !dropping {
+			out.Write(line)
+		}
+	}
+
+	return out.Bytes(), removed
+}
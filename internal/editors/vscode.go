@@ -0,0 +1,128 @@
+package editors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// VSCodeRecentKeys are the state.vscdb ItemTable keys holding recently
+// opened workspaces and files, and command palette and search history
+var VSCodeRecentKeys = []string{
+	"history.recentlyOpenedPathsList",
+	"commandPalette.mru.cache",
+	"commandPalette.mru.counter",
+	"workbench.find.history",
+	"workbench.view.search.state",
+}
+
+// vscodeStorageKeys are the storage.json keys holding recently opened paths;
+// lastKnownMenubarData caches the File > Open Recent menu
+var vscodeStorageKeys = []string{"openedPathsList", "lastKnownMenubarData"}
+
+// CleanVSCodeStorage removes the recently opened workspaces and files from a
+// VS Code storage.json. The removed keys are cut out of the original bytes,
+// so other keys, such as window state and theme, keep their order and
+// formatting. It returns the new contents and the number of recent entries.
+func CleanVSCodeStorage(data []byte) ([]byte, int, error) {
+	out, values, err := removeJSONMembers(data, vscodeStorageKeys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	removed := 0
+	for _, value := range values {
+		// Older versions used workspaces2/files2, newer ones a single list
+		var recent map[string][]json.RawMessage
+		if json.Unmarshal(value, &recent) == nil {
+			for _, list := range recent {
+				removed += len(list)
+			}
+		}
+	}
+	return out, removed, nil
+}
+
+// jsonMember is the position of a top-level member of a JSON object
+type jsonMember struct {
+	keyStart int // offset of the key's opening quote
+	end      int // offset just after the value
+}
+
+// removeJSONMembers cuts the given top-level keys out of a JSON object,
+// along with the comma and whitespace separating them from their
+// neighbours. It returns the new contents, unchanged when no key exists,
+// and the removed values by key.
+func removeJSONMembers(data []byte, keys []string) ([]byte, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, nil, err
+	} else if tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("not a JSON object")
+	}
+	open := int(dec.InputOffset())
+
+	drop := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		drop[key] = true
+	}
+
+	members := make([]jsonMember, 0)
+	removed := make([]bool, 0)
+	values := make(map[string]json.RawMessage)
+	for dec.More() {
+		start := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+
+		keyStart := start
+		for keyStart < len(data) && strings.IndexByte(" \t\r\n,", data[keyStart]) >= 0 {
+			keyStart++
+		}
+		key, _ := tok.(string)
+		members = append(members, jsonMember{keyStart: keyStart, end: int(dec.InputOffset())})
+		removed = append(removed, drop[key])
+		if drop[key] {
+			values[key] = value
+		}
+	}
+	if len(values) == 0 {
+		return data, values, nil
+	}
+
+	// Removed members are cut up to the next key; removed members at the
+	// end are cut from the end of the last kept value, taking its comma
+	tail := len(members)
+	for tail > 0 && removed[tail-1] {
+		tail--
+	}
+	out := make([]byte, 0, len(data))
+	last := 0
+	for i := 0; i < tail; i++ {
+		if removed[i] {
+			out = append(out, data[last:members[i].keyStart]...)
+			last = members[i+1].keyStart
+		}
+	}
+	if tail < len(members) {
+		from := open
+		if tail > 0 {
+			from = members[tail-1].end
+		}
+		out = append(out, data[last:from]...)
+		last = members[len(members)-1].end
+	}
+	out = append(out, data[last:]...)
+
+	if !json.Valid(out) {
+		return nil, nil, fmt.Errorf("rewritten JSON is invalid")
+	}
+	return out, values, nil
+}
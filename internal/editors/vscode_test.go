@@ -0,0 +1,78 @@
+package editors
+
+import "testing"
+
+func TestCleanVSCodeStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		removed int
+	}{
+		{
+			name: "recent paths in the middle",
+			data: `{
+	"windowsState": {"lastActiveWindow": {"folder": "file:///home/u/a"}},
+	"openedPathsList": {"entries": [{"folderUri": "file:///home/u/a"}, {"fileUri": "file:///home/u/b.txt"}]},
+	"theme": "vs-dark"
+}
+`,
+			want: `{
+	"windowsState": {"lastActiveWindow": {"folder": "file:///home/u/a"}},
+	"theme": "vs-dark"
+}
+`,
+			removed: 2,
+		},
+		{
+			name: "first and last keys",
+			data: `{
+  "openedPathsList": {"workspaces2": ["file:///w"], "files2": ["file:///f1", "file:///f2"]},
+  "zoom": 1,
+  "lastKnownMenubarData": {"menus": {}}
+}`,
+			want: `{
+  "zoom": 1
+}`,
+			removed: 3,
+		},
+		{
+			name:    "only recent keys",
+			data:    `{"openedPathsList": {"entries": []}, "lastKnownMenubarData": {}}`,
+			want:    `{}`,
+			removed: 0,
+		},
+		{
+			name:    "nothing to remove",
+			data:    "{\"b\": 1,   \"a\": [ 2 ]}\n",
+			want:    "{\"b\": 1,   \"a\": [ 2 ]}\n",
+			removed: 0,
+		},
+		{
+			name:    "nested keys are kept",
+			data:    `{"profile": {"openedPathsList": 1}}`,
+			want:    `{"profile": {"openedPathsList": 1}}`,
+			removed: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, removed, err := CleanVSCodeStorage([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("CleanVSCodeStorage() =\n%s\nwant\n%s", out, tt.want)
+			}
+			if removed != tt.removed {
+				t.Errorf("removed %d entries, want %d", removed, tt.removed)
+			}
+		})
+	}
+
+	for _, data := range []string{``, `[1, 2]`, `{"a": }`} {
+		if _, _, err := CleanVSCodeStorage([]byte(data)); err == nil {
+			t.Errorf("CleanVSCodeStorage(%q) succeeded, want an error", data)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package event defines the progress events reported by long-running
+// operations, cleaners as well as free space wipes, so that the TUI, GUI and
+// CLI can show and cancel them the same way.
+package event
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Kind is the stage of an operation an event reports
+type Kind int
+
+const (
+	// Started is sent when an operation begins
+	Started Kind = iota
+	// Progress is sent as an operation advances
+	Progress
+	// Finished is sent when an operation ends; Err is set when it failed or
+	// was cancelled
+	Finished
+)
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	switch k {
+	case Started:
+		return "started"
+	case Progress:
+		return "progress"
+	case Finished:
+		return "finished"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the kind by name
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Units of Done and Total
+const (
+	Bytes = "bytes"
+	Items = "items"
+)
+
+// Event reports the progress of a cleaner or a wipe
+type Event struct {
+	Kind Kind `json:"kind"`
+	// Source is the cleaner name or "Wipe"
+	Source string `json:"source"`
+	// Step describes what is being done, such as an item label or a pass
+	Step string `json:"step,omitempty"`
+	// Done and Total count the work in Unit; Total is 0 when unknown
+	Done  int64  `json:"done"`
+	Total int64  `json:"total"`
+	Unit  string `json:"unit"`
+	// Freed is the number of bytes a cleaner freed so far
+	Freed     int64         `json:"freed"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Remaining time.Duration `json:"remaining"`
+	// Err is the error of a Finished event, for instance context.Canceled
+	Err error `json:"-"`
+}
+
+// Percentage returns the completion percentage (0-100), or 0 when the
+// total is unknown
+func (e Event) Percentage() float64 {
+	if e.Total == 0 {
+		return 0
+	}
+	return float64(e.Done) / float64(e.Total) * 100
+}
+
+// MarshalJSON encodes the event with Err as an "error" string
+func (e Event) MarshalJSON() ([]byte, error) {
+	type plain Event
+	out := struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{plain: plain(e)}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// Func receives events. A nil Func drops them.
+type Func func(Event)
+
+// Send passes e to f unless f is nil
+func (f Func) Send(e Event) {
+	if f != nil {
+		f(e)
+	}
+}
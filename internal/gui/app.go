@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/mat/gowipeme/internal/backup"
 	"github.com/mat/gowipeme/internal/cleaner"
+	"github.com/mat/gowipeme/internal/event"
+	"github.com/mat/gowipeme/internal/process"
 	"github.com/mat/gowipeme/internal/wiper"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct holds the application state
@@ -18,6 +22,14 @@ type App struct {
 	wiper       *wiper.Wiper
 	wiperMethod wiper.WipeMethod
 	backupMgr   *backup.BackupManager
+
+	// cleanerWarnings are the cleaner definitions that failed to load and
+	// the parts of imported ones left out
+	cleanerWarnings []string
+
+	// cancel stops the running dry-run, cleaning or wipe
+	mu     sync.Mutex
+	cancel context.CancelFunc
 }
 
 // NewApp creates a new App application struct
@@ -30,6 +42,30 @@ func (a *App) GetContext() context.Context {
 	return a.ctx
 }
 
+// operation returns the context of a new cancellable operation
+func (a *App) operation() (context.Context, context.CancelFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.cancel = cancel
+	return ctx, cancel
+}
+
+// CancelOperation stops the running dry-run, cleaning or wipe. A stopped
+// wipe removes its wipe files before returning.
+func (a *App) CancelOperation() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
+
+// emitProgress sends an event to the frontend as "progress"
+func (a *App) emitProgress(e event.Event) {
+	runtime.EventsEmit(a.ctx, "progress", e)
+}
+
 // Startup is called when the app starts
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
@@ -37,10 +73,18 @@ func (a *App) Startup(ctx context.Context) {
 	// Initialize cleaner manager
 	a.cleanerMgr = cleaner.NewCleanerManager()
 	a.cleanerMgr.AddCleaner(cleaner.NewBrowserCleaner())
+	a.cleanerMgr.AddCleaner(cleaner.NewFirefoxCleaner())
 	a.cleanerMgr.AddCleaner(cleaner.NewShellCleaner())
+	a.cleanerMgr.AddCleaner(cleaner.NewReplCleaner())
+	a.cleanerMgr.AddCleaner(cleaner.NewEditorCleaner())
 	a.cleanerMgr.AddCleaner(cleaner.NewCacheCleaner())
+	a.cleanerMgr.AddCleaner(cleaner.NewThumbnailCleaner())
+	a.cleanerMgr.AddCleaner(cleaner.NewTrashCleaner())
 	a.cleanerMgr.AddCleaner(cleaner.NewRecentFilesCleaner())
 	a.cleanerMgr.AddCleaner(cleaner.NewClipboardCleaner())
+	for _, err := range a.cleanerMgr.AddCustomCleaners() {
+		a.cleanerWarnings = append(a.cleanerWarnings, err.Error())
+	}
 
 	// Initialize backup manager
 	backupMgr, err := backup.NewBackupManager()
@@ -51,18 +95,42 @@ func (a *App) Startup(ctx context.Context) {
 
 // CleanerInfo represents information about a cleaner
 type CleanerInfo struct {
-	Name  string   `json:"name"`
-	Items []string `json:"items"`
-	Count int      `json:"count"`
+	Name  string         `json:"name"`
+	Items []cleaner.Item `json:"items"`
+	Count int            `json:"count"`
+	Size  int64          `json:"size"`
+	// Running lists programs that use this cleaner's files and are still open
+	Running []string `json:"running"`
+	// Parents lists the shells gowipeme runs in, with advice on keeping
+	// them from writing their history back
+	Parents      []string `json:"parents"`
+	ParentAdvice string   `json:"parentAdvice,omitempty"`
+}
+
+// GetCleanerWarnings returns the errors of the cleaner definitions in
+// ~/.gowipeme/cleaners.d/ that were skipped, and what was left out of
+// imported CleanerML files
+func (a *App) GetCleanerWarnings() []string {
+	return a.cleanerWarnings
 }
 
-// GetCleanerStatus returns the current status of all cleaners
+// GetCleanerStatus returns the current status of all cleaners. Items are
+// also sent as "cleaner:item" events while the dry-run is running.
 func (a *App) GetCleanerStatus() ([]CleanerInfo, error) {
-	dryRunResults, err := a.cleanerMgr.DryRunAll()
+	ctx, cancel := a.operation()
+	defer cancel()
+
+	dryRunResults, err := a.cleanerMgr.DryRunAllStream(ctx, func(item cleaner.Item) {
+		runtime.EventsEmit(a.ctx, "cleaner:item", item)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	// Best effort: not every platform can list processes
+	running, _ := a.cleanerMgr.RunningPrograms(process.NewScanner())
+	parents, _ := a.cleanerMgr.ParentPrograms(process.NewScanner())
+
 	names := make([]string, 0, len(dryRunResults))
 	for cleanerName := range dryRunResults {
 		names = append(names, cleanerName)
@@ -72,21 +140,60 @@ func (a *App) GetCleanerStatus() ([]CleanerInfo, error) {
 	infos := make([]CleanerInfo, 0, len(names))
 	for _, cleanerName := range names {
 		items := dryRunResults[cleanerName]
-		infos = append(infos, CleanerInfo{
-			Name:  cleanerName,
-			Items: items,
-			Count: len(items),
-		})
+		info := CleanerInfo{
+			Name:    cleanerName,
+			Items:   items,
+			Count:   len(items),
+			Running: running[cleanerName],
+			Parents: parents[cleanerName],
+		}
+		if len(info.Parents) > 0 {
+			info.ParentAdvice = cleaner.ParentAdvice
+		}
+		for _, item := range items {
+			info.Size += item.Size
+		}
+		infos = append(infos, info)
 	}
 
 	return infos, nil
 }
 
-// RunCleaner runs all cleaners
+// RunCleaner runs all cleaners, sending "progress" events
 func (a *App) RunCleaner() error {
-	results := a.cleanerMgr.CleanAll()
+	ctx, cancel := a.operation()
+	defer cancel()
+	return cleanResultsError(a.cleanerMgr.CleanAllContext(ctx, a.emitProgress))
+}
 
-	// Check for errors
+// RunCleanerSelected cleans only the items with the given IDs, sending
+// "progress" events
+func (a *App) RunCleanerSelected(ids []string) error {
+	ctx, cancel := a.operation()
+	defer cancel()
+	return cleanResultsError(a.cleanerMgr.CleanSelectedContext(ctx, ids, a.emitProgress))
+}
+
+// WaitForRunning waits until the programs using the files of the named
+// cleaners have exited, or the operation is cancelled. The shells gowipeme
+// runs in are not waited for.
+func (a *App) WaitForRunning(names []string) error {
+	ctx, cancel := a.operation()
+	defer cancel()
+
+	waitFor := cleaner.NewCleanerManager()
+	for _, c := range a.cleanerMgr.GetCleaners() {
+		for _, name := range names {
+			if c.Name() == name {
+				waitFor.AddCleaner(c)
+			}
+		}
+	}
+	return waitFor.WaitForPrograms(ctx, process.NewScanner(), 2*time.Second)
+}
+
+// cleanResultsError returns the first error among the clean results
+func cleanResultsError(results []cleaner.CleanResult) error {
 	for _, result := range results {
 		if result.Error != nil {
 			return fmt.Errorf("%s: %w", result.CleanerName, result.Error)
@@ -157,7 +264,8 @@ func (a *App) GetWiperStatus() (*WiperInfo, error) {
 	}, nil
 }
 
-// RunWiper starts the wiping process
+// RunWiper wipes free space, sending "progress" events until it is done or
+// cancelled
 func (a *App) RunWiper(methodID int) error {
 	// Get home directory
 	homeDir, err := wiper.GetHomeDir()
@@ -175,18 +283,29 @@ func (a *App) RunWiper(methodID int) error {
 	a.wiper = w
 	a.wiperMethod = method
 
-	// Run wiping (this will block)
-	// In the real implementation, we'd want to run this in a goroutine
-	// and send progress updates via runtime events
+	ctx, cancel := a.operation()
+	defer cancel()
+
+	// Progress comes for every megabyte written; a few events a second are
+	// enough for the frontend
 	progressChan := make(chan wiper.Progress, 10)
+	done := make(chan struct{})
 	go func() {
-		for range progressChan {
-			// In a real implementation, we'd emit runtime events here
-			// runtime.EventsEmit(a.ctx, "wiper:progress", prog)
+		defer close(done)
+		var last time.Time
+		for prog := range progressChan {
+			if time.Since(last) >= 250*time.Millisecond {
+				last = time.Now()
+				a.emitProgress(prog.Event())
+			}
 		}
 	}()
 
-	return w.WipeFreeSpace(progressChan)
+	err = w.WipeFreeSpaceContext(ctx, progressChan)
+	close(progressChan)
+	<-done
+	a.emitProgress(event.Event{Kind: event.Finished, Source: wiper.EventSource, Err: err})
+	return err
 }
 
 // Greet returns a greeting message (example method)
@@ -40,4 +40,27 @@ func ExpandPath(path string) (string, error) {
 	return "", fmt.Errorf("unsupported ~ expansion: %q", path)
 }
 
+// GetStateHomePath returns $XDG_STATE_HOME, or ~/.local/state when unset.
+// Many command line tools keep their history there when configured for XDG.
+func GetStateHomePath() (string, error) {
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return xdg, nil
+	}
+	return ExpandPath("~/.local/state")
+}
 
+// GetDataHomePath returns $XDG_DATA_HOME, or ~/.local/share when unset
+func GetDataHomePath() (string, error) {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return xdg, nil
+	}
+	return ExpandPath("~/.local/share")
+}
+
+// GetConfigHomePath returns $XDG_CONFIG_HOME, or ~/.config when unset
+func GetConfigHomePath() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return xdg, nil
+	}
+	return ExpandPath("~/.config")
+}
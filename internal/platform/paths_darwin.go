@@ -3,29 +3,50 @@
 
 package platform
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
 // Browser history paths
+//
+// Chromium-family browsers return their user data directory, which holds
+// "Local State" and one directory per profile.
+
 func GetSafariHistoryPath() (string, error) {
 	return ExpandPath("~/Library/Safari/History.db")
 }
 
-func GetChromeHistoryPath() (string, error) {
-	return ExpandPath("~/Library/Application Support/Google/Chrome/Default/History")
+func GetChromeUserDataPath() (string, error) {
+	return ExpandPath("~/Library/Application Support/Google/Chrome")
+}
+
+func GetChromiumUserDataPath() (string, error) {
+	return ExpandPath("~/Library/Application Support/Chromium")
+}
+
+// GetFirefoxDataPath returns the directory holding profiles.ini and installs.ini
+func GetFirefoxDataPath() (string, error) {
+	return ExpandPath("~/Library/Application Support/Firefox")
 }
 
-func GetFirefoxProfilesPath() (string, error) {
-	return ExpandPath("~/Library/Application Support/Firefox/Profiles")
+// GetFirefoxCachePath returns the directory holding per-profile disk caches,
+// mirroring the profile layout under the data directory
+func GetFirefoxCachePath() (string, error) {
+	return ExpandPath("~/Library/Caches/Firefox")
 }
 
-func GetEdgeHistoryPath() (string, error) {
-	return ExpandPath("~/Library/Application Support/Microsoft Edge/Default/History")
+func GetEdgeUserDataPath() (string, error) {
+	return ExpandPath("~/Library/Application Support/Microsoft Edge")
 }
 
-func GetBraveHistoryPath() (string, error) {
-	return ExpandPath("~/Library/Application Support/BraveSoftware/Brave-Browser/Default/History")
+func GetBraveUserDataPath() (string, error) {
+	return ExpandPath("~/Library/Application Support/BraveSoftware/Brave-Browser")
 }
 
-func GetArcHistoryPath() (string, error) {
-	return ExpandPath("~/Library/Application Support/Arc/User Data/Default/History")
+func GetArcUserDataPath() (string, error) {
+	return ExpandPath("~/Library/Application Support/Arc/User Data")
 }
 
 // Shell history paths
@@ -45,11 +66,101 @@ func GetFishHistoryPath() (string, error) {
 	return ExpandPath("~/.local/share/fish/fish_history")
 }
 
+func GetPowerShellHistoryPath() (string, error) {
+	return ExpandPath("~/.local/share/powershell/PSReadLine/ConsoleHost_history.txt")
+}
+
+// GetNushellConfigPath returns the nushell config directory, which holds
+// history.txt or history.sqlite3
+func GetNushellConfigPath() (string, error) {
+	return ExpandPath("~/Library/Application Support/nushell")
+}
+
+// GetXonshDataPath returns the xonsh data directory, which holds the JSON
+// (history_json) and SQLite history backends
+func GetXonshDataPath() (string, error) {
+	if dir := os.Getenv("XONSH_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+	return ExpandPath("~/.local/share/xonsh")
+}
+
+func GetKshHistoryPath() (string, error) {
+	return ExpandPath("~/.sh_history")
+}
+
+func GetTcshHistoryPath() (string, error) {
+	return ExpandPath("~/.history")
+}
+
+// Editor paths
+func GetViminfoPath() (string, error) {
+	return ExpandPath("~/.viminfo")
+}
+
+// GetNeovimDataPath returns the neovim data directory holding shada/
+func GetNeovimDataPath() (string, error) {
+	data, err := GetDataHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "nvim"), nil
+}
+
+// GetNeovimStatePath returns the neovim state directory holding swap/,
+// undo/ and (since 0.8) shada/
+func GetNeovimStatePath() (string, error) {
+	state, err := GetStateHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "nvim"), nil
+}
+
+// GetAppConfigPath returns the directory where desktop applications such
+// as VS Code and JetBrains IDEs keep their configuration and state
+func GetAppConfigPath() (string, error) {
+	return ExpandPath("~/Library/Application Support")
+}
+
+// Desktop activity paths (KDE and GNOME only run on Linux)
+func GetKDERecentDocumentsPath() (string, error) {
+	return "", fmt.Errorf("KDE is not supported on macOS")
+}
+
+func GetKActivitiesDatabasePath() (string, error) {
+	return "", fmt.Errorf("KDE is not supported on macOS")
+}
+
+func GetKDEPlacesPath() (string, error) {
+	return "", fmt.Errorf("KDE is not supported on macOS")
+}
+
+func GetZeitgeistPath() (string, error) {
+	return "", fmt.Errorf("zeitgeist is not supported on macOS")
+}
+
+func GetTrackerCachePaths() ([]string, error) {
+	return nil, fmt.Errorf("tracker is not supported on macOS")
+}
+
+func GetGTKBookmarksPath() (string, error) {
+	return "", fmt.Errorf("GTK bookmarks are not supported on macOS")
+}
+
 // Application cache paths
 func GetCachesPath() (string, error) {
 	return ExpandPath("~/Library/Caches")
 }
 
+func GetThumbnailsPaths() ([]string, error) {
+	return nil, fmt.Errorf("freedesktop thumbnails are not supported on macOS")
+}
+
+func GetTrashPaths() ([]string, error) {
+	return nil, fmt.Errorf("freedesktop trash is not supported on macOS")
+}
+
 // Recent files paths
 func GetRecentDocumentsPath() (string, error) {
 	return ExpandPath("~/Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.ApplicationRecentDocuments")
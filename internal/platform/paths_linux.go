@@ -4,34 +4,52 @@
 package platform
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 // Browser history paths
+//
+// Chromium-family browsers return their user data directory, which holds
+// "Local State" and one directory per profile.
+
 func GetSafariHistoryPath() (string, error) {
 	return "", fmt.Errorf("Safari is not available on Linux")
 }
 
-func GetChromeHistoryPath() (string, error) {
+func GetChromeUserDataPath() (string, error) {
 	// Google Chrome
-	return ExpandPath("~/.config/google-chrome/Default/History")
+	return ExpandPath("~/.config/google-chrome")
+}
+
+func GetChromiumUserDataPath() (string, error) {
+	return ExpandPath("~/.config/chromium")
 }
 
-func GetFirefoxProfilesPath() (string, error) {
+// GetFirefoxDataPath returns the directory holding profiles.ini and installs.ini
+func GetFirefoxDataPath() (string, error) {
 	return ExpandPath("~/.mozilla/firefox")
 }
 
-func GetEdgeHistoryPath() (string, error) {
-	return ExpandPath("~/.config/microsoft-edge/Default/History")
+// GetFirefoxCachePath returns the directory holding per-profile disk caches,
+// mirroring the profile layout under the data directory
+func GetFirefoxCachePath() (string, error) {
+	return ExpandPath("~/.cache/mozilla/firefox")
+}
+
+func GetEdgeUserDataPath() (string, error) {
+	return ExpandPath("~/.config/microsoft-edge")
 }
 
-func GetBraveHistoryPath() (string, error) {
-	return ExpandPath("~/.config/BraveSoftware/Brave-Browser/Default/History")
+func GetBraveUserDataPath() (string, error) {
+	return ExpandPath("~/.config/BraveSoftware/Brave-Browser")
 }
 
-func GetArcHistoryPath() (string, error) {
+func GetArcUserDataPath() (string, error) {
 	return "", fmt.Errorf("Arc is not supported on Linux")
 }
 
@@ -53,6 +71,131 @@ func GetFishHistoryPath() (string, error) {
 	return ExpandPath("~/.local/share/fish/fish_history")
 }
 
+func GetPowerShellHistoryPath() (string, error) {
+	// PSReadLine follows XDG on Linux
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "powershell", "PSReadLine", "ConsoleHost_history.txt"), nil
+	}
+	return ExpandPath("~/.local/share/powershell/PSReadLine/ConsoleHost_history.txt")
+}
+
+// GetNushellConfigPath returns the nushell config directory, which holds
+// history.txt or history.sqlite3
+func GetNushellConfigPath() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "nushell"), nil
+	}
+	return ExpandPath("~/.config/nushell")
+}
+
+// GetXonshDataPath returns the xonsh data directory, which holds the JSON
+// (history_json) and SQLite history backends
+func GetXonshDataPath() (string, error) {
+	if dir := os.Getenv("XONSH_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "xonsh"), nil
+	}
+	return ExpandPath("~/.local/share/xonsh")
+}
+
+func GetKshHistoryPath() (string, error) {
+	return ExpandPath("~/.sh_history")
+}
+
+func GetTcshHistoryPath() (string, error) {
+	return ExpandPath("~/.history")
+}
+
+// Editor paths
+func GetViminfoPath() (string, error) {
+	return ExpandPath("~/.viminfo")
+}
+
+// GetNeovimDataPath returns the neovim data directory holding shada/
+func GetNeovimDataPath() (string, error) {
+	data, err := GetDataHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "nvim"), nil
+}
+
+// GetNeovimStatePath returns the neovim state directory holding swap/,
+// undo/ and (since 0.8) shada/
+func GetNeovimStatePath() (string, error) {
+	state, err := GetStateHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "nvim"), nil
+}
+
+// GetAppConfigPath returns the directory where desktop applications such
+// as VS Code and JetBrains IDEs keep their configuration and state
+func GetAppConfigPath() (string, error) {
+	return GetConfigHomePath()
+}
+
+// Desktop activity paths
+
+// GetKDERecentDocumentsPath returns the directory of KDE's recent document
+// .desktop links
+func GetKDERecentDocumentsPath() (string, error) {
+	data, err := GetDataHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "RecentDocuments"), nil
+}
+
+// GetKActivitiesDatabasePath returns kactivitymanagerd's resource usage database
+func GetKActivitiesDatabasePath() (string, error) {
+	data, err := GetDataHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "kactivitymanagerd", "resources", "database"), nil
+}
+
+// GetKDEPlacesPath returns the places shown in KDE file dialogs and Dolphin
+func GetKDEPlacesPath() (string, error) {
+	data, err := GetDataHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "user-places.xbel"), nil
+}
+
+// GetZeitgeistPath returns the directory of GNOME's zeitgeist activity log
+func GetZeitgeistPath() (string, error) {
+	data, err := GetDataHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "zeitgeist"), nil
+}
+
+// GetTrackerCachePaths returns the file index caches of Tracker 2 and
+// Tracker 3 / localsearch
+func GetTrackerCachePaths() ([]string, error) {
+	cache, err := GetCachesPath()
+	if err != nil {
+		return nil, err
+	}
+	return []string{filepath.Join(cache, "tracker3", "files"), filepath.Join(cache, "tracker")}, nil
+}
+
+// GetGTKBookmarksPath returns the bookmarks shown in GTK file choosers
+func GetGTKBookmarksPath() (string, error) {
+	config, err := GetConfigHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(config, "gtk-3.0", "bookmarks"), nil
+}
+
 // Application cache paths
 func GetCachesPath() (string, error) {
 	// Prefer XDG cache dir if set
@@ -63,6 +206,60 @@ func GetCachesPath() (string, error) {
 	return ExpandPath("~/.cache")
 }
 
+// GetThumbnailsPaths returns the freedesktop thumbnail caches: the current
+// one under the cache directory and the legacy ~/.thumbnails
+func GetThumbnailsPaths() ([]string, error) {
+	cache, err := GetCachesPath()
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := ExpandPath("~/.thumbnails")
+	if err != nil {
+		return nil, err
+	}
+	return []string{filepath.Join(cache, "thumbnails"), legacy}, nil
+}
+
+// GetTrashPaths returns the home trash and the trash directories of mounted
+// volumes: $topdir/.Trash/$UID when .Trash is a sticky directory (not a
+// symbolic link), and $topdir/.Trash-$UID
+func GetTrashPaths() ([]string, error) {
+	data, err := GetDataHomePath()
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{filepath.Join(data, "Trash")}
+
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return paths, nil
+	}
+	defer f.Close()
+
+	uid := strconv.Itoa(os.Getuid())
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		// Spaces and other characters are octal escapes such as \040
+		top, err := strconv.Unquote(`"` + strings.ReplaceAll(fields[1], `"`, `\"`) + `"`)
+		if err != nil || seen[top] {
+			continue
+		}
+		seen[top] = true
+
+		if info, err := os.Lstat(filepath.Join(top, ".Trash")); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+			paths = append(paths, filepath.Join(top, ".Trash", uid))
+		}
+		paths = append(paths, filepath.Join(top, ".Trash-"+uid))
+	}
+
+	return paths, nil
+}
+
 // Recent files paths
 func GetRecentDocumentsPath() (string, error) {
 	// Freedesktop recent files list
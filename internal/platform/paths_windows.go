@@ -32,43 +32,66 @@ func appData() (string, error) {
 }
 
 // Browser history paths
+//
+// Chromium-family browsers return their user data directory, which holds
+// "Local State" and one directory per profile.
+
 func GetSafariHistoryPath() (string, error) {
 	return "", fmt.Errorf("Safari is not available on Windows")
 }
 
-func GetChromeHistoryPath() (string, error) {
+func GetChromeUserDataPath() (string, error) {
+	lad, err := localAppData()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(lad, "Google", "Chrome", "User Data"), nil
+}
+
+func GetChromiumUserDataPath() (string, error) {
 	lad, err := localAppData()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(lad, "Google", "Chrome", "User Data", "Default", "History"), nil
+	return filepath.Join(lad, "Chromium", "User Data"), nil
 }
 
-func GetFirefoxProfilesPath() (string, error) {
+// GetFirefoxDataPath returns the directory holding profiles.ini and installs.ini
+func GetFirefoxDataPath() (string, error) {
 	ad, err := appData()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(ad, "Mozilla", "Firefox", "Profiles"), nil
+	return filepath.Join(ad, "Mozilla", "Firefox"), nil
 }
 
-func GetEdgeHistoryPath() (string, error) {
+// GetFirefoxCachePath returns the directory holding per-profile disk caches,
+// mirroring the profile layout under the data directory
+func GetFirefoxCachePath() (string, error) {
 	lad, err := localAppData()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(lad, "Microsoft", "Edge", "User Data", "Default", "History"), nil
+	return filepath.Join(lad, "Mozilla", "Firefox"), nil
 }
 
-func GetBraveHistoryPath() (string, error) {
+func GetEdgeUserDataPath() (string, error) {
 	lad, err := localAppData()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(lad, "BraveSoftware", "Brave-Browser", "User Data", "Default", "History"), nil
+	return filepath.Join(lad, "Microsoft", "Edge", "User Data"), nil
 }
 
-func GetArcHistoryPath() (string, error) {
+func GetBraveUserDataPath() (string, error) {
+	lad, err := localAppData()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(lad, "BraveSoftware", "Brave-Browser", "User Data"), nil
+}
+
+func GetArcUserDataPath() (string, error) {
 	return "", fmt.Errorf("Arc is not supported on Windows")
 }
 
@@ -90,6 +113,93 @@ func GetFishHistoryPath() (string, error) {
 	return ExpandPath("~/.local/share/fish/fish_history")
 }
 
+func GetPowerShellHistoryPath() (string, error) {
+	ad, err := appData()
+	if err != nil {
+		return "", err
+	}
+	// PSReadLine history (PowerShell 5+ / 7+)
+	return filepath.Join(ad, "Microsoft", "Windows", "PowerShell", "PSReadLine", "ConsoleHost_history.txt"), nil
+}
+
+// GetNushellConfigPath returns the nushell config directory, which holds
+// history.txt or history.sqlite3
+func GetNushellConfigPath() (string, error) {
+	ad, err := appData()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ad, "nushell"), nil
+}
+
+// GetXonshDataPath returns the xonsh data directory, which holds the JSON
+// (history_json) and SQLite history backends
+func GetXonshDataPath() (string, error) {
+	if dir := os.Getenv("XONSH_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+	return ExpandPath("~/.local/share/xonsh")
+}
+
+func GetKshHistoryPath() (string, error) {
+	return "", fmt.Errorf("ksh is not supported on Windows")
+}
+
+func GetTcshHistoryPath() (string, error) {
+	return "", fmt.Errorf("tcsh is not supported on Windows")
+}
+
+// Editor paths
+func GetViminfoPath() (string, error) {
+	return ExpandPath("~/_viminfo")
+}
+
+// GetNeovimDataPath returns the neovim data directory holding shada/
+func GetNeovimDataPath() (string, error) {
+	lad, err := localAppData()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(lad, "nvim-data"), nil
+}
+
+// GetNeovimStatePath returns the neovim state directory holding swap/ and
+// undo/; Windows uses the data directory for both
+func GetNeovimStatePath() (string, error) {
+	return GetNeovimDataPath()
+}
+
+// GetAppConfigPath returns the directory where desktop applications such
+// as VS Code and JetBrains IDEs keep their configuration and state
+func GetAppConfigPath() (string, error) {
+	return appData()
+}
+
+// Desktop activity paths (KDE and GNOME only run on Linux)
+func GetKDERecentDocumentsPath() (string, error) {
+	return "", fmt.Errorf("KDE is not supported on Windows")
+}
+
+func GetKActivitiesDatabasePath() (string, error) {
+	return "", fmt.Errorf("KDE is not supported on Windows")
+}
+
+func GetKDEPlacesPath() (string, error) {
+	return "", fmt.Errorf("KDE is not supported on Windows")
+}
+
+func GetZeitgeistPath() (string, error) {
+	return "", fmt.Errorf("zeitgeist is not supported on Windows")
+}
+
+func GetTrackerCachePaths() ([]string, error) {
+	return nil, fmt.Errorf("tracker is not supported on Windows")
+}
+
+func GetGTKBookmarksPath() (string, error) {
+	return "", fmt.Errorf("GTK bookmarks are not supported on Windows")
+}
+
 // Application cache paths
 func GetCachesPath() (string, error) {
 	lad, err := localAppData()
@@ -100,6 +210,14 @@ func GetCachesPath() (string, error) {
 	return filepath.Join(lad, "Temp"), nil
 }
 
+func GetThumbnailsPaths() ([]string, error) {
+	return nil, fmt.Errorf("freedesktop thumbnails are not supported on Windows")
+}
+
+func GetTrashPaths() ([]string, error) {
+	return nil, fmt.Errorf("freedesktop trash is not supported on Windows")
+}
+
 // Recent files paths
 func GetRecentDocumentsPath() (string, error) {
 	ad, err := appData()
@@ -0,0 +1,174 @@
+// Package process finds running programs by reading a procfs, so cleaners can
+// warn before touching files those programs hold open or rewrite on exit.
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoProcfs is returned when the procfs root does not exist, e.g. on
+// macOS and Windows
+var ErrNoProcfs = errors.New("procfs not available")
+
+// Process is a running process
+type Process struct {
+	PID  int
+	PPID int    // parent PID from status, 0 when unknown
+	Name string // short name from comm, e.g. "firefox"
+	Exe  string // base name of argv[0], e.g. "chrome"
+	// Ancestor marks the processes Scanner.Self runs under, such as the
+	// shell gowipeme was started from
+	Ancestor bool
+}
+
+// Matches reports whether the process was started as one of names.
+// Names are compared case-insensitively against comm and argv[0].
+func (p Process) Matches(names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(p.Name, name) || strings.EqualFold(p.Exe, name) {
+			return true
+		}
+		// comm is truncated to 15 bytes by the kernel
+		if len(p.Name) == 15 && len(name) > 15 && strings.EqualFold(p.Name, name[:15]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scanner lists processes from a procfs mounted at Root
+type Scanner struct {
+	// Root is the procfs mount point, "/proc" by default. Tests can point
+	// it at a directory laid out like /proc/<pid>/{comm,cmdline,status}.
+	Root string
+	// Self is the PID left out of List; its ancestors are marked, as they
+	// cannot exit before it does. 0 keeps and marks nothing.
+	Self int
+}
+
+// NewScanner creates a scanner for the system procfs that leaves out this
+// process and marks its ancestors
+func NewScanner() *Scanner {
+	return &Scanner{Root: "/proc", Self: os.Getpid()}
+}
+
+// List returns every process readable under Root except Self, ordered by
+// PID. Processes that exit while scanning are skipped.
+func (s *Scanner) List() ([]Process, error) {
+	entries, err := os.ReadDir(s.Root)
+	if os.IsNotExist(err) {
+		return nil, ErrNoProcfs
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	procs := make([]Process, 0, len(entries))
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(s.Root, entry.Name())
+		p := Process{PID: pid}
+		if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+			p.Name = strings.TrimSpace(string(comm))
+		}
+		if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+			argv0 := strings.SplitN(string(cmdline), "\x00", 2)[0]
+			// Login shells are started as "-bash"
+			p.Exe = strings.TrimPrefix(filepath.Base(argv0), "-")
+		}
+		if status, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+			p.PPID = parentPID(string(status))
+		}
+		if p.Name == "" && p.Exe == "" {
+			continue
+		}
+		procs = append(procs, p)
+	}
+
+	if s.Self > 0 {
+		own := ancestors(procs, s.Self)
+		others := procs[:0]
+		for _, p := range procs {
+			if p.PID == s.Self {
+				continue
+			}
+			p.Ancestor = own[p.PID]
+			others = append(others, p)
+		}
+		procs = others
+	}
+
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs, nil
+}
+
+// parentPID returns the PPid field of a /proc/<pid>/status file
+func parentPID(status string) int {
+	for _, line := range strings.Split(status, "\n") {
+		if value, ok := strings.CutPrefix(line, "PPid:"); ok {
+			ppid, _ := strconv.Atoi(strings.TrimSpace(value))
+			return ppid
+		}
+	}
+	return 0
+}
+
+// ancestors returns pid and the PIDs of its parents up to the first one
+// not in procs
+func ancestors(procs []Process, pid int) map[int]bool {
+	parents := make(map[int]int, len(procs))
+	for _, p := range procs {
+		parents[p.PID] = p.PPID
+	}
+
+	own := make(map[int]bool)
+	for pid > 0 && !own[pid] {
+		own[pid] = true
+		pid = parents[pid]
+	}
+	return own
+}
+
+// Running returns the running processes started as one of names
+func (s *Scanner) Running(names []string) ([]Process, error) {
+	procs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	running := make([]Process, 0)
+	for _, p := range procs {
+		if p.Matches(names) {
+			running = append(running, p)
+		}
+	}
+	return running, nil
+}
+
+// Names returns the distinct program names of procs, sorted. Browsers run
+// many processes, so this is what is shown to the user.
+func Names(procs []Process) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, p := range procs {
+		name := p.Exe
+		if name == "" {
+			name = p.Name
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,281 @@
+package process_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mat/gowipeme/internal/cleaner"
+	"github.com/mat/gowipeme/internal/process"
+)
+
+// fakeProc is one /proc/<pid> directory of a fake procfs
+type fakeProc struct {
+	pid     int
+	ppid    int
+	comm    string
+	cmdline string // NUL-separated argv
+}
+
+// writeProcfs lays out procs under a temporary root like /proc
+func writeProcfs(t *testing.T, procs ...fakeProc) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, p := range procs {
+		writeProc(t, root, p)
+	}
+	// Entries that are not processes are ignored
+	if err := os.WriteFile(filepath.Join(root, "uptime"), []byte("1.0 1.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// writeProc adds p to the fake procfs at root
+func writeProc(t *testing.T, root string, p fakeProc) {
+	t.Helper()
+	dir := filepath.Join(root, fmt.Sprint(p.pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"comm":    p.comm + "\n",
+		"cmdline": p.cmdline,
+		"status":  fmt.Sprintf("Name:\t%s\nState:\tS (sleeping)\nPid:\t%d\nPPid:\t%d\n", p.comm, p.pid, p.ppid),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		proc  process.Process
+		names []string
+		want  bool
+	}{
+		{"comm", process.Process{Name: "firefox"}, []string{"firefox"}, true},
+		{"argv0", process.Process{Name: "MainThread", Exe: "chrome"}, []string{"chrome"}, true},
+		{"case-insensitive", process.Process{Name: "Firefox"}, []string{"firefox"}, true},
+		{"truncated comm", process.Process{Name: "gnome-shell-cal"}, []string{"gnome-shell-calendar-server"}, true},
+		{"short comm is not a prefix", process.Process{Name: "gnome"}, []string{"gnome-shell-calendar-server"}, false},
+		{"15-byte comm needs a longer name", process.Process{Name: "gnome-shell-cal"}, []string{"gnome-shell-ca"}, false},
+		{"other program", process.Process{Name: "bash", Exe: "bash"}, []string{"zsh", "fish"}, false},
+		{"no names", process.Process{Name: "bash"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.proc.Matches(tt.names); got != tt.want {
+				t.Errorf("%+v.Matches(%q) = %v, want %v", tt.proc, tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	root := writeProcfs(t,
+		fakeProc{pid: 1, comm: "systemd", cmdline: "/sbin/init\x00splash\x00"},
+		fakeProc{pid: 42, ppid: 1, comm: "bash", cmdline: "-bash\x00"},
+		fakeProc{pid: 7, ppid: 1, comm: "gnome-shell-cal", cmdline: "/usr/libexec/gnome-shell-calendar-server\x00"},
+		fakeProc{pid: 300, ppid: 1, comm: "kworker/0:1"},
+	)
+
+	procs, err := (&process.Scanner{Root: root}).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []process.Process{
+		{PID: 1, Name: "systemd", Exe: "init"},
+		{PID: 7, PPID: 1, Name: "gnome-shell-cal", Exe: "gnome-shell-calendar-server"},
+		{PID: 42, PPID: 1, Name: "bash", Exe: "bash"},
+		{PID: 300, PPID: 1, Name: "kworker/0:1"},
+	}
+	if !reflect.DeepEqual(procs, want) {
+		t.Errorf("List() = %+v, want %+v", procs, want)
+	}
+}
+
+func TestListLeavesOutSelfAndMarksAncestors(t *testing.T) {
+	root := writeProcfs(t,
+		fakeProc{pid: 1, comm: "systemd", cmdline: "/sbin/init\x00"},
+		fakeProc{pid: 10, ppid: 1, comm: "bash", cmdline: "-bash\x00"},
+		fakeProc{pid: 20, ppid: 10, comm: "sudo", cmdline: "sudo\x00gowipeme\x00"},
+		fakeProc{pid: 30, ppid: 20, comm: "gowipeme", cmdline: "./gowipeme\x00clean\x00"},
+		fakeProc{pid: 40, ppid: 1, comm: "bash", cmdline: "bash\x00"},
+		fakeProc{pid: 50, ppid: 30, comm: "xclip", cmdline: "xclip\x00"},
+	)
+
+	tests := []struct {
+		self      int
+		want      []int
+		ancestors []int
+	}{
+		{0, []int{1, 10, 20, 30, 40, 50}, []int{}},
+		{30, []int{1, 10, 20, 40, 50}, []int{1, 10, 20}},
+		{40, []int{1, 10, 20, 30, 50}, []int{1}},
+		// A PID that is not listed leaves out and marks nothing
+		{99, []int{1, 10, 20, 30, 40, 50}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.self), func(t *testing.T) {
+			procs, err := (&process.Scanner{Root: root, Self: tt.self}).List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			pids := make([]int, 0, len(procs))
+			ancestors := make([]int, 0)
+			for _, p := range procs {
+				pids = append(pids, p.PID)
+				if p.Ancestor {
+					ancestors = append(ancestors, p.PID)
+				}
+			}
+			if !reflect.DeepEqual(pids, tt.want) {
+				t.Errorf("List() with Self %d = %v, want %v", tt.self, pids, tt.want)
+			}
+			if !reflect.DeepEqual(ancestors, tt.ancestors) {
+				t.Errorf("List() with Self %d marks %v as ancestors, want %v", tt.self, ancestors, tt.ancestors)
+			}
+		})
+	}
+}
+
+func TestListWithoutProcfs(t *testing.T) {
+	_, err := (&process.Scanner{Root: filepath.Join(t.TempDir(), "missing")}).List()
+	if !errors.Is(err, process.ErrNoProcfs) {
+		t.Errorf("List() error = %v, want ErrNoProcfs", err)
+	}
+}
+
+// awareCleaner is a cleaner whose files are used by the named programs
+type awareCleaner struct {
+	name      string
+	processes []string
+}
+
+func (c awareCleaner) Name() string                          { return c.name }
+func (c awareCleaner) DryRun() ([]cleaner.Item, error)       { return nil, nil }
+func (c awareCleaner) Clean() error                          { return nil }
+func (c awareCleaner) CleanItems(items []cleaner.Item) error { return nil }
+func (c awareCleaner) Processes() []string                   { return c.processes }
+
+// plainCleaner does not implement ProcessAware
+type plainCleaner struct{ name string }
+
+func (c plainCleaner) Name() string                          { return c.name }
+func (c plainCleaner) DryRun() ([]cleaner.Item, error)       { return nil, nil }
+func (c plainCleaner) Clean() error                          { return nil }
+func (c plainCleaner) CleanItems(items []cleaner.Item) error { return nil }
+
+func TestRunningPrograms(t *testing.T) {
+	root := writeProcfs(t,
+		fakeProc{pid: 1, comm: "systemd", cmdline: "/sbin/init\x00"},
+		fakeProc{pid: 10, ppid: 1, comm: "bash", cmdline: "-bash\x00"},
+		fakeProc{pid: 11, ppid: 10, comm: "gowipeme", cmdline: "gowipeme\x00clean\x00"},
+		fakeProc{pid: 20, ppid: 1, comm: "zsh", cmdline: "-zsh\x00"},
+		fakeProc{pid: 30, ppid: 1, comm: "GeckoMain", cmdline: "/usr/lib/firefox/firefox\x00"},
+		fakeProc{pid: 31, ppid: 30, comm: "Web Content", cmdline: "/usr/lib/firefox/firefox\x00-contentproc\x00"},
+		fakeProc{pid: 40, ppid: 1, comm: "chrome", cmdline: "/opt/google/chrome/chrome\x00"},
+	)
+
+	cm := cleaner.NewCleanerManager()
+	cm.AddCleaner(awareCleaner{"Shell History", []string{"bash", "zsh", "fish"}})
+	cm.AddCleaner(awareCleaner{"Firefox", []string{"firefox"}})
+	cm.AddCleaner(awareCleaner{"Brave", []string{"brave"}})
+	cm.AddCleaner(plainCleaner{"Trash"})
+
+	tests := []struct {
+		name string
+		self int
+		want map[string][]string
+	}{
+		{"every process", 0, map[string][]string{
+			"Shell History": {"bash", "zsh"},
+			"Firefox":       {"firefox"},
+		}},
+		{"own shell kept", 11, map[string][]string{
+			"Shell History": {"bash", "zsh"},
+			"Firefox":       {"firefox"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running, err := cm.RunningPrograms(&process.Scanner{Root: root, Self: tt.self})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(running, tt.want) {
+				t.Errorf("RunningPrograms() = %v, want %v", running, tt.want)
+			}
+		})
+	}
+}
+
+func TestParentPrograms(t *testing.T) {
+	root := writeProcfs(t,
+		fakeProc{pid: 1, comm: "systemd", cmdline: "/sbin/init\x00"},
+		fakeProc{pid: 10, ppid: 1, comm: "bash", cmdline: "-bash\x00"},
+		fakeProc{pid: 11, ppid: 10, comm: "gowipeme", cmdline: "gowipeme\x00clean\x00"},
+		fakeProc{pid: 20, ppid: 1, comm: "zsh", cmdline: "-zsh\x00"},
+	)
+
+	cm := cleaner.NewCleanerManager()
+	cm.AddCleaner(awareCleaner{"Shell History", []string{"bash", "zsh", "fish"}})
+	cm.AddCleaner(awareCleaner{"Firefox", []string{"firefox"}})
+
+	parents, err := cm.ParentPrograms(&process.Scanner{Root: root, Self: 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{"Shell History": {"bash"}}; !reflect.DeepEqual(parents, want) {
+		t.Errorf("ParentPrograms() = %v, want %v", parents, want)
+	}
+}
+
+func TestWaitForPrograms(t *testing.T) {
+	cm := cleaner.NewCleanerManager()
+	cm.AddCleaner(awareCleaner{"Shell History", []string{"bash", "zsh", "fish"}})
+
+	// The parent shell is not waited for
+	root := writeProcfs(t,
+		fakeProc{pid: 10, ppid: 1, comm: "bash", cmdline: "-bash\x00"},
+		fakeProc{pid: 11, ppid: 10, comm: "gowipeme", cmdline: "gowipeme\x00clean\x00"},
+	)
+	scanner := &process.Scanner{Root: root, Self: 11}
+	if err := cm.WaitForPrograms(context.Background(), scanner, time.Millisecond); err != nil {
+		t.Errorf("WaitForPrograms() with only the parent shell = %v, want nil", err)
+	}
+
+	// Another shell keeps it waiting until it exits
+	other := filepath.Join(root, "20")
+	writeProc(t, root, fakeProc{pid: 20, ppid: 1, comm: "zsh", cmdline: "-zsh\x00"})
+	done := make(chan error)
+	go func() { done <- cm.WaitForPrograms(context.Background(), scanner, time.Millisecond) }()
+	select {
+	case err := <-done:
+		t.Fatalf("WaitForPrograms() returned %v while zsh runs", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := os.RemoveAll(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("WaitForPrograms() after zsh exited = %v, want nil", err)
+	}
+
+	// Cancelling stops waiting
+	writeProc(t, root, fakeProc{pid: 20, ppid: 1, comm: "zsh", cmdline: "-zsh\x00"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cm.WaitForPrograms(ctx, scanner, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitForPrograms() after cancel = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,45 @@
+package secrets
+
+import "regexp"
+
+// DefaultRules returns the built-in rules, most specific first so that
+// generic rules do not claim well-known token formats
+func DefaultRules() []Rule {
+	return []Rule{
+		{Name: "private-key", Pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+		{Name: "aws-access-key-id", Pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+		{Name: "aws-secret-access-key", Pattern: regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`)},
+		{Name: "github-token", Pattern: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+		{Name: "gitlab-token", Pattern: regexp.MustCompile(`\b(glpat-[A-Za-z0-9_-]{20,})`)},
+		{Name: "slack-token", Pattern: regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})`)},
+		{Name: "stripe-key", Pattern: regexp.MustCompile(`\b((?:sk|rk)_live_[A-Za-z0-9]{16,})`)},
+		{Name: "api-key", Pattern: regexp.MustCompile(`\b(sk-[A-Za-z0-9_-]{20,})`)},
+		{Name: "google-api-key", Pattern: regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})`)},
+		{Name: "jwt", Pattern: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,})`)},
+
+		// Credentials in commands and URLs
+		{Name: "bearer-token", Pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{8,})`)},
+		{Name: "basic-auth", Pattern: regexp.MustCompile(`(?i)authorization:\s*basic\s+([A-Za-z0-9+/=]{8,})`)},
+		{Name: "url-password", Pattern: regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`)},
+		{Name: "mysql-password", Pattern: regexp.MustCompile(`\b(?:mysql|mysqldump|mysqladmin|mariadb)\b[^|;&]*?\s-p([^\s'"]+)`)},
+		{Name: "password-flag", Pattern: regexp.MustCompile(`(?i)--(?:password|passwd|pass|token|secret|api-key)[= ]["']?([^\s"']+)`)},
+		{Name: "curl-user", Pattern: regexp.MustCompile(`\bcurl\b.*?\s(?:-u|--user)\s*["']?[^\s:"']+:([^\s"']+)`)},
+		{Name: "sshpass", Pattern: regexp.MustCompile(`\bsshpass\s+-p\s*["']?([^\s"']+)`)},
+
+		// Variables named like secrets, if the value does not look like a
+		// placeholder or a variable reference
+		{
+			Name:       "secret-assignment",
+			Pattern:    regexp.MustCompile(`(?i)\b[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY)[A-Z0-9_]*["']?\s*[=:]\s*["']?([^\s"'$]{8,})`),
+			MinEntropy: 3.0,
+		},
+
+		// Long random-looking strings anywhere. Hex digests (at most 4 bits
+		// per character) fall below the threshold.
+		{
+			Name:       "high-entropy-string",
+			Pattern:    regexp.MustCompile(`\b([A-Za-z0-9+/_=-]{32,})\b`),
+			MinEntropy: 4.5,
+		},
+	}
+}
@@ -0,0 +1,172 @@
+// Package secrets finds credentials such as API keys, tokens and passwords
+// in text, so they can be shown masked and redacted from shell histories.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mat/gowipeme/internal/config"
+)
+
+// Redacted replaces secrets when an entry is masked
+const Redacted = "[REDACTED]"
+
+// Rule detects one kind of secret
+type Rule struct {
+	Name string
+	// Pattern matches text containing the secret. The first capture group is
+	// the secret; without groups the whole match is.
+	Pattern *regexp.Regexp
+	// MinEntropy drops matches whose secret has fewer bits of Shannon
+	// entropy per character; 0 disables the check
+	MinEntropy float64
+}
+
+// Finding is a secret found in a text
+type Finding struct {
+	Rule  string
+	Start int // byte offsets of the secret in the text
+	End   int
+	Value string
+}
+
+// Masked returns the secret with all but its first characters hidden. Long
+// secrets keep a 4-character prefix so the key can still be identified.
+func (f Finding) Masked() string {
+	return Mask(f.Value)
+}
+
+// Mask hides a secret value. The number of stars is fixed so the length of
+// the secret is not revealed.
+func Mask(value string) string {
+	if len(value) >= 16 {
+		return value[:4] + "********"
+	}
+	return "********"
+}
+
+// Scanner finds secrets using a set of rules
+type Scanner struct {
+	rules []Rule
+}
+
+// NewScanner creates a scanner with the given rules
+func NewScanner(rules []Rule) *Scanner {
+	return &Scanner{rules: rules}
+}
+
+// Scan returns the secrets in text, ordered by position. When rules overlap,
+// the rule listed first wins.
+func (s *Scanner) Scan(text string) []Finding {
+	findings := make([]Finding, 0)
+
+	for _, rule := range s.rules {
+		for _, m := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if start == end {
+				continue
+			}
+			value := text[start:end]
+			if rule.MinEntropy > 0 && Entropy(value) < rule.MinEntropy {
+				continue
+			}
+			if overlaps(findings, start, end) {
+				continue
+			}
+			findings = append(findings, Finding{Rule: rule.Name, Start: start, End: end, Value: value})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+// overlaps reports whether [start, end) overlaps an existing finding
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.End && f.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace returns text with each finding replaced by repl(finding).
+// findings must come from Scan on the same text.
+func Replace(text string, findings []Finding, repl func(Finding) string) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		b.WriteString(text[last:f.Start])
+		b.WriteString(repl(f))
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// MaskAll returns text with its secrets masked for display
+func MaskAll(text string, findings []Finding) string {
+	return Replace(text, findings, Finding.Masked)
+}
+
+// Redact returns text with its secrets replaced by Redacted
+func Redact(text string, findings []Finding) string {
+	return Replace(text, findings, func(Finding) string { return Redacted })
+}
+
+// Entropy returns the Shannon entropy of s in bits per character
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// RulesFromConfig returns the built-in rules, minus those disabled in cfg,
+// followed by the rules defined in cfg
+func RulesFromConfig(cfg config.SecretsConfig) ([]Rule, error) {
+	disabled := make(map[string]bool, len(cfg.Disable))
+	for _, name := range cfg.Disable {
+		disabled[name] = true
+	}
+
+	rules := make([]Rule, 0)
+	for _, rule := range DefaultRules() {
+		if !disabled[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	for i, custom := range cfg.Rules {
+		name := custom.Name
+		if name == "" {
+			name = fmt.Sprintf("custom-%d", i+1)
+		}
+		pattern, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("secret rule %s: %w", name, err)
+		}
+		rules = append(rules, Rule{Name: name, Pattern: pattern, MinEntropy: custom.MinEntropy})
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,111 @@
+package shellhist
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// splitLines splits data into lines without their terminators
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// Timestamp line prefixes: bash writes "#1700000000" when HISTTIMEFORMAT is
+// set, tcsh writes "#+1700000000" with savehist
+const (
+	bashStampPrefix = "#"
+	tcshStampPrefix = "#+"
+)
+
+// bashTimestamp parses a timestamp line starting with prefix
+func bashTimestamp(line, prefix string) (time.Time, bool) {
+	digits, ok := strings.CutPrefix(line, prefix)
+	if !ok || digits == "" {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || secs < 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
+
+// parseBash reads a bash history file. Without timestamps every line is a
+// command, and a timestamp line dates the command on the next line.
+//
+// With lithist, bash also stores multi-line commands as the lines between
+// two timestamps, which looks just like the lines a session without
+// HISTTIMEFORMAT appends. Those lines are only joined into one command in
+// consistently timestamped files: files starting with a timestamp, where
+// the lines end at another timestamp or other commands span several lines.
+// Elsewhere each line is a command of its own.
+// tcsh files are read the same way with their own timestamp prefix.
+func parseBash(data []byte, prefix string) []Entry {
+	lines := splitLines(data)
+	isStamp := func(line string) bool {
+		_, ok := bashTimestamp(line, prefix)
+		return ok
+	}
+
+	// Find the last timestamp and whether any command between two
+	// timestamps has more than one line
+	lastStamp, multiline := -1, false
+	for i, line := range lines {
+		if !isStamp(line) {
+			continue
+		}
+		if lastStamp >= 0 && i-lastStamp > 2 {
+			multiline = true
+		}
+		lastStamp = i
+	}
+	consistent := len(lines) > 0 && isStamp(lines[0])
+
+	entries := make([]Entry, 0)
+	dated := false // the last entry is a timestamp still waiting for its command
+	for i, line := range lines {
+		if t, ok := bashTimestamp(line, prefix); ok {
+			// A timestamp not followed by a command is dropped
+			if dated {
+				entries = entries[:len(entries)-1]
+			}
+			entries = append(entries, Entry{Time: t})
+			dated = true
+			continue
+		}
+
+		switch {
+		case dated:
+			entries[len(entries)-1].Command = line
+			dated = false
+		case consistent && (i < lastStamp || multiline):
+			entries[len(entries)-1].Command += "\n" + line
+		default:
+			entries = append(entries, Entry{Command: line})
+		}
+	}
+	if dated {
+		entries = entries[:len(entries)-1]
+	}
+
+	return entries
+}
+
+// marshalBash writes entries in bash or tcsh format, with a timestamp line
+// before each entry that has a time
+func marshalBash(entries []Entry, prefix string) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if !entry.Time.IsZero() {
+			buf.WriteString(prefix + strconv.FormatInt(entry.Time.Unix(), 10) + "\n")
+		}
+		buf.WriteString(entry.Command + "\n")
+	}
+	return buf.Bytes()
+}
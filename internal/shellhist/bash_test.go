@@ -0,0 +1,134 @@
+package shellhist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBash(t *testing.T) {
+	t1, t2, t3 := time.Unix(1700000000, 0), time.Unix(1700000100, 0), time.Unix(1700000200, 0)
+
+	tests := []struct {
+		name string
+		data string
+		want []Entry
+		// written is what Marshal writes back when it differs from data
+		written string
+	}{
+		{
+			name: "empty",
+			data: "",
+			want: []Entry{},
+		},
+		{
+			name: "untimed",
+			data: "ls -la\ncd /tmp\n\ngit status\n",
+			want: []Entry{{Command: "ls -la"}, {Command: "cd /tmp"}, {Command: ""}, {Command: "git status"}},
+		},
+		{
+			name: "timed",
+			data: "#1700000000\nls\n#1700000100\npwd\n",
+			want: []Entry{{Command: "ls", Time: t1}, {Command: "pwd", Time: t2}},
+		},
+		{
+			name: "lithist",
+			data: "#1700000000\nfor i in 1 2; do\n  echo $i\ndone\n#1700000100\npwd\n",
+			want: []Entry{{Command: "for i in 1 2; do\n  echo $i\ndone", Time: t1}, {Command: "pwd", Time: t2}},
+		},
+		{
+			name: "lithist ending in a multi-line command",
+			data: "#1700000000\nif true; then\n  ls\nfi\n#1700000100\nwhile true; do\n  sleep 1\ndone\n",
+			want: []Entry{
+				{Command: "if true; then\n  ls\nfi", Time: t1},
+				{Command: "while true; do\n  sleep 1\ndone", Time: t2},
+			},
+		},
+		{
+			name: "untimed session appended to a timed file",
+			data: "#1700000000\nls\n#1700000100\npwd\nmake\nmake test\n",
+			want: []Entry{{Command: "ls", Time: t1}, {Command: "pwd", Time: t2}, {Command: "make"}, {Command: "make test"}},
+		},
+		{
+			name: "timed session appended to an untimed file",
+			data: "ls\ncd /tmp\n#1700000000\nmake\nmake test\n#1700000100\npwd\n",
+			want: []Entry{
+				{Command: "ls"}, {Command: "cd /tmp"},
+				{Command: "make", Time: t1}, {Command: "make test"},
+				{Command: "pwd", Time: t2},
+			},
+		},
+		{
+			name:    "timestamps without commands",
+			data:    "#1700000000\n#1700000100\nls\n#1700000200\n",
+			want:    []Entry{{Command: "ls", Time: t2}},
+			written: "#1700000100\nls\n",
+		},
+		{
+			name: "comments are commands",
+			data: "#1700000200\n# not a timestamp\n#12ab\n",
+			want: []Entry{{Command: "# not a timestamp", Time: t3}, {Command: "#12ab"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(Bash, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(Bash, %q) =\n%#v\nwant\n%#v", tt.data, got, tt.want)
+			}
+
+			// Files are written back unchanged
+			out, err := Marshal(Bash, got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantOut := tt.data
+			if tt.written != "" {
+				wantOut = tt.written
+			}
+			if string(out) != wantOut {
+				t.Errorf("Marshal(Bash) = %q, want %q", out, wantOut)
+			}
+		})
+	}
+}
+
+func TestParseTcsh(t *testing.T) {
+	data := "#+1700000000\nls\n#+1700000100\npwd\n#1700000200\n"
+	want := []Entry{
+		{Command: "ls", Time: time.Unix(1700000000, 0)},
+		{Command: "pwd", Time: time.Unix(1700000100, 0)},
+		// bash timestamps are commands in tcsh files
+		{Command: "#1700000200"},
+	}
+
+	got, err := Parse(Tcsh, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(Tcsh) =\n%#v\nwant\n%#v", got, want)
+	}
+	if out, _ := Marshal(Tcsh, got); string(out) != data {
+		t.Errorf("Marshal(Tcsh) = %q, want %q", out, data)
+	}
+}
+
+func TestKeepLastMixedBash(t *testing.T) {
+	data := "#1700000000\nls\n#1700000100\npwd\nmake\nmake test\n"
+
+	entries, err := Parse(Bash, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, removed := Policy{KeepLast: 2}.Apply(entries)
+	if removed != 2 {
+		t.Errorf("removed %d entries, want 2", removed)
+	}
+	if out, _ := Marshal(Bash, kept); string(out) != "make\nmake test\n" {
+		t.Errorf("kept %q, want the last two commands", out)
+	}
+}
@@ -0,0 +1,93 @@
+package shellhist
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseFish reads fish's YAML-like history, where each record starts with a
+// "- cmd:" line followed by an indented "when:" timestamp and an optional
+// "paths:" list. Lines fish does not write itself are ignored.
+func parseFish(data []byte) []Entry {
+	entries := make([]Entry, 0)
+	inPaths := false
+
+	for _, line := range splitLines(data) {
+		if cmd, ok := strings.CutPrefix(line, "- cmd:"); ok {
+			entries = append(entries, Entry{Command: unescapeFish(strings.TrimPrefix(cmd, " "))})
+			inPaths = false
+			continue
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		last := &entries[len(entries)-1]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "when:"):
+			secs, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(trimmed, "when:")), 10, 64)
+			if err == nil {
+				last.Time = time.Unix(secs, 0)
+			}
+			inPaths = false
+		case trimmed == "paths:":
+			inPaths = true
+		case inPaths && strings.HasPrefix(trimmed, "- "):
+			last.Paths = append(last.Paths, unescapeFish(strings.TrimPrefix(trimmed, "- ")))
+		default:
+			inPaths = false
+		}
+	}
+
+	return entries
+}
+
+// marshalFish writes entries in fish's history format
+func marshalFish(entries []Entry) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.WriteString("- cmd: " + escapeFish(entry.Command) + "\n")
+		if !entry.Time.IsZero() {
+			buf.WriteString("  when: " + strconv.FormatInt(entry.Time.Unix(), 10) + "\n")
+		}
+		if len(entry.Paths) > 0 {
+			buf.WriteString("  paths:\n")
+			for _, path := range entry.Paths {
+				buf.WriteString("    - " + escapeFish(path) + "\n")
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// escapeFish escapes backslashes and newlines as fish does
+func escapeFish(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "\n", `\n`)
+}
+
+// unescapeFish reverses escapeFish
+func unescapeFish(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package shellhist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFish(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Entry
+	}{
+		{
+			name: "records",
+			data: "- cmd: ls\n  when: 1700000000\n- cmd: vim notes.txt\n  when: 1700000100\n  paths:\n    - notes.txt\n",
+			want: []Entry{
+				{Command: "ls", Time: time.Unix(1700000000, 0)},
+				{Command: "vim notes.txt", Time: time.Unix(1700000100, 0), Paths: []string{"notes.txt"}},
+			},
+		},
+		{
+			name: "escapes",
+			data: "- cmd: echo a\\nb \\\\n\n  when: 1700000000\n",
+			want: []Entry{{Command: "echo a\nb \\n", Time: time.Unix(1700000000, 0)}},
+		},
+		{
+			name: "record without time",
+			data: "- cmd: pwd\n",
+			want: []Entry{{Command: "pwd"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(Fish, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(Fish, %q) =\n%#v\nwant\n%#v", tt.data, got, tt.want)
+			}
+			if out, _ := Marshal(Fish, got); string(out) != tt.data {
+				t.Errorf("Marshal(Fish) = %q, want %q", out, tt.data)
+			}
+		})
+	}
+
+	// Lines fish does not write are ignored
+	got, _ := Parse(Fish, []byte("garbage\n- cmd: ls\n  when: soon\n  extra: 1\n"))
+	if want := []Entry{{Command: "ls"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(Fish) with unknown lines = %#v, want %#v", got, want)
+	}
+}
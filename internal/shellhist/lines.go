@@ -0,0 +1,34 @@
+package shellhist
+
+import (
+	"bytes"
+	"strings"
+)
+
+// parseContinued reads one command per line. If continuation is set, a
+// line ending in it is joined with the next one, keeping the marker and the
+// newline so the command is written back unchanged.
+func parseContinued(data []byte, continuation string) []Entry {
+	entries := make([]Entry, 0)
+
+	lines := splitLines(data)
+	for i := 0; i < len(lines); i++ {
+		command := strings.TrimSuffix(lines[i], "\r")
+		for continuation != "" && strings.HasSuffix(command, continuation) && i+1 < len(lines) {
+			i++
+			command += "\n" + strings.TrimSuffix(lines[i], "\r")
+		}
+		entries = append(entries, Entry{Command: command})
+	}
+
+	return entries
+}
+
+// marshalLines writes one command per line
+func marshalLines(entries []Entry) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.WriteString(entry.Command + "\n")
+	}
+	return buf.Bytes()
+}
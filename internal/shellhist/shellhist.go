@@ -0,0 +1,166 @@
+// Package shellhist reads and writes shell history files so they can be
+// pruned entry by entry instead of being truncated.
+package shellhist
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Format identifies a history file format
+type Format string
+
+const (
+	Bash Format = "bash" // one command per line, "#epoch" lines with HISTTIMEFORMAT
+	Zsh  Format = "zsh"  // plain or extended (": epoch:duration;cmd"), metafied
+	Fish Format = "fish" // "- cmd:" records with "when:" and "paths:"
+	Tcsh Format = "tcsh" // like bash, with "#+epoch" lines
+
+	// PowerShell is PSReadLine's ConsoleHost_history.txt; a line ending in
+	// a backtick continues the command on the next line
+	PowerShell Format = "powershell"
+	// Lines is one command per line without timestamps, e.g. nushell's
+	// history.txt
+	Lines Format = "lines"
+)
+
+// Entry is one command in a history file
+type Entry struct {
+	Command  string
+	Time     time.Time     // zero when the file records no timestamp
+	Duration time.Duration // zsh extended history only
+	Paths    []string      // fish only
+}
+
+// Parse decodes history data in the given format
+func Parse(format Format, data []byte) ([]Entry, error) {
+	switch format {
+	case Bash:
+		return parseBash(data, bashStampPrefix), nil
+	case Tcsh:
+		return parseBash(data, tcshStampPrefix), nil
+	case Zsh:
+		return parseZsh(data), nil
+	case Fish:
+		return parseFish(data), nil
+	case PowerShell:
+		return parseContinued(data, "`"), nil
+	case Lines:
+		return parseContinued(data, ""), nil
+	default:
+		return nil, fmt.Errorf("unknown history format %q", format)
+	}
+}
+
+// Marshal encodes entries in the given format
+func Marshal(format Format, entries []Entry) ([]byte, error) {
+	switch format {
+	case Bash:
+		return marshalBash(entries, bashStampPrefix), nil
+	case Tcsh:
+		return marshalBash(entries, tcshStampPrefix), nil
+	case Zsh:
+		return marshalZsh(entries), nil
+	case Fish:
+		return marshalFish(entries), nil
+	case PowerShell, Lines:
+		return marshalLines(entries), nil
+	default:
+		return nil, fmt.Errorf("unknown history format %q", format)
+	}
+}
+
+// ReadFile parses the history file at path
+func ReadFile(path string, format Format) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(format, data)
+}
+
+// Policy selects the history entries to remove
+type Policy struct {
+	// KeepLast keeps only this many of the most recent entries; 0 keeps all
+	KeepLast int
+	// Entries recorded in [Since, Until) are removed. Zero bounds are open;
+	// when both are zero no entry is removed by time.
+	Since time.Time
+	Until time.Time
+}
+
+// IsZero reports whether the policy removes nothing
+func (p Policy) IsZero() bool {
+	return p.KeepLast <= 0 && p.Since.IsZero() && p.Until.IsZero()
+}
+
+// Apply returns the entries the policy keeps and the number removed.
+// An entry without a timestamp is dated like the next entry that has one,
+// since it was recorded before it; untimed entries at the end are kept.
+func (p Policy) Apply(entries []Entry) ([]Entry, int) {
+	if p.IsZero() {
+		return entries, 0
+	}
+
+	remove := make([]bool, len(entries))
+	if p.KeepLast > 0 {
+		for i := 0; i < len(entries)-p.KeepLast; i++ {
+			remove[i] = true
+		}
+	}
+
+	if !p.Since.IsZero() || !p.Until.IsZero() {
+		var next time.Time
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !entries[i].Time.IsZero() {
+				next = entries[i].Time
+			}
+			if !next.IsZero() && p.contains(next) {
+				remove[i] = true
+			}
+		}
+	}
+
+	kept := make([]Entry, 0, len(entries))
+	for i, entry := range entries {
+		if !remove[i] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, len(entries) - len(kept)
+}
+
+// contains reports whether t falls inside [Since, Until)
+func (p Policy) contains(t time.Time) bool {
+	if !p.Since.IsZero() && t.Before(p.Since) {
+		return false
+	}
+	if !p.Until.IsZero() && !t.Before(p.Until) {
+		return false
+	}
+	return true
+}
+
+// Prune applies the policy to the history file at path and rewrites it
+// atomically if anything is removed. It returns the number of removed entries.
+func Prune(path string, format Format, p Policy) (int, error) {
+	entries, err := ReadFile(path, format)
+	if err != nil {
+		return 0, err
+	}
+
+	kept, removed := p.Apply(entries)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	data, err := Marshal(format, kept)
+	if err != nil {
+		return 0, err
+	}
+	if err := WriteFileAtomic(path, data); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
@@ -0,0 +1,99 @@
+package shellhist
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zshMeta prefixes metafied bytes in zsh files; the following byte is the
+// original XOR 0x20
+const zshMeta = 0x83
+
+// zshExtended matches an EXTENDED_HISTORY record: ": start:elapsed;command"
+var zshExtended = regexp.MustCompile(`(?s)^: *([0-9]+):([0-9]+);(.*)$`)
+
+// parseZsh reads a zsh history file in plain or extended format. A line
+// ending in a backslash continues on the next line, which is how zsh stores
+// multi-line commands.
+func parseZsh(data []byte) []Entry {
+	entries := make([]Entry, 0)
+
+	lines := splitLines(data)
+	for i := 0; i < len(lines); i++ {
+		record := lines[i]
+		for strings.HasSuffix(record, "\\") && i+1 < len(lines) {
+			i++
+			record = record[:len(record)-1] + "\n" + lines[i]
+		}
+		record = unmetafy(record)
+
+		m := zshExtended.FindStringSubmatch(record)
+		if m == nil {
+			entries = append(entries, Entry{Command: record})
+			continue
+		}
+		start, _ := strconv.ParseInt(m[1], 10, 64)
+		elapsed, _ := strconv.ParseInt(m[2], 10, 64)
+		entries = append(entries, Entry{
+			Command:  m[3],
+			Time:     time.Unix(start, 0),
+			Duration: time.Duration(elapsed) * time.Second,
+		})
+	}
+
+	return entries
+}
+
+// marshalZsh writes entries in zsh format, using extended records for
+// entries that have a time
+func marshalZsh(entries []Entry) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if !entry.Time.IsZero() {
+			buf.WriteString(": " + strconv.FormatInt(entry.Time.Unix(), 10) + ":" +
+				strconv.FormatInt(int64(entry.Duration/time.Second), 10) + ";")
+		}
+		buf.WriteString(strings.ReplaceAll(metafy(entry.Command), "\n", "\\\n"))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// zshIsMeta reports whether zsh stores b metafied: NUL, Meta itself and
+// the bytes zsh uses internally as tokens (0x83-0xa2)
+func zshIsMeta(b byte) bool {
+	return b == 0 || (b >= zshMeta && b <= 0xa2)
+}
+
+// unmetafy decodes the Meta escapes zsh writes for special bytes
+func unmetafy(s string) string {
+	if strings.IndexByte(s, zshMeta) < 0 {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == zshMeta && i+1 < len(s) {
+			i++
+			out = append(out, s[i]^0x20)
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// metafy encodes special bytes the way zsh writes them
+func metafy(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if zshIsMeta(s[i]) {
+			out = append(out, zshMeta, s[i]^0x20)
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
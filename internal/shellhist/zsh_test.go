@@ -0,0 +1,63 @@
+package shellhist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseZsh(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Entry
+	}{
+		{
+			name: "plain",
+			data: "ls\ncd /tmp\n",
+			want: []Entry{{Command: "ls"}, {Command: "cd /tmp"}},
+		},
+		{
+			name: "extended",
+			data: ": 1700000000:0;ls\n: 1700000100:12;make test\n",
+			want: []Entry{
+				{Command: "ls", Time: time.Unix(1700000000, 0)},
+				{Command: "make test", Time: time.Unix(1700000100, 0), Duration: 12 * time.Second},
+			},
+		},
+		{
+			name: "multi-line",
+			data: ": 1700000000:0;for i in 1 2; do\\\n  echo $i\\\ndone\nls\n",
+			want: []Entry{
+				{Command: "for i in 1 2; do\n  echo $i\ndone", Time: time.Unix(1700000000, 0)},
+				{Command: "ls"},
+			},
+		},
+		{
+			name: "metafied",
+			// Meta itself and NUL are stored as Meta followed by the byte
+			// XOR 0x20
+			data: ": 1700000000:0;echo \x83\xa3 \x83\x20\n",
+			want: []Entry{{Command: "echo \x83 \x00", Time: time.Unix(1700000000, 0)}},
+		},
+		{
+			name: "colon without a record",
+			data: ": not extended\n",
+			want: []Entry{{Command: ": not extended"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(Zsh, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(Zsh, %q) =\n%#v\nwant\n%#v", tt.data, got, tt.want)
+			}
+			if out, _ := Marshal(Zsh, got); string(out) != tt.data {
+				t.Errorf("Marshal(Zsh) = %q, want %q", out, tt.data)
+			}
+		})
+	}
+}
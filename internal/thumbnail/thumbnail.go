@@ -0,0 +1,149 @@
+// Package thumbnail reads the metadata of freedesktop thumbnails, PNG files
+// named after the MD5 of the original file's URI that record the URI in a
+// Thumb::URI text chunk.
+package thumbnail
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// maxTextChunk bounds the text chunks read; thumbnails keep a few short keys
+const maxTextChunk = 1 << 20
+
+// ErrNotPNG is returned for files that are not PNG images
+var ErrNotPNG = errors.New("thumbnail: not a PNG file")
+
+// Info is the metadata of a thumbnail
+type Info struct {
+	// URI of the original file, empty when the thumbnail does not record it
+	URI string
+	// MTime is the modification time of the original when thumbnailed
+	MTime time.Time
+}
+
+// Path returns the local path of the original file, or "" for other URIs
+func (i Info) Path() string {
+	u, err := url.Parse(i.URI)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// Read returns the metadata of the thumbnail at path
+func Read(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+	return ReadFrom(bufio.NewReader(f))
+}
+
+// ReadFrom reads thumbnail metadata from the text chunks of a PNG stream.
+// It stops at the image data, which comes after the metadata in
+// thumbnails written by GLib and others.
+func ReadFrom(r io.Reader) (Info, error) {
+	var info Info
+
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil || !bytes.Equal(signature, pngSignature) {
+		return info, ErrNotPNG
+	}
+
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return info, nil
+			}
+			return info, fmt.Errorf("thumbnail: %w", err)
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		kind := string(header[4:8])
+
+		if kind == "IDAT" || kind == "IEND" {
+			return info, nil
+		}
+		if (kind != "tEXt" && kind != "zTXt" && kind != "iTXt") || length > maxTextChunk {
+			// Skip the data and the CRC
+			if _, err := io.CopyN(io.Discard, r, int64(length)+4); err != nil {
+				return info, fmt.Errorf("thumbnail: %w", err)
+			}
+			continue
+		}
+
+		data := make([]byte, length+4)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return info, fmt.Errorf("thumbnail: %w", err)
+		}
+		key, value, ok := textChunk(kind, data[:length])
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Thumb::URI":
+			info.URI = value
+		case "Thumb::MTime":
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				info.MTime = time.Unix(seconds, 0)
+			}
+		}
+	}
+}
+
+// textChunk decodes the keyword and text of a tEXt, zTXt or iTXt chunk
+func textChunk(kind string, data []byte) (string, string, bool) {
+	key, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return "", "", false
+	}
+
+	compressed := false
+	switch kind {
+	case "zTXt":
+		// compression method, then zlib data
+		if len(rest) < 1 {
+			return "", "", false
+		}
+		rest, compressed = rest[1:], true
+	case "iTXt":
+		// compression flag and method, language tag, translated keyword
+		if len(rest) < 2 {
+			return "", "", false
+		}
+		compressed = rest[0] == 1
+		rest = rest[2:]
+		for i := 0; i < 2; i++ {
+			if _, rest, ok = bytes.Cut(rest, []byte{0}); !ok {
+				return "", "", false
+			}
+		}
+	}
+
+	if compressed {
+		zr, err := zlib.NewReader(bytes.NewReader(rest))
+		if err != nil {
+			return "", "", false
+		}
+		defer zr.Close()
+		if rest, err = io.ReadAll(io.LimitReader(zr, maxTextChunk)); err != nil {
+			return "", "", false
+		}
+	}
+	return string(key), string(rest), true
+}
@@ -0,0 +1,145 @@
+package thumbnail
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// chunk encodes one PNG chunk
+func chunk(kind string, data []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.WriteString(kind)
+	b.Write(data)
+	binary.Write(&b, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+	return b.Bytes()
+}
+
+// deflate compresses s with zlib
+func deflate(s string) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write([]byte(s))
+	zw.Close()
+	return b.Bytes()
+}
+
+// png joins the signature and chunks
+func png(chunks ...[]byte) []byte {
+	return bytes.Join(append([][]byte{pngSignature}, chunks...), nil)
+}
+
+func TestReadFrom(t *testing.T) {
+	ihdr := chunk("IHDR", make([]byte, 13))
+	uri := chunk("tEXt", []byte("Thumb::URI\x00file:///home/u/a%20b.jpg"))
+	mtime := chunk("tEXt", []byte("Thumb::MTime\x001700000000"))
+
+	tests := []struct {
+		name string
+		data []byte
+		want Info
+	}{
+		{
+			name: "tEXt",
+			data: png(ihdr, uri, mtime, chunk("IDAT", nil), chunk("IEND", nil)),
+			want: Info{URI: "file:///home/u/a%20b.jpg", MTime: time.Unix(1700000000, 0)},
+		},
+		{
+			name: "zTXt",
+			data: png(ihdr, chunk("zTXt", append([]byte("Thumb::URI\x00\x00"), deflate("file:///z.png")...)), chunk("IEND", nil)),
+			want: Info{URI: "file:///z.png"},
+		},
+		{
+			name: "iTXt",
+			data: png(ihdr, chunk("iTXt", []byte("Thumb::URI\x00\x00\x00en\x00\x00file:///i.png")), chunk("IEND", nil)),
+			want: Info{URI: "file:///i.png"},
+		},
+		{
+			name: "compressed iTXt",
+			data: png(ihdr, chunk("iTXt", append([]byte("Thumb::URI\x00\x01\x00\x00\x00"), deflate("file:///c.png")...)), chunk("IEND", nil)),
+			want: Info{URI: "file:///c.png"},
+		},
+		{
+			name: "text after the image data",
+			data: png(ihdr, chunk("IDAT", []byte{1, 2, 3}), uri, chunk("IEND", nil)),
+			want: Info{},
+		},
+		{
+			name: "no end chunk",
+			data: png(ihdr, uri),
+			want: Info{URI: "file:///home/u/a%20b.jpg"},
+		},
+		{
+			name: "bad values are ignored",
+			data: png(chunk("tEXt", []byte("Thumb::MTime\x00yesterday")), chunk("tEXt", []byte("no keyword")), chunk("zTXt", []byte("Thumb::URI\x00\x00not zlib"))),
+			want: Info{},
+		},
+		{
+			name: "other keys",
+			data: png(chunk("tEXt", []byte("Software\x00GNOME::ThumbnailFactory")), mtime),
+			want: Info{MTime: time.Unix(1700000000, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFrom(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadFrom() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFromErrors(t *testing.T) {
+	truncated := png(chunk("tEXt", []byte("Thumb::URI\x00file:///a")))
+	truncated = truncated[:len(truncated)-6]
+
+	tests := []struct {
+		name   string
+		data   []byte
+		notPNG bool
+	}{
+		{"empty", nil, true},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), true},
+		{"truncated header", append(png(), 0, 0, 0), false},
+		{"truncated chunk", truncated, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFrom(bytes.NewReader(tt.data))
+			if err == nil {
+				t.Fatal("ReadFrom() succeeded, want an error")
+			}
+			if errors.Is(err, ErrNotPNG) != tt.notPNG {
+				t.Errorf("ReadFrom() error = %v, want ErrNotPNG %v", err, tt.notPNG)
+			}
+		})
+	}
+}
+
+func TestInfoPath(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"file:///home/u/a%20b.jpg", filepath.FromSlash("/home/u/a b.jpg")},
+		{"file://localhost/tmp/x.png", filepath.FromSlash("/tmp/x.png")},
+		{"file://otherhost/tmp/x.png", ""},
+		{"smb://server/share/x.png", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (Info{URI: tt.uri}).Path(); got != tt.want {
+			t.Errorf("Info{URI: %q}.Path() = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package trash reads and empties freedesktop.org trash directories: the
+// home trash and the per-volume .Trash/$UID and .Trash-$UID directories.
+package trash
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mat/gowipeme/internal/shellhist"
+)
+
+// infoSuffix ends the names of trash info files
+const infoSuffix = ".trashinfo"
+
+// dateLayout is the DeletionDate format, in local time
+const dateLayout = "2006-01-02T15:04:05"
+
+// Info is the content of a .trashinfo file
+type Info struct {
+	// Path is the original path, relative to the volume top directory for
+	// per-volume trash directories
+	Path         string
+	DeletionDate time.Time
+}
+
+// ParseInfo parses a .trashinfo file
+func ParseInfo(data []byte) (Info, error) {
+	var info Info
+	inGroup := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inGroup = line == "[Trash Info]"
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inGroup || !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Path":
+			path, err := url.PathUnescape(strings.TrimSpace(value))
+			if err != nil {
+				return info, fmt.Errorf("trashinfo: invalid Path: %w", err)
+			}
+			info.Path = path
+		case "DeletionDate":
+			// Some implementations append a time zone or fractional seconds
+			value = strings.TrimSpace(value)
+			if len(value) > len(dateLayout) {
+				value = value[:len(dateLayout)]
+			}
+			if t, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+				info.DeletionDate = t
+			}
+		}
+	}
+	if info.Path == "" {
+		return info, errors.New("trashinfo: missing [Trash Info] Path")
+	}
+	return info, nil
+}
+
+// Dir is a trash directory holding files/, info/ and directorysizes
+type Dir struct {
+	Path string
+	// Top is the volume top directory that relative paths start from; empty
+	// for the home trash, whose paths are absolute
+	Top string
+}
+
+// NewDir returns the trash directory at path, deriving the volume top
+// directory from the .Trash/$UID or .Trash-$UID name
+func NewDir(path string) Dir {
+	d := Dir{Path: path}
+	parent := filepath.Dir(path)
+	switch {
+	case strings.HasPrefix(filepath.Base(path), ".Trash-"):
+		d.Top = parent
+	case filepath.Base(parent) == ".Trash":
+		d.Top = filepath.Dir(parent)
+	}
+	return d
+}
+
+// Entry is an item in the trash
+type Entry struct {
+	// Name is the file name in files/ and, with .trashinfo, in info/
+	Name string
+	// Info is the parsed info file; Info.Path is empty when it is missing
+	// or unreadable
+	Info Info
+}
+
+// FilePath returns the path of the trashed file or directory
+func (d Dir) FilePath(name string) string {
+	return filepath.Join(d.Path, "files", name)
+}
+
+// InfoPath returns the path of the info file of a trashed item
+func (d Dir) InfoPath(name string) string {
+	return filepath.Join(d.Path, "info", name+infoSuffix)
+}
+
+// OriginalPath returns the absolute path an entry was trashed from, or ""
+// when its info file is missing
+func (d Dir) OriginalPath(e Entry) string {
+	if e.Info.Path == "" || filepath.IsAbs(e.Info.Path) || d.Top == "" {
+		return e.Info.Path
+	}
+	return filepath.Join(d.Top, e.Info.Path)
+}
+
+// Entries lists the items in files/ with their info
+func (d Dir) Entries() ([]Entry, error) {
+	files, err := os.ReadDir(filepath.Join(d.Path, "files"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(files))
+	for _, file := range files {
+		e := Entry{Name: file.Name()}
+		if data, err := os.ReadFile(d.InfoPath(e.Name)); err == nil {
+			e.Info, _ = ParseInfo(data)
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Info.DeletionDate.Before(entries[j].Info.DeletionDate) })
+
+	return entries, nil
+}
+
+// Remove deletes a trashed item with remove, then its info file. The info
+// file goes last so an interrupted removal leaves an entry that can be
+// listed and removed again.
+func (d Dir) Remove(name string, remove func(path string) error) error {
+	if err := remove(d.FilePath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(d.InfoPath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SyncDirectorySizes drops the directorysizes lines of directories no
+// longer in files/, as required after removing trashed directories. The
+// cache is rewritten atomically, as the specification asks, and only when
+// it changes.
+func (d Dir) SyncDirectorySizes() error {
+	path := filepath.Join(d.Path, "directorysizes")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	changed := false
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		// size mtime percent-encoded-name
+		fields := strings.SplitN(strings.TrimRight(line, "\r\n"), " ", 3)
+		if len(fields) == 3 {
+			if name, err := url.PathUnescape(fields[2]); err == nil {
+				if _, err := os.Lstat(d.FilePath(name)); os.IsNotExist(err) {
+					changed = true
+					continue
+				}
+			}
+		}
+		out.WriteString(line)
+	}
+	if !changed {
+		return nil
+	}
+
+	return shellhist.WriteFileAtomic(path, out.Bytes())
+}
@@ -0,0 +1,166 @@
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseInfo(t *testing.T) {
+	deleted := time.Date(2024, 3, 1, 12, 30, 5, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Info
+		wantErr bool
+	}{
+		{
+			name: "home trash",
+			data: "[Trash Info]\nPath=/home/u/notes.txt\nDeletionDate=2024-03-01T12:30:05\n",
+			want: Info{Path: "/home/u/notes.txt", DeletionDate: deleted},
+		},
+		{
+			name: "percent-encoded relative path",
+			data: "[Trash Info]\nPath=photos/a%20b%25.jpg\nDeletionDate=2024-03-01T12:30:05\n",
+			want: Info{Path: "photos/a b%.jpg", DeletionDate: deleted},
+		},
+		{
+			name: "time zone and fractional seconds",
+			data: "[Trash Info]\r\nPath = /tmp/x\r\nDeletionDate = 2024-03-01T12:30:05.123+01:00\r\n",
+			want: Info{Path: "/tmp/x", DeletionDate: deleted},
+		},
+		{
+			name: "comments and other groups",
+			data: "# written by hand\n[Desktop Entry]\nPath=/wrong\n[Trash Info]\nPath=/right\n",
+			want: Info{Path: "/right"},
+		},
+		{
+			name: "bad date",
+			data: "[Trash Info]\nPath=/tmp/x\nDeletionDate=yesterday\n",
+			want: Info{Path: "/tmp/x"},
+		},
+		{
+			name:    "path outside the group",
+			data:    "Path=/tmp/x\n[Trash Info]\nDeletionDate=2024-03-01T12:30:05\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid escape",
+			data:    "[Trash Info]\nPath=/tmp/%zz\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			data:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseInfo([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseInfo() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Path != tt.want.Path || !got.DeletionDate.Equal(tt.want.DeletionDate) {
+				t.Errorf("ParseInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirOriginalPath(t *testing.T) {
+	tests := []struct {
+		dir  string
+		path string
+		want string
+	}{
+		{"/home/u/.local/share/Trash", "/home/u/a", "/home/u/a"},
+		{"/media/usb/.Trash-1000", "docs/a", "/media/usb/docs/a"},
+		{"/media/usb/.Trash/1000", "docs/a", "/media/usb/docs/a"},
+		{"/media/usb/.Trash/1000", "/media/usb/b", "/media/usb/b"},
+		{"/media/usb/.Trash-1000", "", ""},
+	}
+	for _, tt := range tests {
+		d := NewDir(filepath.FromSlash(tt.dir))
+		got := d.OriginalPath(Entry{Info: Info{Path: filepath.FromSlash(tt.path)}})
+		if got != filepath.FromSlash(tt.want) {
+			t.Errorf("NewDir(%q).OriginalPath(%q) = %q, want %q", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSyncDirectorySizes(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		data  string
+		want  string
+	}{
+		{
+			name:  "removed directories",
+			files: []string{"kept", "a b"},
+			data:  "4096 1700000000 kept\n8192 1700000000 gone\n12 1700000000 a%20b\n",
+			want:  "4096 1700000000 kept\n12 1700000000 a%20b\n",
+		},
+		{
+			name:  "every directory removed",
+			files: nil,
+			data:  "4096 1700000000 gone\r\n",
+			want:  "",
+		},
+		{
+			name:  "unknown lines are kept",
+			files: nil,
+			data:  "garbage\n1 2 %zz\n4096 1700000000 gone",
+			want:  "garbage\n1 2 %zz\n",
+		},
+		{
+			name:  "nothing removed",
+			files: []string{"kept"},
+			data:  "4096 1700000000 kept",
+			want:  "4096 1700000000 kept",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Dir{Path: t.TempDir()}
+			for _, name := range tt.files {
+				if err := os.MkdirAll(d.FilePath(name), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := filepath.Join(d.Path, "directorysizes")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := d.SyncDirectorySizes(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("directorysizes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// A trash directory without the cache is left alone
+	d := Dir{Path: t.TempDir()}
+	if err := d.SyncDirectorySizes(); err != nil {
+		t.Errorf("SyncDirectorySizes() without directorysizes: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(d.Path, "directorysizes")); !os.IsNotExist(err) {
+		t.Errorf("SyncDirectorySizes() created directorysizes")
+	}
+}
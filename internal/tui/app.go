@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -13,6 +15,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mat/gowipeme/internal/backup"
 	"github.com/mat/gowipeme/internal/cleaner"
+	"github.com/mat/gowipeme/internal/event"
+	"github.com/mat/gowipeme/internal/process"
 	"github.com/mat/gowipeme/internal/wiper"
 )
 
@@ -61,6 +65,7 @@ const (
 	restoreConfirmView
 	restoreRunningView
 	cleanerView
+	cleanerRunningView
 	wiperMethodView
 	wiperConfirmView
 	wiperProgressView
@@ -81,7 +86,20 @@ type model struct {
 	list            list.Model
 	currentView     view
 	cleanerMgr      *cleaner.CleanerManager
-	dryRunResults   map[string][]string
+	dryRunResults   map[string][]cleaner.Item
+	cleanerItems    []cleaner.Item
+	cleanerSelected map[string]bool
+	cleanerCursor   int
+	cleanerRunning  map[string][]string
+	cleanerParents  map[string][]string
+	cleanerSkipped  []string
+	cleanerWaiting  bool
+	cleanerWarnings []error
+	dryRunEvents    chan tea.Msg
+	cleanEvents     chan tea.Msg
+	cleanStatus     []event.Event
+	cancel          context.CancelFunc
+	cancelling      bool
 	cleanResults    []cleaner.CleanResult
 	backupMgr       *backup.BackupManager
 	backupPreview   []string
@@ -92,6 +110,7 @@ type model struct {
 	restoreError    error
 	restoreResultID string
 	wiper           *wiper.Wiper
+	wiperEvents     chan tea.Msg
 	wiperMethod     wiper.WipeMethod
 	wiperProgress   wiper.Progress
 	wiperComplete   bool
@@ -129,10 +148,16 @@ func initialModel() model {
 	// Initialize cleaner manager
 	cm := cleaner.NewCleanerManager()
 	cm.AddCleaner(cleaner.NewBrowserCleaner())
+	cm.AddCleaner(cleaner.NewFirefoxCleaner())
 	cm.AddCleaner(cleaner.NewShellCleaner())
+	cm.AddCleaner(cleaner.NewReplCleaner())
+	cm.AddCleaner(cleaner.NewEditorCleaner())
 	cm.AddCleaner(cleaner.NewCacheCleaner())
+	cm.AddCleaner(cleaner.NewThumbnailCleaner())
+	cm.AddCleaner(cleaner.NewTrashCleaner())
 	cm.AddCleaner(cleaner.NewRecentFilesCleaner())
 	cm.AddCleaner(cleaner.NewClipboardCleaner())
+	warnings := cm.AddCustomCleaners()
 
 	bm, _ := backup.NewBackupManager()
 
@@ -143,6 +168,7 @@ func initialModel() model {
 		list:            l,
 		currentView:     menuView,
 		cleanerMgr:      cm,
+		cleanerWarnings: warnings,
 		backupMgr:       bm,
 		progressBar:     pb,
 		methodSelection: 0, // Default to SinglePassZeros
@@ -151,6 +177,22 @@ func initialModel() model {
 	}
 }
 
+type dryRunItemMsg struct {
+	item   cleaner.Item
+	events chan tea.Msg
+}
+type dryRunDoneMsg struct {
+	results map[string][]cleaner.Item
+	err     error
+	events  chan tea.Msg
+}
+
+type cleanEventMsg event.Event
+type cleanDoneMsg []cleaner.CleanResult
+
+// programsExitedMsg ends waiting for running programs to exit
+type programsExitedMsg struct{ err error }
+
 type wiperProgressMsg wiper.Progress
 type wiperCompleteMsg struct{}
 type wiperErrorMsg error
@@ -167,34 +209,73 @@ func (m model) Init() tea.Cmd {
 	return nil
 }
 
-func startWiping(w *wiper.Wiper) tea.Cmd {
+// startDryRun runs the dry-run in the background, sending each item as it
+// is found and then the results on the returned channel
+func startDryRun(ctx context.Context, cm *cleaner.CleanerManager) chan tea.Msg {
+	events := make(chan tea.Msg)
+	go func() {
+		results, err := cm.DryRunAllStream(ctx, func(it cleaner.Item) {
+			events <- dryRunItemMsg{item: it, events: events}
+		})
+		events <- dryRunDoneMsg{results: results, err: err, events: events}
+		close(events)
+	}()
+	return events
+}
+
+// waitForMsg receives the next message of a background operation
+func waitForMsg(events chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// startCleaning cleans the selected items in the background, sending
+// progress events and then the results on the returned channel
+func startCleaning(ctx context.Context, cm *cleaner.CleanerManager, ids []string) chan tea.Msg {
+	events := make(chan tea.Msg)
+	go func() {
+		results := cm.CleanSelectedContext(ctx, ids, func(e event.Event) {
+			events <- cleanEventMsg(e)
+		})
+		events <- cleanDoneMsg(results)
+	}()
+	return events
+}
+
+// runningPollInterval is how often waiting checks for programs to exit
+const runningPollInterval = 2 * time.Second
+
+// waitForPrograms waits in the background until the programs using the
+// files of cm's cleaners have exited
+func waitForPrograms(ctx context.Context, cm *cleaner.CleanerManager) tea.Cmd {
+	return func() tea.Msg {
+		return programsExitedMsg{err: cm.WaitForPrograms(ctx, process.NewScanner(), runningPollInterval)}
+	}
+}
+
+// startWiping wipes free space in the background, sending progress and
+// then completion or the error on the returned channel. It is started once;
+// each progress message waits for the next one on the same channel.
+func startWiping(ctx context.Context, w *wiper.Wiper) chan tea.Msg {
+	events := make(chan tea.Msg)
+	go func() {
 		progressChan := make(chan wiper.Progress)
 		errChan := make(chan error, 1)
-
-		// Run wiping in goroutine
 		go func() {
-			err := w.WipeFreeSpace(progressChan)
-			if err != nil {
-				errChan <- err
-			}
+			errChan <- w.WipeFreeSpaceContext(ctx, progressChan)
 			close(progressChan)
 		}()
-
-		// Wait for progress updates or completion
-		for {
-			select {
-			case prog, ok := <-progressChan:
-				if !ok {
-					// Channel closed, wiping complete
-					return wiperCompleteMsg{}
-				}
-				return wiperProgressMsg(prog)
-			case err := <-errChan:
-				return wiperErrorMsg(err)
-			}
+		for prog := range progressChan {
+			events <- wiperProgressMsg(prog)
 		}
-	}
+		if err := <-errChan; err != nil {
+			events <- wiperErrorMsg(err)
+			return
+		}
+		events <- wiperCompleteMsg{}
+	}()
+	return events
 }
 
 func loadBackupPreview(bm *backup.BackupManager) tea.Cmd {
@@ -251,17 +332,69 @@ func startRestore(bm *backup.BackupManager, backupID string) tea.Cmd {
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case dryRunItemMsg:
+		// Items of an abandoned dry-run are drained and dropped
+		if msg.events == m.dryRunEvents {
+			m.dryRunResults[msg.item.Cleaner] = append(m.dryRunResults[msg.item.Cleaner], msg.item)
+			m.cleanerItems = append(m.cleanerItems, msg.item)
+			m.cleanerSelected[msg.item.ID] = true
+		}
+		return m, waitForMsg(msg.events)
+
+	case dryRunDoneMsg:
+		if msg.events != m.dryRunEvents {
+			return m, nil
+		}
+		m.dryRunEvents = nil
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		// Keep what was deselected while scanning
+		deselected := m.cleanerSelected
+		m.setDryRunResults(msg.results)
+		for id, on := range deselected {
+			if !on {
+				m.cleanerSelected[id] = false
+			}
+		}
+		return m, nil
+
+	case programsExitedMsg:
+		if !m.cleanerWaiting {
+			return m, nil
+		}
+		m.cleanerWaiting = false
+		if msg.err != nil {
+			// Cancelled; stay on the prompt
+			return m, nil
+		}
+		return m.cleanIDs(m.selectedIDs())
+
+	case cleanEventMsg:
+		m.setCleanStatus(event.Event(msg))
+		return m, waitForMsg(m.cleanEvents)
+
+	case cleanDoneMsg:
+		m.cleanResults = []cleaner.CleanResult(msg)
+		m.cleanEvents = nil
+		m.resultsMode = resultsCleaner
+		m.currentView = resultsView
+		return m, nil
+
 	case wiperProgressMsg:
 		m.wiperProgress = wiper.Progress(msg)
-		return m, startWiping(m.wiper) // Continue receiving updates
+		return m, waitForMsg(m.wiperEvents) // Continue receiving updates
 
 	case wiperCompleteMsg:
+		m.wiperEvents = nil
 		m.wiperComplete = true
 		m.resultsMode = resultsWiper
 		m.currentView = resultsView
 		return m, nil
 
 	case wiperErrorMsg:
+		m.wiperEvents = nil
 		m.wiperError = error(msg)
 		m.resultsMode = resultsWiper
 		m.currentView = resultsView
@@ -310,18 +443,47 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		switch keypress := msg.String(); keypress {
-		case "ctrl+c", "q":
+		case "ctrl+c", "q", "esc":
+			if msg.String() == "esc" && m.currentView != cleanerRunningView {
+				break
+			}
+			if m.currentView == cleanerRunningView {
+				// Back to the item list, no longer waiting
+				if m.cleanerWaiting && m.cancel != nil {
+					m.cancel()
+				}
+				m.cancel = nil
+				m.cleanerWaiting = false
+				m.currentView = cleanerView
+				return m, nil
+			}
 			if m.currentView == menuView {
 				m.quitting = true
 				return m, tea.Quit
 			}
-			if m.currentView == wiperProgressView || m.currentView == backupRunningView || m.currentView == restoreRunningView {
+			if m.currentView == wiperProgressView || m.cleanEvents != nil {
+				// Stop the operation; its completion message shows the results
+				if m.cancel != nil {
+					m.cancel()
+				}
+				m.cancelling = true
+				return m, nil
+			}
+			if m.currentView == backupRunningView || m.currentView == restoreRunningView {
 				// Don't allow quitting during in-progress operations
 				return m, nil
 			}
-			// Go back to menu from other views
+			// Go back to menu from other views, stopping a running dry-run
+			if m.cancel != nil {
+				m.cancel()
+			}
+			m.cancel = nil
+			m.cancelling = false
 			m.currentView = menuView
+			m.dryRunEvents = nil
 			m.dryRunResults = nil
+			m.cleanerItems = nil
+			m.cleanerSelected = nil
 			m.cleanResults = nil
 			m.backupPreview = nil
 			m.backupInfo = nil
@@ -342,6 +504,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.currentView == restoreSelectView && m.restoreSelection > 0 {
 				m.restoreSelection--
 			}
+			if m.currentView == cleanerView && m.cleanerCursor > 0 {
+				m.cleanerCursor--
+			}
 
 		case "down", "j":
 			if m.currentView == wiperMethodView && m.methodSelection < 2 {
@@ -350,6 +515,61 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.currentView == restoreSelectView && m.restoreSelection < len(m.restoreBackups)-1 {
 				m.restoreSelection++
 			}
+			if m.currentView == cleanerView && m.cleanerCursor < len(m.cleanerItems)-1 {
+				m.cleanerCursor++
+			}
+
+		case " ":
+			// Toggle the item under the cursor
+			if m.currentView == cleanerView && len(m.cleanerItems) > 0 {
+				id := m.cleanerItems[m.cleanerCursor].ID
+				m.cleanerSelected[id] = !m.cleanerSelected[id]
+			}
+
+		case "s":
+			// Clean without the cleaners whose programs are running
+			if m.currentView == cleanerRunningView && !m.cleanerWaiting {
+				affected := m.runningSelected()
+				ids := make([]string, 0)
+				for _, it := range m.cleanerItems {
+					if _, running := affected[it.Cleaner]; m.cleanerSelected[it.ID] && !running {
+						ids = append(ids, it.ID)
+					}
+				}
+				m.cleanerSkipped = sortedNames(affected)
+				return m.cleanIDs(ids)
+			}
+
+		case "w":
+			// Wait for the programs to exit, then clean
+			if m.currentView == cleanerRunningView && !m.cleanerWaiting {
+				affected := m.runningSelected()
+				waitFor := cleaner.NewCleanerManager()
+				for _, c := range m.cleanerMgr.GetCleaners() {
+					if _, ok := affected[c.Name()]; ok {
+						waitFor.AddCleaner(c)
+					}
+				}
+				var ctx context.Context
+				ctx, m.cancel = context.WithCancel(context.Background())
+				m.cleanerWaiting = true
+				return m, waitForPrograms(ctx, waitFor)
+			}
+
+		case "c":
+			// Clean anyway
+			if m.currentView == cleanerRunningView && !m.cleanerWaiting {
+				return m.cleanIDs(m.selectedIDs())
+			}
+
+		case "a":
+			// Select all items, or none if all are already selected
+			if m.currentView == cleanerView {
+				all := m.selectedCount() == len(m.cleanerItems)
+				for _, it := range m.cleanerItems {
+					m.cleanerSelected[it.ID] = !all
+				}
+			}
 
 		case "enter":
 			if m.currentView == menuView {
@@ -373,14 +593,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 					case "Clear All History":
 						m.currentView = cleanerView
-						// Run dry-run
-						results, err := m.cleanerMgr.DryRunAll()
-						if err != nil {
-							m.err = err
-							return m, nil
-						}
-						m.dryRunResults = results
-						return m, nil
+						m.err = nil
+						m.setDryRunResults(nil)
+						m.cleanerSkipped = nil
+						// Best effort: warn about browsers and shells that are still open
+						m.cleanerRunning, _ = m.cleanerMgr.RunningPrograms(process.NewScanner())
+						m.cleanerParents, _ = m.cleanerMgr.ParentPrograms(process.NewScanner())
+						// Run dry-run, listing items as they are found
+						var ctx context.Context
+						ctx, m.cancel = context.WithCancel(context.Background())
+						m.dryRunEvents = startDryRun(ctx, m.cleanerMgr)
+						return m, waitForMsg(m.dryRunEvents)
 
 					case "Secure Wipe Free Space":
 						m.currentView = wiperMethodView
@@ -411,11 +634,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.currentView = restoreRunningView
 				return m, startRestore(m.backupMgr, m.restoreSelected.ID)
 			} else if m.currentView == cleanerView {
-				// User confirmed, run cleaning
-				m.cleanResults = m.cleanerMgr.CleanAll()
-				m.resultsMode = resultsCleaner
-				m.currentView = resultsView
-				return m, nil
+				// User confirmed, clean the selected items
+				ids := m.selectedIDs()
+				if len(ids) == 0 || m.dryRunEvents != nil {
+					return m, nil
+				}
+				// Ask first when programs using the files are running
+				if len(m.runningSelected()) > 0 {
+					m.currentView = cleanerRunningView
+					return m, nil
+				}
+				return m.cleanIDs(ids)
 			} else if m.currentView == wiperMethodView {
 				// User selected a wipe method
 				m.wiperMethod = wiper.WipeMethod(m.methodSelection)
@@ -444,12 +673,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			} else if m.currentView == wiperConfirmView {
 				// Start wiping
+				var ctx context.Context
+				ctx, m.cancel = context.WithCancel(context.Background())
+				m.cancelling = false
 				m.currentView = wiperProgressView
-				return m, startWiping(m.wiper)
+				m.wiperEvents = startWiping(ctx, m.wiper)
+				return m, waitForMsg(m.wiperEvents)
 			} else if m.currentView == resultsView {
+				if m.cleanEvents != nil {
+					// Cleaning is still running
+					return m, nil
+				}
 				// Go back to menu
+				m.cancel = nil
+				m.cancelling = false
 				m.currentView = menuView
 				m.dryRunResults = nil
+				m.cleanerItems = nil
+				m.cleanerSelected = nil
 				m.cleanResults = nil
 				m.backupPreview = nil
 				m.backupInfo = nil
@@ -500,6 +741,9 @@ func (m model) View() string {
 	case cleanerView:
 		return m.renderCleanerView()
 
+	case cleanerRunningView:
+		return m.renderCleanerRunningView()
+
 	case wiperMethodView:
 		return m.renderWiperMethodView()
 
@@ -637,48 +881,211 @@ func (m model) renderRestoreRunningView() string {
 	return "\n  🔄 Restoring backup...\n\n  Please wait.\n"
 }
 
+// setDryRunResults stores dry-run results and selects every item
+func (m *model) setDryRunResults(results map[string][]cleaner.Item) {
+	m.dryRunResults = make(map[string][]cleaner.Item, len(results))
+	for cleanerName, items := range results {
+		m.dryRunResults[cleanerName] = items
+	}
+	m.cleanerItems = nil
+	m.cleanerSelected = make(map[string]bool)
+	m.cleanerCursor = 0
+
+	names := make([]string, 0, len(results))
+	for cleanerName := range results {
+		names = append(names, cleanerName)
+	}
+	sort.Strings(names)
+
+	for _, cleanerName := range names {
+		for _, it := range results[cleanerName] {
+			m.cleanerItems = append(m.cleanerItems, it)
+			m.cleanerSelected[it.ID] = true
+		}
+	}
+}
+
+// cleanIDs starts cleaning the items with the given IDs
+func (m model) cleanIDs(ids []string) (tea.Model, tea.Cmd) {
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+	m.cancelling = false
+	m.cleanStatus = nil
+	m.cleanResults = nil
+	m.cleanEvents = startCleaning(ctx, m.cleanerMgr, ids)
+	m.resultsMode = resultsCleaner
+	m.currentView = resultsView
+	return m, waitForMsg(m.cleanEvents)
+}
+
+// runningSelected returns, by cleaner name, the running programs of the
+// cleaners with selected items
+func (m model) runningSelected() map[string][]string {
+	affected := make(map[string][]string)
+	for _, it := range m.cleanerItems {
+		if programs := m.cleanerRunning[it.Cleaner]; m.cleanerSelected[it.ID] && len(programs) > 0 {
+			affected[it.Cleaner] = programs
+		}
+	}
+	return affected
+}
+
+// sortedNames returns the keys of m in order
+func sortedNames(m map[string][]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectedIDs returns the IDs of the selected cleaner items
+func (m model) selectedIDs() []string {
+	ids := make([]string, 0, len(m.cleanerItems))
+	for _, it := range m.cleanerItems {
+		if m.cleanerSelected[it.ID] {
+			ids = append(ids, it.ID)
+		}
+	}
+	return ids
+}
+
+// selectedCount returns the number of selected cleaner items
+func (m model) selectedCount() int {
+	return len(m.selectedIDs())
+}
+
 func (m model) renderCleanerView() string {
 	var s strings.Builder
 
 	s.WriteString("\n  🧹 Clear All History - Dry Run\n\n")
 
+	// Broken definitions in cleaners.d are skipped, not fatal
+	for _, warning := range m.cleanerWarnings {
+		s.WriteString(fmt.Sprintf("  ⚠ %v\n", warning))
+	}
+	if len(m.cleanerWarnings) > 0 {
+		s.WriteString("\n")
+	}
+
 	if m.err != nil {
 		s.WriteString(fmt.Sprintf("  Error: %v\n\n", m.err))
 		s.WriteString("  Press 'q' to go back\n")
 		return s.String()
 	}
 
-	if len(m.dryRunResults) == 0 {
+	if len(m.cleanerItems) == 0 && m.dryRunEvents != nil {
+		s.WriteString("  Scanning for items to clean...\n\n")
+		s.WriteString("  Press 'q' to cancel\n")
+		return s.String()
+	}
+
+	if len(m.cleanerItems) == 0 {
 		s.WriteString("  ✓ Nothing to clean!\n\n")
 		s.WriteString("  Press 'q' to go back\n")
 		return s.String()
 	}
 
-	totalItems := 0
-	names := make([]string, 0, len(m.dryRunResults))
-	for cleanerName := range m.dryRunResults {
-		names = append(names, cleanerName)
+	// Show a window of items around the cursor so long lists stay usable
+	const window = 20
+	start := 0
+	if m.cleanerCursor >= window {
+		start = m.cleanerCursor - window + 1
+	}
+	end := start + window
+	if end > len(m.cleanerItems) {
+		end = len(m.cleanerItems)
 	}
-	sort.Strings(names)
 
-	for _, cleanerName := range names {
-		items := m.dryRunResults[cleanerName]
-		totalItems += len(items)
-		s.WriteString(fmt.Sprintf("  %s (%d items):\n", cleanerName, len(items)))
-		for _, item := range items {
-			s.WriteString(fmt.Sprintf("    • %s\n", item))
+	if start > 0 {
+		s.WriteString(fmt.Sprintf("    ↑ %d more\n", start))
+	}
+	lastCleaner := ""
+	for i := start; i < end; i++ {
+		it := m.cleanerItems[i]
+		if it.Cleaner != lastCleaner {
+			s.WriteString(fmt.Sprintf("  %s (%d items):\n", it.Cleaner, len(m.dryRunResults[it.Cleaner])))
+			lastCleaner = it.Cleaner
 		}
-		s.WriteString("\n")
+		cursor := "  "
+		if i == m.cleanerCursor {
+			cursor = "> "
+		}
+		check := "[ ]"
+		if m.cleanerSelected[it.ID] {
+			check = "[x]"
+		}
+		s.WriteString(fmt.Sprintf("  %s%s %s\n", cursor, check, it))
+	}
+	if end < len(m.cleanerItems) {
+		s.WriteString(fmt.Sprintf("    ↓ %d more\n", len(m.cleanerItems)-end))
 	}
 
-	s.WriteString(fmt.Sprintf("  Total items to clean: %d\n\n", totalItems))
+	var selectedSize int64
+	for _, it := range m.cleanerItems {
+		if m.cleanerSelected[it.ID] {
+			selectedSize += it.Size
+		}
+	}
+
+	s.WriteString(fmt.Sprintf("\n  Selected: %d of %d items (%s)\n\n", m.selectedCount(), len(m.cleanerItems), wiper.FormatBytes(selectedSize)))
+	if m.dryRunEvents != nil {
+		s.WriteString("  Scanning for more items...\n\n")
+	}
+	running := sortedNames(m.cleanerRunning)
+	for _, cleanerName := range running {
+		s.WriteString(fmt.Sprintf("  ⚠️  %s is running; close it first or %s may be undone\n",
+			strings.Join(m.cleanerRunning[cleanerName], ", "), cleanerName))
+	}
+	m.writeParentAdvice(&s)
+	if len(running) > 0 {
+		s.WriteString("\n")
+	}
 	s.WriteString("  ⚠️  WARNING: This action cannot be undone!\n\n")
-	s.WriteString("  Press ENTER to confirm and clean\n")
+	s.WriteString("  Use arrow keys or j/k to move, SPACE to toggle, 'a' to toggle all\n")
+	s.WriteString("  Press ENTER to confirm and clean the selected items\n")
 	s.WriteString("  Press 'q' to cancel\n")
 
 	return s.String()
 }
 
+// writeParentAdvice tells how to keep the shells gowipeme runs in from
+// writing their history back
+func (m model) writeParentAdvice(s *strings.Builder) {
+	for _, cleanerName := range sortedNames(m.cleanerParents) {
+		s.WriteString(fmt.Sprintf("  ⚠️  gowipeme runs in %s; %s\n",
+			strings.Join(m.cleanerParents[cleanerName], ", "), cleaner.ParentAdvice))
+	}
+}
+
+func (m model) renderCleanerRunningView() string {
+	var s strings.Builder
+
+	s.WriteString("\n  🧹 Clear All History - Programs Running\n\n")
+
+	affected := m.runningSelected()
+	for _, cleanerName := range sortedNames(affected) {
+		s.WriteString(fmt.Sprintf("  ⚠️  %s is running; cleaning %s may fail or be undone when it exits\n",
+			strings.Join(affected[cleanerName], ", "), cleanerName))
+	}
+	m.writeParentAdvice(&s)
+	s.WriteString("\n")
+
+	if m.cleanerWaiting {
+		s.WriteString("  Waiting for the programs to exit...\n\n")
+		s.WriteString("  Press 'q' to stop waiting\n")
+		return s.String()
+	}
+
+	s.WriteString("  Press 's' to skip these cleaners and clean the rest\n")
+	s.WriteString("  Press 'w' to wait for the programs to exit, then clean\n")
+	s.WriteString("  Press 'c' to clean anyway\n")
+	s.WriteString("  Press 'q' to go back\n")
+
+	return s.String()
+}
+
 func (m model) renderResultsView() string {
 	var s strings.Builder
 
@@ -686,7 +1093,10 @@ func (m model) renderResultsView() string {
 	case resultsWiper:
 		s.WriteString("\n  ✨ Disk Wiping Complete\n\n")
 
-		if m.wiperError != nil {
+		if errors.Is(m.wiperError, context.Canceled) {
+			s.WriteString("  ✗ Cancelled; the wipe files were removed\n")
+			s.WriteString(fmt.Sprintf("  ✓ Wiped before cancelling: %s\n", wiper.FormatBytes(m.wiperProgress.BytesWritten)))
+		} else if m.wiperError != nil {
 			s.WriteString(fmt.Sprintf("  ✗ Error: %v\n", m.wiperError))
 		} else {
 			s.WriteString(fmt.Sprintf("  ✓ Successfully wiped free space using %s\n", m.wiperMethod.String()))
@@ -717,13 +1127,24 @@ func (m model) renderResultsView() string {
 			}
 		}
 	default:
+		if m.cleanEvents != nil {
+			return m.renderCleaningStatus()
+		}
 		s.WriteString("\n  ✨ Cleaning Complete\n\n")
 
+		if m.cancelling {
+			s.WriteString("  ✗ Cancelled; the remaining items were kept\n\n")
+		}
+		for _, cleanerName := range m.cleanerSkipped {
+			s.WriteString(fmt.Sprintf("  - %s: skipped, still running\n", cleanerName))
+		}
 		for _, result := range m.cleanResults {
-			if result.Error != nil {
+			if errors.Is(result.Error, context.Canceled) {
+				s.WriteString(fmt.Sprintf("  ✗ %s: cancelled after %d items\n", result.CleanerName, result.ItemsCleaned))
+			} else if result.Error != nil {
 				s.WriteString(fmt.Sprintf("  ✗ %s: %v\n", result.CleanerName, result.Error))
 			} else {
-				s.WriteString(fmt.Sprintf("  ✓ %s: cleaned %d items\n", result.CleanerName, result.ItemsCleaned))
+				s.WriteString(fmt.Sprintf("  ✓ %s: cleaned %d items, %s freed\n", result.CleanerName, result.ItemsCleaned, wiper.FormatBytes(result.BytesFreed)))
 			}
 		}
 	}
@@ -817,8 +1238,51 @@ func (m model) renderWiperProgressView() string {
 		s.WriteString("  Initializing...\n")
 	}
 
-	s.WriteString("\n  ⚠️  Please do not interrupt this process!\n")
-	s.WriteString("  (Ctrl+C is disabled during wiping)\n")
+	if m.cancelling {
+		s.WriteString("\n  Cancelling and removing the wipe files...\n")
+	} else {
+		s.WriteString("\n  Press 'q' to cancel; the wipe files are removed\n")
+	}
+
+	return s.String()
+}
+
+// setCleanStatus records the latest event of a cleaner, keeping cleaners
+// in the order they started
+func (m *model) setCleanStatus(e event.Event) {
+	for i, status := range m.cleanStatus {
+		if status.Source == e.Source {
+			m.cleanStatus[i] = e
+			return
+		}
+	}
+	m.cleanStatus = append(m.cleanStatus, e)
+}
+
+// renderCleaningStatus shows each started cleaner while cleaning runs
+func (m model) renderCleaningStatus() string {
+	var s strings.Builder
+
+	s.WriteString("\n  🧹 Cleaning...\n\n")
+
+	for _, e := range m.cleanStatus {
+		switch {
+		case e.Kind != event.Finished:
+			s.WriteString(fmt.Sprintf("  ⟳ %s: %d of %d items, %s freed\n", e.Source, e.Done, e.Total, wiper.FormatBytes(e.Freed)))
+		case errors.Is(e.Err, context.Canceled):
+			s.WriteString(fmt.Sprintf("  ✗ %s: cancelled after %d items\n", e.Source, e.Done))
+		case e.Err != nil:
+			s.WriteString(fmt.Sprintf("  ✗ %s: %v\n", e.Source, e.Err))
+		default:
+			s.WriteString(fmt.Sprintf("  ✓ %s: cleaned %d items, %s freed\n", e.Source, e.Done, wiper.FormatBytes(e.Freed)))
+		}
+	}
+
+	if m.cancelling {
+		s.WriteString("\n  Cancelling...\n")
+	} else {
+		s.WriteString("\n  Press 'q' to cancel\n")
+	}
 
 	return s.String()
 }
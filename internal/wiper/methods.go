@@ -1,6 +1,7 @@
 package wiper
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -15,6 +16,9 @@ import (
 // Algorithm defines the interface for wiping algorithms
 type Algorithm interface {
 	Wipe(tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error
+	// WipeContext is Wipe stopping with the context's error when it is
+	// cancelled; the wipe files written so far are left in tempDir
+	WipeContext(ctx context.Context, tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error
 	NumPasses() int
 }
 
@@ -40,7 +44,11 @@ func (a *SinglePassAlgorithm) NumPasses() int {
 }
 
 func (a *SinglePassAlgorithm) Wipe(tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error {
-	return writePass(tempDir, targetBytes, 0x00, 1, 1, "Single Pass (Zeros)", progressChan, startTime)
+	return a.WipeContext(context.Background(), tempDir, targetBytes, progressChan, startTime)
+}
+
+func (a *SinglePassAlgorithm) WipeContext(ctx context.Context, tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error {
+	return writePass(ctx, tempDir, targetBytes, 0x00, 1, 1, "Single Pass (Zeros)", progressChan, startTime)
 }
 
 // DoDAlgorithm implements DoD 5220.22-M (3 passes)
@@ -51,20 +59,24 @@ func (a *DoDAlgorithm) NumPasses() int {
 }
 
 func (a *DoDAlgorithm) Wipe(tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error {
+	return a.WipeContext(context.Background(), tempDir, targetBytes, progressChan, startTime)
+}
+
+func (a *DoDAlgorithm) WipeContext(ctx context.Context, tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error {
 	// Pass 1: Write zeros
-	err := writePass(tempDir, targetBytes, 0x00, 1, 3, "DoD Pass 1/3 (0x00)", progressChan, startTime)
+	err := writePass(ctx, tempDir, targetBytes, 0x00, 1, 3, "DoD Pass 1/3 (0x00)", progressChan, startTime)
 	if err != nil {
 		return err
 	}
 
 	// Pass 2: Write ones
-	err = writePass(tempDir, targetBytes, 0xFF, 2, 3, "DoD Pass 2/3 (0xFF)", progressChan, startTime)
+	err = writePass(ctx, tempDir, targetBytes, 0xFF, 2, 3, "DoD Pass 2/3 (0xFF)", progressChan, startTime)
 	if err != nil {
 		return err
 	}
 
 	// Pass 3: Write random
-	err = writePassRandom(tempDir, targetBytes, 3, 3, "DoD Pass 3/3 (Random)", progressChan, startTime)
+	err = writePassRandom(ctx, tempDir, targetBytes, 3, 3, "DoD Pass 3/3 (Random)", progressChan, startTime)
 	if err != nil {
 		return err
 	}
@@ -80,12 +92,16 @@ func (a *GutmannAlgorithm) NumPasses() int {
 }
 
 func (a *GutmannAlgorithm) Wipe(tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error {
+	return a.WipeContext(context.Background(), tempDir, targetBytes, progressChan, startTime)
+}
+
+func (a *GutmannAlgorithm) WipeContext(ctx context.Context, tempDir string, targetBytes int64, progressChan chan<- Progress, startTime time.Time) error {
 	// Gutmann method: 4 random passes + 27 pattern passes + 4 random passes
 	// For simplicity, we'll do: 4 random + 27 alternating patterns + 4 random
 
 	// First 4 random passes
 	for i := 1; i <= 4; i++ {
-		err := writePassRandom(tempDir, targetBytes, i, 35, fmt.Sprintf("Gutmann Pass %d/35 (Random)", i), progressChan, startTime)
+		err := writePassRandom(ctx, tempDir, targetBytes, i, 35, fmt.Sprintf("Gutmann Pass %d/35 (Random)", i), progressChan, startTime)
 		if err != nil {
 			return err
 		}
@@ -95,7 +111,7 @@ func (a *GutmannAlgorithm) Wipe(tempDir string, targetBytes int64, progressChan
 	patterns := []byte{0x55, 0xAA, 0x92, 0x49, 0x24, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
 	for i := 0; i < 27; i++ {
 		pattern := patterns[i%len(patterns)]
-		err := writePass(tempDir, targetBytes, pattern, i+5, 35, fmt.Sprintf("Gutmann Pass %d/35 (0x%02X)", i+5, pattern), progressChan, startTime)
+		err := writePass(ctx, tempDir, targetBytes, pattern, i+5, 35, fmt.Sprintf("Gutmann Pass %d/35 (0x%02X)", i+5, pattern), progressChan, startTime)
 		if err != nil {
 			return err
 		}
@@ -103,7 +119,7 @@ func (a *GutmannAlgorithm) Wipe(tempDir string, targetBytes int64, progressChan
 
 	// Final 4 random passes
 	for i := 32; i <= 35; i++ {
-		err := writePassRandom(tempDir, targetBytes, i, 35, fmt.Sprintf("Gutmann Pass %d/35 (Random)", i), progressChan, startTime)
+		err := writePassRandom(ctx, tempDir, targetBytes, i, 35, fmt.Sprintf("Gutmann Pass %d/35 (Random)", i), progressChan, startTime)
 		if err != nil {
 			return err
 		}
@@ -113,7 +129,7 @@ func (a *GutmannAlgorithm) Wipe(tempDir string, targetBytes int64, progressChan
 }
 
 // writePass writes a single pass with a specific byte pattern
-func writePass(tempDir string, targetBytes int64, pattern byte, currentPass, totalPasses int, methodName string, progressChan chan<- Progress, startTime time.Time) error {
+func writePass(ctx context.Context, tempDir string, targetBytes int64, pattern byte, currentPass, totalPasses int, methodName string, progressChan chan<- Progress, startTime time.Time) error {
 	const bufferSize = 1024 * 1024 // 1 MB buffer
 	buffer := make([]byte, bufferSize)
 
@@ -122,19 +138,20 @@ func writePass(tempDir string, targetBytes int64, pattern byte, currentPass, tot
 		buffer[i] = pattern
 	}
 
-	return writeBuffer(tempDir, targetBytes, buffer, currentPass, totalPasses, methodName, false, progressChan, startTime)
+	return writeBuffer(ctx, tempDir, targetBytes, buffer, currentPass, totalPasses, methodName, false, progressChan, startTime)
 }
 
 // writePassRandom writes a single pass with random data
-func writePassRandom(tempDir string, targetBytes int64, currentPass, totalPasses int, methodName string, progressChan chan<- Progress, startTime time.Time) error {
+func writePassRandom(ctx context.Context, tempDir string, targetBytes int64, currentPass, totalPasses int, methodName string, progressChan chan<- Progress, startTime time.Time) error {
 	const bufferSize = 1024 * 1024 // 1 MB buffer
 	buffer := make([]byte, bufferSize)
 
-	return writeBuffer(tempDir, targetBytes, buffer, currentPass, totalPasses, methodName, true, progressChan, startTime)
+	return writeBuffer(ctx, tempDir, targetBytes, buffer, currentPass, totalPasses, methodName, true, progressChan, startTime)
 }
 
-// writeBuffer writes data to fill the target space
-func writeBuffer(tempDir string, targetBytes int64, buffer []byte, currentPass, totalPasses int, methodName string, random bool, progressChan chan<- Progress, startTime time.Time) error {
+// writeBuffer writes data to fill the target space, checking for
+// cancellation before every write
+func writeBuffer(ctx context.Context, tempDir string, targetBytes int64, buffer []byte, currentPass, totalPasses int, methodName string, random bool, progressChan chan<- Progress, startTime time.Time) error {
 	var passWritten int64 = 0
 	fileIndex := 0
 
@@ -151,6 +168,11 @@ func writeBuffer(tempDir string, targetBytes int64, buffer []byte, currentPass,
 		fileSize := int64(0)
 
 		for fileSize < remaining {
+			if err := ctx.Err(); err != nil {
+				file.Close()
+				return err
+			}
+
 			writeSize := int64(len(buffer))
 			if fileSize+writeSize > remaining {
 				writeSize = remaining - fileSize
@@ -199,7 +221,10 @@ func writeBuffer(tempDir string, targetBytes int64, buffer []byte, currentPass,
 					progress.EstimatedTime = time.Duration(float64(remainingBytes)/bytesPerSecond) * time.Second
 				}
 
-				progressChan <- progress
+				select {
+				case progressChan <- progress:
+				case <-ctx.Done():
+				}
 			}
 
 			// Check if we've reached the target
@@ -0,0 +1,156 @@
+package wiper
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotOverwritten is wrapped by the errors of files that could not be
+// overwritten safely, such as hard-linked files, but are removed anyway
+var ErrNotOverwritten = errors.New("not overwritten")
+
+// randomPass marks a pass that writes random data
+const randomPass = -1
+
+// passes returns the byte written by each pass of a method, or randomPass
+func (w WipeMethod) passes() []int {
+	switch w {
+	case DoD522022M:
+		return []int{0x00, 0xFF, randomPass}
+	case Gutmann:
+		// Same sequence as GutmannAlgorithm
+		passes := []int{randomPass, randomPass, randomPass, randomPass}
+		patterns := []byte{0x55, 0xAA, 0x92, 0x49, 0x24, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+		for i := 0; i < 27; i++ {
+			passes = append(passes, int(patterns[i%len(patterns)]))
+		}
+		return append(passes, randomPass, randomPass, randomPass, randomPass)
+	default:
+		return []int{0x00}
+	}
+}
+
+// OverwriteFile overwrites the contents of a regular file in place with the
+// passes of method, syncing after each pass. Files with other hard links
+// are left alone, since overwriting them would destroy the other copies,
+// as are devices, pipes and sockets; both return ErrNotOverwritten.
+// Symbolic links and empty files hold nothing to overwrite.
+// Copy-on-write filesystems and SSDs may keep the old blocks regardless;
+// wipe the free space afterwards for those.
+func OverwriteFile(path string, method WipeMethod) error {
+	return OverwriteFileContext(context.Background(), path, method)
+}
+
+// OverwriteFileContext is OverwriteFile stopping with the context's error
+// when it is cancelled, leaving the file partly overwritten
+func OverwriteFileContext(ctx context.Context, path string, method WipeMethod) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return nil
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("%w: %s (not a regular file)", ErrNotOverwritten, path)
+	case linkCount(info) > 1:
+		return fmt.Errorf("%w: %s (hard-linked)", ErrNotOverwritten, path)
+	case info.Size() == 0:
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	const bufferSize = 1024 * 1024 // 1 MB buffer
+	buffer := make([]byte, bufferSize)
+
+	for _, pattern := range method.passes() {
+		if pattern != randomPass {
+			for i := range buffer {
+				buffer[i] = byte(pattern)
+			}
+		}
+		for offset := int64(0); offset < info.Size(); offset += bufferSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			n := min(int64(bufferSize), info.Size()-offset)
+			if pattern == randomPass {
+				if _, err := rand.Read(buffer[:n]); err != nil {
+					return fmt.Errorf("failed to generate random data: %w", err)
+				}
+			}
+			if _, err := file.WriteAt(buffer[:n], offset); err != nil {
+				return fmt.Errorf("failed to overwrite %s: %w", path, err)
+			}
+		}
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// ShredFile overwrites a file with method and removes it
+func ShredFile(path string, method WipeMethod) error {
+	return ShredFileContext(context.Background(), path, method)
+}
+
+// ShredFileContext is ShredFile keeping the file when the context is
+// cancelled. A file that is not overwritten is still removed and its
+// ErrNotOverwritten error returned.
+func ShredFileContext(ctx context.Context, path string, method WipeMethod) error {
+	overwriteErr := OverwriteFileContext(ctx, path, method)
+	if overwriteErr != nil && !errors.Is(overwriteErr, ErrNotOverwritten) {
+		return overwriteErr
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return overwriteErr
+}
+
+// ShredTree overwrites every regular file below path with method, without
+// following symbolic links, and removes the tree
+func ShredTree(path string, method WipeMethod) error {
+	return ShredTreeContext(context.Background(), path, method)
+}
+
+// ShredTreeContext is ShredTree keeping the tree when the context is
+// cancelled. The tree is removed even when some files are not overwritten;
+// they are then listed in an error wrapping ErrNotOverwritten.
+func ShredTreeContext(ctx context.Context, path string, method WipeMethod) error {
+	skipped := make([]string, 0)
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		err = OverwriteFileContext(ctx, p, method)
+		if errors.Is(err, ErrNotOverwritten) {
+			skipped = append(skipped, strings.TrimPrefix(err.Error(), ErrNotOverwritten.Error()+": "))
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("%w: %s", ErrNotOverwritten, strings.Join(skipped, ", "))
+	}
+	return nil
+}
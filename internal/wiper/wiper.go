@@ -1,10 +1,14 @@
 package wiper
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/mat/gowipeme/internal/event"
 )
 
 // WipeMethod represents the wiping algorithm to use
@@ -47,6 +51,21 @@ func (w WipeMethod) Description() string {
 	}
 }
 
+// ParseMethod returns the wipe method for a short name such as "zeros",
+// "dod" or "gutmann". It is used by the command line interface.
+func ParseMethod(name string) (WipeMethod, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "zeros", "zero", "single", "singlepass", "1":
+		return SinglePassZeros, nil
+	case "dod", "dod5220", "dod522022m", "3":
+		return DoD522022M, nil
+	case "gutmann", "35":
+		return Gutmann, nil
+	default:
+		return 0, fmt.Errorf("unknown wipe method %q (expected zeros, dod or gutmann)", name)
+	}
+}
+
 // Progress represents the current progress of a wiping operation
 type Progress struct {
 	BytesWritten  int64
@@ -66,6 +85,26 @@ func (p Progress) Percentage() float64 {
 	return float64(p.BytesWritten) / float64(p.TotalBytes) * 100
 }
 
+// EventSource is the source of the events of free space wipes
+const EventSource = "Wipe"
+
+// Event returns the progress as an event shared with the cleaners
+func (p Progress) Event() event.Event {
+	return event.Event{
+		Kind:      event.Progress,
+		Source:    EventSource,
+		Step:      p.CurrentMethod,
+		Done:      p.BytesWritten,
+		Total:     p.TotalBytes,
+		Unit:      event.Bytes,
+		Elapsed:   p.TimeElapsed,
+		Remaining: p.EstimatedTime,
+	}
+}
+
+// TempDirName is the directory holding the wipe files on the volume
+const TempDirName = ".gowipeme_temp"
+
 // Wiper handles secure disk wiping operations
 type Wiper struct {
 	Method     WipeMethod
@@ -104,6 +143,12 @@ func NewWiper(volumePath string, method WipeMethod) (*Wiper, error) {
 //
 // This ensures the OS always has breathing room and won't crash from a full disk.
 func (w *Wiper) WipeFreeSpace(progressChan chan<- Progress) error {
+	return w.WipeFreeSpaceContext(context.Background(), progressChan)
+}
+
+// WipeFreeSpaceContext is WipeFreeSpace stopping with the context's error
+// when it is cancelled. The wipe files are removed however it returns.
+func (w *Wiper) WipeFreeSpaceContext(ctx context.Context, progressChan chan<- Progress) (err error) {
 	// Get free space
 	freeSpace, err := w.GetFreeSpace()
 	if err != nil {
@@ -122,20 +167,29 @@ func (w *Wiper) WipeFreeSpace(progressChan chan<- Progress) error {
 		return fmt.Errorf("insufficient free space (need at least %s)", FormatBytes(safetyBuffer))
 	}
 
-	// Create temporary directory for wipe files
-	tempDir := filepath.Join(w.VolumePath, ".gowipeme_temp")
+	// Create temporary directory for wipe files, dropping the files of a run
+	// that was killed before it could remove them
+	tempDir := filepath.Join(w.VolumePath, TempDirName)
+	if err := w.RemoveTemp(); err != nil {
+		return err
+	}
 	err = os.MkdirAll(tempDir, 0755)
 	if err != nil {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
-	defer os.RemoveAll(tempDir)
+	defer func() {
+		// A full disk must not outlive the wipe, cancelled or not
+		if rmErr := w.RemoveTemp(); rmErr != nil && err == nil {
+			err = rmErr
+		}
+	}()
 
 	algorithm := GetAlgorithm(w.Method)
 	startTime := time.Now()
 
 	// PHASE 1: Fill most of the disk, leaving safety buffer
 	phase1Target := freeSpace - safetyBuffer
-	err = algorithm.Wipe(tempDir, phase1Target, progressChan, startTime)
+	err = algorithm.WipeContext(ctx, tempDir, phase1Target, progressChan, startTime)
 	if err != nil {
 		return fmt.Errorf("phase 1 failed: %w", err)
 	}
@@ -169,12 +223,20 @@ func (w *Wiper) WipeFreeSpace(progressChan chan<- Progress) error {
 
 	// Now wipe the space we freed + the original safety buffer
 	phase2Target := deletedSpace + safetyBuffer
-	err = algorithm.Wipe(tempDir, phase2Target, progressChan, startTime)
+	err = algorithm.WipeContext(ctx, tempDir, phase2Target, progressChan, startTime)
 	if err != nil {
 		return fmt.Errorf("phase 2 failed: %w", err)
 	}
 
-	// Cleanup is handled by defer os.RemoveAll(tempDir)
+	// Cleanup is handled by the deferred RemoveTemp
+	return nil
+}
+
+// RemoveTemp removes the wipe files of the volume
+func (w *Wiper) RemoveTemp() error {
+	if err := os.RemoveAll(filepath.Join(w.VolumePath, TempDirName)); err != nil {
+		return fmt.Errorf("failed to remove wipe files: %w", err)
+	}
 	return nil
 }
 
@@ -5,6 +5,7 @@ package wiper
 
 import (
 	"fmt"
+	"os"
 	"syscall"
 )
 
@@ -34,3 +35,10 @@ func (w *Wiper) GetVolumeInfo() (totalSpace, freeSpace int64, err error) {
 	return totalSpace, freeSpace, nil
 }
 
+// linkCount returns the number of hard links to a file
+func linkCount(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+	return 1
+}
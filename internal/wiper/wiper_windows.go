@@ -5,6 +5,7 @@ package wiper
 
 import (
 	"fmt"
+	"os"
 	"syscall"
 	"unsafe"
 )
@@ -64,3 +65,8 @@ func (w *Wiper) GetVolumeInfo() (totalSpace, freeSpace int64, err error) {
 	return totalBytes, freeBytes, nil
 }
 
+// linkCount returns the number of hard links to a file; Windows file
+// information does not carry it, so files are treated as single links
+func linkCount(info os.FileInfo) uint64 {
+	return 1
+}
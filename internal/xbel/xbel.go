@@ -0,0 +1,207 @@
+// Package xbel reads and prunes XBEL bookmark files as written by GLib's
+// GBookmarkFile, such as the freedesktop recently-used.xbel.
+package xbel
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Application is an application that registered a bookmark
+type Application struct {
+	Name     string
+	Exec     string
+	Modified time.Time
+	Count    int
+}
+
+// Bookmark is a <bookmark> element with the metadata GLib records
+type Bookmark struct {
+	Href         string
+	Added        time.Time
+	Modified     time.Time
+	Visited      time.Time
+	MimeType     string
+	Applications []Application
+	Groups       []string
+
+	// byte range of the element in the parsed data
+	start, end int
+}
+
+// Path returns the local path of a file:// bookmark, or "" for other URIs
+func (b Bookmark) Path() string {
+	u, err := url.Parse(b.Href)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// LastUsed returns the most recent time the bookmark was added, modified,
+// visited or registered by an application
+func (b Bookmark) LastUsed() time.Time {
+	last := b.Added
+	for _, t := range []time.Time{b.Modified, b.Visited} {
+		if t.After(last) {
+			last = t
+		}
+	}
+	for _, app := range b.Applications {
+		if app.Modified.After(last) {
+			last = app.Modified
+		}
+	}
+	return last
+}
+
+// ErrNotXBEL is returned for XML documents whose root element is not <xbel>
+var ErrNotXBEL = errors.New("xbel: not an XBEL document")
+
+// Parse returns the bookmarks of an XBEL document
+func Parse(data []byte) ([]Bookmark, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	bookmarks := make([]Bookmark, 0)
+	var current *Bookmark
+	var text bytes.Buffer
+	depth := 0
+	bookmarkDepth := 0
+	root := false
+
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xbel: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				if t.Name.Local != "xbel" {
+					return nil, ErrNotXBEL
+				}
+				root = true
+			}
+			text.Reset()
+			switch {
+			case t.Name.Local == "bookmark" && current == nil:
+				bookmarks = append(bookmarks, Bookmark{
+					Href:     attr(t, "href"),
+					Added:    parseTime(attr(t, "added")),
+					Modified: parseTime(attr(t, "modified")),
+					Visited:  parseTime(attr(t, "visited")),
+					start:    int(offset),
+				})
+				current = &bookmarks[len(bookmarks)-1]
+				bookmarkDepth = depth
+			case current == nil:
+			case t.Name.Local == "mime-type":
+				current.MimeType = attr(t, "type")
+			case t.Name.Local == "application":
+				app := Application{Name: attr(t, "name"), Exec: attr(t, "exec"), Modified: parseTime(attr(t, "modified"))}
+				// Files written before GLib 2.66 store seconds since the epoch
+				if stamp, err := strconv.ParseInt(attr(t, "timestamp"), 10, 64); err == nil && app.Modified.IsZero() {
+					app.Modified = time.Unix(stamp, 0)
+				}
+				app.Count, _ = strconv.Atoi(attr(t, "count"))
+				current.Applications = append(current.Applications, app)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if current != nil && t.Name.Local == "group" {
+				current.Groups = append(current.Groups, text.String())
+			}
+			if current != nil && depth == bookmarkDepth {
+				current.end = int(d.InputOffset())
+				current = nil
+			}
+			depth--
+		}
+	}
+	if depth != 0 || current != nil {
+		return nil, errors.New("xbel: unexpected end of file")
+	}
+	if !root {
+		return nil, ErrNotXBEL
+	}
+
+	return bookmarks, nil
+}
+
+// Remove deletes the bookmarks for which drop returns true. The elements are
+// cut out of the original bytes, so the XML declaration, namespaces and the
+// remaining bookmarks are written back unchanged. It returns the new
+// contents and the removed bookmarks.
+func Remove(data []byte, drop func(Bookmark) bool) ([]byte, []Bookmark, error) {
+	bookmarks, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	removed := make([]Bookmark, 0)
+	out := make([]byte, 0, len(data))
+	last := 0
+	for _, b := range bookmarks {
+		if !drop(b) {
+			continue
+		}
+		removed = append(removed, b)
+
+		// Take the indentation before and the line break after the element
+		start, end := b.start, b.end
+		for start > last && (data[start-1] == ' ' || data[start-1] == '\t') {
+			start--
+		}
+		if end < len(data) && data[end] == '\r' {
+			end++
+		}
+		if end < len(data) && data[end] == '\n' {
+			end++
+		}
+		out = append(out, data[last:start]...)
+		last = end
+	}
+	if len(removed) == 0 {
+		return data, removed, nil
+	}
+	out = append(out, data[last:]...)
+
+	// Never hand back a file GLib would refuse to load
+	if _, err := Parse(out); err != nil {
+		return nil, nil, fmt.Errorf("xbel: rewritten file is invalid: %w", err)
+	}
+	return out, removed, nil
+}
+
+// attr returns the value of the named attribute of an element
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseTime parses an ISO 8601 timestamp, returning the zero time if invalid
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}